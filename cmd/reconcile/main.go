@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"insider-backend/internal/config"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/repository/postgres"
+	"insider-backend/internal/service"
+	"insider-backend/pkg/logger"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// reconcile compares every wallet with its completed transactions and prints
+// a drift report as JSON. It exits with status 1 if drift remains.
+//
+//	go run ./cmd/reconcile [-user <user_id>] [-repair]
+func main() {
+	userFlag := flag.String("user", "", "only reconcile this user ID")
+	repair := flag.Bool("repair", false, "reset drifted balances to the recomputed amount")
+	timeout := flag.Duration("timeout", 10*time.Minute, "maximum run time")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	logger.Init(logger.LoggerConfig{
+		Level:  cfg.Logging.Level,
+		Format: cfg.Logging.Format,
+	})
+	// Keep stdout for the report
+	log.Logger = log.Logger.Output(os.Stderr)
+
+	var userID *uuid.UUID
+	if *userFlag != "" {
+		parsed, err := uuid.Parse(*userFlag)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid user ID")
+		}
+		userID = &parsed
+	}
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open database")
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to ping database")
+	}
+
+	repos := &repository.Repositories{
+		User:           postgres.NewUserRepository(db),
+		Transaction:    postgres.NewTransactionRepository(db),
+		Balance:        postgres.NewBalanceRepository(db),
+		AuditLog:       postgres.NewAuditLogRepository(db),
+		Ledger:         postgres.NewLedgerRepository(db),
+		Reconciliation: postgres.NewReconciliationRepository(db),
+	}
+	repos.UnitOfWork = postgres.NewUnitOfWork(db, nil)
+
+	report, err := service.NewReconciliationService(repos).Reconcile(ctx, userID, *repair)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Reconciliation failed")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write report")
+	}
+
+	if len(report.Mismatches) > report.RepairedCount {
+		os.Exit(1)
+	}
+}
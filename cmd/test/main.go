@@ -54,27 +54,27 @@ func main() {
 	// Test Transaction Creation
 	fromUserID := uuid.New()
 	toUserID := uuid.New()
-	transaction, err := domain.NewTransaction(&fromUserID, &toUserID, 100.50, domain.TransactionTypeTransfer, "Test transfer", "TEST001")
+	transaction, err := domain.NewTransaction(&fromUserID, &toUserID, domain.MustParseMoney("100.50"), domain.DefaultCurrency, domain.TransactionTypeTransfer, "Test transfer", "TEST001")
 	if err != nil {
 		fmt.Printf("❌ Transaction creation failed: %v\n", err)
 	} else {
-		fmt.Printf("✅ Transaction created successfully: %s (%.2f)\n", transaction.Type, transaction.Amount)
+		fmt.Printf("✅ Transaction created successfully: %s (%s)\n", transaction.Type, transaction.Amount)
 	}
 
 	// Test Balance Operations
-	balance := domain.NewBalance(uuid.New())
-	err = balance.Credit(100.0)
+	balance := domain.NewBalance(uuid.New(), domain.DefaultCurrency)
+	err = balance.Credit(domain.MustParseMoney("100.00"))
 	if err != nil {
 		fmt.Printf("❌ Balance credit failed: %v\n", err)
 	} else {
-		fmt.Printf("✅ Balance credited successfully: %.2f\n", balance.GetAmount())
+		fmt.Printf("✅ Balance credited successfully: %s\n", balance.GetAmount())
 	}
 
-	err = balance.Debit(25.0)
+	err = balance.Debit(domain.MustParseMoney("25.00"))
 	if err != nil {
 		fmt.Printf("❌ Balance debit failed: %v\n", err)
 	} else {
-		fmt.Printf("✅ Balance debited successfully: %.2f\n", balance.GetAmount())
+		fmt.Printf("✅ Balance debited successfully: %s\n", balance.GetAmount())
 	}
 
 	// Test 4: JWT Token Generation (if user service was initialized)
@@ -24,48 +24,62 @@ var (
 
 // CircuitBreaker implements the circuit breaker pattern
 type CircuitBreaker struct {
-	name         string
-	maxRequests  uint32
-	interval     time.Duration
-	timeout      time.Duration
-	failureRatio float64
+	name          string
+	maxRequests   uint32
+	interval      time.Duration
+	timeout       time.Duration
+	failureRatio  float64
+	isSuccessful  func(err error) bool
+	onStateChange func(name string, from State, to State)
 
 	mutex      sync.Mutex
 	state      State
 	generation uint64
 	counts     *Counts
+	window     slidingWindow
 	expiry     time.Time
 }
 
 // Counts holds the numbers of requests and their successes/failures
 type Counts struct {
-	Requests             uint32
-	TotalSuccesses       uint32
-	TotalFailures        uint32
-	ConsecutiveSuccesses uint32
-	ConsecutiveFailures  uint32
+	Requests             uint32 `json:"requests"`
+	TotalSuccesses       uint32 `json:"total_successes"`
+	TotalFailures        uint32 `json:"total_failures"`
+	ConsecutiveSuccesses uint32 `json:"consecutive_successes"`
+	ConsecutiveFailures  uint32 `json:"consecutive_failures"`
 }
 
-// Settings configures a CircuitBreaker
+// Settings configures a CircuitBreaker. A closed breaker trips once it has
+// seen MaxRequests calls in its window and FailureRatio of them failed.
 type Settings struct {
-	Name          string
-	MaxRequests   uint32
-	Interval      time.Duration
-	Timeout       time.Duration
-	FailureRatio  float64
+	Name         string
+	MaxRequests  uint32
+	Interval     time.Duration
+	Timeout      time.Duration
+	FailureRatio float64
+	// Window selects the calls a closed breaker trips on. A time window
+	// spans Interval.
+	Window WindowType
+	// WindowSize is the number of calls in a count window
+	WindowSize int
+	// IsSuccessful reports whether a call that returned err counts as a
+	// success. Defaults to err == nil.
+	IsSuccessful  func(err error) bool
 	OnStateChange func(name string, from State, to State)
 }
 
 // NewCircuitBreaker creates a new CircuitBreaker
 func NewCircuitBreaker(st Settings) *CircuitBreaker {
 	cb := &CircuitBreaker{
-		name:         st.Name,
-		maxRequests:  st.MaxRequests,
-		interval:     st.Interval,
-		timeout:      st.Timeout,
-		failureRatio: st.FailureRatio,
-		state:        StateClosed,
-		counts:       &Counts{},
+		name:          st.Name,
+		maxRequests:   st.MaxRequests,
+		interval:      st.Interval,
+		timeout:       st.Timeout,
+		failureRatio:  st.FailureRatio,
+		isSuccessful:  st.IsSuccessful,
+		onStateChange: st.OnStateChange,
+		state:         StateClosed,
+		counts:        &Counts{},
 	}
 
 	if cb.maxRequests == 0 {
@@ -80,6 +94,24 @@ func NewCircuitBreaker(st Settings) *CircuitBreaker {
 	if cb.failureRatio <= 0 {
 		cb.failureRatio = 0.6
 	}
+	if cb.isSuccessful == nil {
+		cb.isSuccessful = func(err error) bool { return err == nil }
+	}
+
+	switch st.Window {
+	case WindowCount:
+		size := st.WindowSize
+		if size <= 0 {
+			size = defaultWindowSize
+		}
+		// A smaller window could never hold enough calls to trip
+		if size < int(cb.maxRequests) {
+			size = int(cb.maxRequests)
+		}
+		cb.window = newCountWindow(size)
+	case WindowTime:
+		cb.window = newTimeWindow(cb.interval)
+	}
 
 	return cb
 }
@@ -100,7 +132,7 @@ func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{},
 	}()
 
 	result, err := req()
-	cb.afterRequest(generation, err == nil)
+	cb.afterRequest(generation, cb.isSuccessful(err))
 	return result, err
 }
 
@@ -112,6 +144,11 @@ func (cb *CircuitBreaker) Call(req func() error) error {
 	return err
 }
 
+// Name returns the name of the CircuitBreaker
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
 // State returns the current state of the CircuitBreaker
 func (cb *CircuitBreaker) State() State {
 	cb.mutex.Lock()
@@ -122,12 +159,64 @@ func (cb *CircuitBreaker) State() State {
 	return state
 }
 
-// Counts returns a copy of the current counts
+// OpenUntil returns when an open CircuitBreaker lets requests through again,
+// or the zero time if it is not open
+func (cb *CircuitBreaker) OpenUntil() time.Time {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if state, _ := cb.currentState(time.Now()); state != StateOpen {
+		return time.Time{}
+	}
+	return cb.expiry
+}
+
+// Counts returns a copy of the current counts. While a breaker with a sliding
+// window is closed, the totals are those of the calls in its window.
 func (cb *CircuitBreaker) Counts() Counts {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
-	return *cb.counts
+	now := time.Now()
+	state, _ := cb.currentState(now)
+	return cb.currentCounts(state, now)
+}
+
+// Status is a snapshot of the state of a CircuitBreaker
+type Status struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	Counts    Counts     `json:"counts"`
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+}
+
+// Status returns the state, counts and, while open, reopening time of the
+// CircuitBreaker, read together
+func (cb *CircuitBreaker) Status() Status {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	state, _ := cb.currentState(now)
+	status := Status{
+		Name:   cb.name,
+		State:  state.String(),
+		Counts: cb.currentCounts(state, now),
+	}
+	if state == StateOpen {
+		openUntil := cb.expiry
+		status.OpenUntil = &openUntil
+	}
+	return status
+}
+
+func (cb *CircuitBreaker) currentCounts(state State, now time.Time) Counts {
+	counts := *cb.counts
+	if cb.window != nil && state == StateClosed {
+		counts.TotalSuccesses, counts.TotalFailures = cb.window.totals(now)
+		counts.Requests = counts.TotalSuccesses + counts.TotalFailures
+	}
+	return counts
 }
 
 func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
@@ -166,6 +255,9 @@ func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
 
 func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
 	cb.counts.onSuccess()
+	if cb.window != nil && state == StateClosed {
+		cb.window.record(now, true)
+	}
 
 	if state == StateHalfOpen {
 		cb.setState(StateClosed, now)
@@ -174,10 +266,13 @@ func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
 
 func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
 	cb.counts.onFailure()
+	if cb.window != nil && state == StateClosed {
+		cb.window.record(now, false)
+	}
 
 	switch state {
 	case StateClosed:
-		if cb.readyToTrip(cb.counts) {
+		if cb.readyToTrip(now) {
 			cb.setState(StateOpen, now)
 		}
 	case StateHalfOpen:
@@ -185,9 +280,16 @@ func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
 	}
 }
 
-func (cb *CircuitBreaker) readyToTrip(counts *Counts) bool {
-	return counts.Requests >= cb.maxRequests &&
-		float64(counts.TotalFailures)/float64(counts.Requests) >= cb.failureRatio
+func (cb *CircuitBreaker) readyToTrip(now time.Time) bool {
+	requests, failures := cb.counts.Requests, cb.counts.TotalFailures
+	if cb.window != nil {
+		var successes uint32
+		successes, failures = cb.window.totals(now)
+		requests = successes + failures
+	}
+
+	return requests >= cb.maxRequests &&
+		float64(failures)/float64(requests) >= cb.failureRatio
 }
 
 func (cb *CircuitBreaker) setState(state State, now time.Time) {
@@ -205,6 +307,10 @@ func (cb *CircuitBreaker) setState(state State, now time.Time) {
 		Str("from_state", stateToString(prev)).
 		Str("to_state", stateToString(state)).
 		Msg("Circuit breaker state changed")
+
+	if cb.onStateChange != nil {
+		cb.onStateChange(cb.name, prev, state)
+	}
 }
 
 func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
@@ -224,11 +330,15 @@ func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
 func (cb *CircuitBreaker) toNewGeneration(now time.Time) {
 	cb.generation++
 	cb.counts.clear()
+	if cb.window != nil {
+		cb.window.reset()
+	}
 
 	var zero time.Time
 	switch cb.state {
 	case StateClosed:
-		if cb.interval == 0 {
+		// A sliding window drops old outcomes itself
+		if cb.interval == 0 || cb.window != nil {
 			cb.expiry = zero
 		} else {
 			cb.expiry = now.Add(cb.interval)
@@ -264,6 +374,11 @@ func (c *Counts) clear() {
 	c.ConsecutiveFailures = 0
 }
 
+// String returns the name of the state
+func (s State) String() string {
+	return stateToString(s)
+}
+
 func stateToString(state State) string {
 	switch state {
 	case StateClosed:
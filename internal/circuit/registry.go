@@ -0,0 +1,76 @@
+package circuit
+
+import (
+	"sort"
+	"sync"
+)
+
+// Registry creates circuit breakers by name and keeps them, so that every
+// caller protecting the same dependency shares one breaker
+type Registry struct {
+	defaults Settings
+
+	mutex    sync.RWMutex
+	breakers map[string]*CircuitBreaker
+}
+
+// NewRegistry creates a registry whose breakers default to the defaults
+// settings under their own name
+func NewRegistry(defaults Settings) *Registry {
+	return &Registry{
+		defaults: defaults,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// Get returns the breaker named name, creating it from the default settings
+// if there is none
+func (r *Registry) Get(name string) *CircuitBreaker {
+	st := r.defaults
+	st.Name = name
+	return r.GetWithSettings(st)
+}
+
+// GetWithSettings returns the breaker named st.Name, creating it from st if
+// there is none. The settings of an existing breaker are left unchanged.
+func (r *Registry) GetWithSettings(st Settings) *CircuitBreaker {
+	if cb, ok := r.Lookup(st.Name); ok {
+		return cb
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if cb, ok := r.breakers[st.Name]; ok {
+		return cb
+	}
+	cb := NewCircuitBreaker(st)
+	r.breakers[st.Name] = cb
+	return cb
+}
+
+// Lookup returns the breaker named name if it exists
+func (r *Registry) Lookup(name string) (*CircuitBreaker, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	cb, ok := r.breakers[name]
+	return cb, ok
+}
+
+// Statuses returns the status of every breaker, ordered by name
+func (r *Registry) Statuses() []Status {
+	r.mutex.RLock()
+	breakers := make([]*CircuitBreaker, 0, len(r.breakers))
+	for _, cb := range r.breakers {
+		breakers = append(breakers, cb)
+	}
+	r.mutex.RUnlock()
+
+	statuses := make([]Status, len(breakers))
+	for i, cb := range breakers {
+		statuses[i] = cb.Status()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,160 @@
+package circuit
+
+import (
+	"fmt"
+	"time"
+)
+
+// WindowType selects which outcomes a closed CircuitBreaker trips on
+type WindowType int
+
+const (
+	// WindowFixed counts the outcomes since the counts were last cleared,
+	// every Interval
+	WindowFixed WindowType = iota
+	// WindowCount counts the outcomes of the last WindowSize calls
+	WindowCount
+	// WindowTime counts the outcomes of the calls in the last Interval
+	WindowTime
+)
+
+// timeWindowBuckets is the number of buckets a time window is divided into.
+// Outcomes leave the window one bucket at a time.
+const timeWindowBuckets = 10
+
+// defaultWindowSize is the size of a count window that does not set one
+const defaultWindowSize = 100
+
+// ParseWindowType returns the WindowType named fixed, count or time
+func ParseWindowType(name string) (WindowType, error) {
+	switch name {
+	case "", "fixed":
+		return WindowFixed, nil
+	case "count":
+		return WindowCount, nil
+	case "time":
+		return WindowTime, nil
+	default:
+		return WindowFixed, fmt.Errorf("unknown circuit breaker window: %s", name)
+	}
+}
+
+// String returns the name of the window type
+func (t WindowType) String() string {
+	switch t {
+	case WindowFixed:
+		return "fixed"
+	case WindowCount:
+		return "count"
+	case WindowTime:
+		return "time"
+	default:
+		return "unknown"
+	}
+}
+
+// slidingWindow aggregates the outcomes of recent calls
+type slidingWindow interface {
+	record(now time.Time, success bool)
+	totals(now time.Time) (successes, failures uint32)
+	reset()
+}
+
+// countWindow keeps the outcomes of the last calls in a ring
+type countWindow struct {
+	failed    []bool
+	next      int
+	filled    int
+	successes uint32
+	failures  uint32
+}
+
+func newCountWindow(size int) *countWindow {
+	return &countWindow{failed: make([]bool, size)}
+}
+
+func (w *countWindow) record(now time.Time, success bool) {
+	if w.filled == len(w.failed) {
+		if w.failed[w.next] {
+			w.failures--
+		} else {
+			w.successes--
+		}
+	} else {
+		w.filled++
+	}
+
+	w.failed[w.next] = !success
+	if success {
+		w.successes++
+	} else {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.failed)
+}
+
+func (w *countWindow) totals(now time.Time) (uint32, uint32) {
+	return w.successes, w.failures
+}
+
+func (w *countWindow) reset() {
+	w.next = 0
+	w.filled = 0
+	w.successes = 0
+	w.failures = 0
+}
+
+// timeWindow keeps the outcomes of the calls in the last span in buckets of
+// equal width
+type timeWindow struct {
+	buckets []windowBucket
+	width   time.Duration
+}
+
+type windowBucket struct {
+	start     time.Time
+	successes uint32
+	failures  uint32
+}
+
+func newTimeWindow(span time.Duration) *timeWindow {
+	width := span / timeWindowBuckets
+	if width <= 0 {
+		width = time.Millisecond
+	}
+	return &timeWindow{buckets: make([]windowBucket, timeWindowBuckets), width: width}
+}
+
+func (w *timeWindow) record(now time.Time, success bool) {
+	start := now.Truncate(w.width)
+	bucket := &w.buckets[(start.UnixNano()/int64(w.width))%int64(len(w.buckets))]
+	if !bucket.start.Equal(start) {
+		*bucket = windowBucket{start: start}
+	}
+
+	if success {
+		bucket.successes++
+	} else {
+		bucket.failures++
+	}
+}
+
+func (w *timeWindow) totals(now time.Time) (uint32, uint32) {
+	oldest := now.Truncate(w.width).Add(-w.width * time.Duration(len(w.buckets)-1))
+
+	var successes, failures uint32
+	for _, bucket := range w.buckets {
+		if bucket.start.Before(oldest) {
+			continue
+		}
+		successes += bucket.successes
+		failures += bucket.failures
+	}
+	return successes, failures
+}
+
+func (w *timeWindow) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = windowBucket{}
+	}
+}
@@ -10,11 +10,23 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Logging  LoggingConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	JWT        JWTConfig
+	Logging    LoggingConfig
+	FX         FXConfig
+	Reconcile  ReconcileConfig
+	Hold       HoldConfig
+	Scheduler  SchedulerConfig
+	Outbox     OutboxConfig
+	Webhook    WebhookConfig
+	Projection ProjectionConfig
+	Snapshot   SnapshotConfig
+	EventBus   EventBusConfig
+	Stream     StreamConfig
+	Breaker    BreakerConfig
+	Worker     WorkerConfig
 }
 
 type ServerConfig struct {
@@ -54,6 +66,120 @@ type LoggingConfig struct {
 	Format string
 }
 
+type ReconcileConfig struct {
+	// Interval between background reconciliation runs; zero disables them
+	Interval time.Duration
+}
+
+type HoldConfig struct {
+	// DefaultTTL is how long a hold lasts when no expiry is requested
+	DefaultTTL time.Duration
+	// ExpiryInterval between runs releasing expired holds; zero disables them
+	ExpiryInterval time.Duration
+}
+
+type SchedulerConfig struct {
+	// Interval between checks for due scheduled transactions; zero disables them
+	Interval time.Duration
+}
+
+type OutboxConfig struct {
+	// RelayInterval between deliveries of queued events; zero disables them
+	RelayInterval time.Duration
+	// MaxAttempts before an undeliverable event is parked as failed
+	MaxAttempts int
+	// Retention of delivered messages before they are pruned
+	Retention time.Duration
+}
+
+type WebhookConfig struct {
+	// DeliveryInterval between sends of due webhook deliveries; zero disables them
+	DeliveryInterval time.Duration
+	// MaxAttempts before a failing delivery is dead-lettered
+	MaxAttempts int
+	// Timeout of a single webhook request
+	Timeout time.Duration
+}
+
+type ProjectionConfig struct {
+	// Interval between projection catch-ups; zero disables them
+	Interval time.Duration
+	// BatchSize of the pages of events read by a catch-up or rebuild
+	BatchSize int
+	// Lag before an event is projected, leaving time for slower transactions to commit
+	Lag time.Duration
+}
+
+type SnapshotConfig struct {
+	// Every is the number of events of an aggregate between snapshots; zero disables them
+	Every int
+}
+
+type EventBusConfig struct {
+	// QueueSize of each subscriber's queue of events
+	QueueSize int
+	// Concurrency is the number of events each subscriber handles at once
+	Concurrency int
+	// MaxAttempts before a failing event is dead-lettered
+	MaxAttempts int
+}
+
+type StreamConfig struct {
+	// Enabled routes events through Redis Streams, so every replica's
+	// subscribers receive the events relayed by any replica
+	Enabled bool
+	// Prefix of the stream keys
+	Prefix string
+	// MaxLen is the approximate number of entries kept per stream; zero keeps all
+	MaxLen int
+	// Group is the consumer group shared by the replicas
+	Group string
+	// Consumer names this replica within the group; defaults to the hostname
+	Consumer string
+	// ClaimIdle before an unacknowledged entry is reclaimed by another replica
+	ClaimIdle time.Duration
+	// MaxDeliveries before an entry is moved to the dead-letter stream
+	MaxDeliveries int
+}
+
+type BreakerConfig struct {
+	// MaxRequests before a closed breaker may trip, and let through while half-open
+	MaxRequests int
+	// Interval after which a closed breaker clears its counts
+	Interval time.Duration
+	// Timeout an open breaker waits before letting requests through again
+	Timeout time.Duration
+	// FailureRatio of requests that trips a closed breaker
+	FailureRatio float64
+	// Window is fixed, count or time: the calls a closed breaker trips on are
+	// those since its counts were cleared, the last WindowSize ones or those
+	// in the last Interval
+	Window string
+	// WindowSize is the number of calls in a count window
+	WindowSize int
+}
+
+type WorkerConfig struct {
+	// Count of workers running jobs on this instance
+	Count int
+	// Name identifies this instance in the leases of the jobs it claims
+	Name string
+	// PollInterval between looks for due jobs while the queue is idle
+	PollInterval time.Duration
+	// VisibilityTimeout after which a job whose worker did not report back is
+	// claimed again
+	VisibilityTimeout time.Duration
+	// MaxAttempts before a job is dead-lettered, unless its type sets them
+	MaxAttempts int
+	// Retention of completed, failed and discarded jobs, zero keeps them forever
+	Retention time.Duration
+}
+
+type FXConfig struct {
+	// RatesFile is a JSON file of static exchange rates; empty disables currency conversion
+	RatesFile string
+}
+
 func Load() (*Config, error) {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
@@ -93,6 +219,67 @@ func Load() (*Config, error) {
 			Level:  getEnvOrDefault("LOG_LEVEL", "info"),
 			Format: getEnvOrDefault("LOG_FORMAT", "json"),
 		},
+		FX: FXConfig{
+			RatesFile: getEnvOrDefault("FX_RATES_FILE", ""),
+		},
+		Reconcile: ReconcileConfig{
+			Interval: parseDurationOrDefault("RECONCILE_INTERVAL", time.Hour),
+		},
+		Hold: HoldConfig{
+			DefaultTTL:     parseDurationOrDefault("HOLD_DEFAULT_TTL", 7*24*time.Hour),
+			ExpiryInterval: parseDurationOrDefault("HOLD_EXPIRY_INTERVAL", time.Minute),
+		},
+		Scheduler: SchedulerConfig{
+			Interval: parseDurationOrDefault("SCHEDULER_INTERVAL", 10*time.Second),
+		},
+		Outbox: OutboxConfig{
+			RelayInterval: parseDurationOrDefault("OUTBOX_RELAY_INTERVAL", time.Second),
+			MaxAttempts:   parseIntOrDefault("OUTBOX_MAX_ATTEMPTS", 10),
+			Retention:     parseDurationOrDefault("OUTBOX_RETENTION", 24*time.Hour),
+		},
+		Webhook: WebhookConfig{
+			DeliveryInterval: parseDurationOrDefault("WEBHOOK_DELIVERY_INTERVAL", 5*time.Second),
+			MaxAttempts:      parseIntOrDefault("WEBHOOK_MAX_ATTEMPTS", 8),
+			Timeout:          parseDurationOrDefault("WEBHOOK_TIMEOUT", 10*time.Second),
+		},
+		Projection: ProjectionConfig{
+			Interval:  parseDurationOrDefault("PROJECTION_INTERVAL", time.Second),
+			BatchSize: parseIntOrDefault("PROJECTION_BATCH_SIZE", 500),
+			Lag:       parseDurationOrDefault("PROJECTION_LAG", 5*time.Second),
+		},
+		Snapshot: SnapshotConfig{
+			Every: parseIntOrDefault("SNAPSHOT_EVERY", 100),
+		},
+		EventBus: EventBusConfig{
+			QueueSize:   parseIntOrDefault("EVENT_BUS_QUEUE_SIZE", 1000),
+			Concurrency: parseIntOrDefault("EVENT_BUS_CONCURRENCY", 1),
+			MaxAttempts: parseIntOrDefault("EVENT_BUS_MAX_ATTEMPTS", 3),
+		},
+		Stream: StreamConfig{
+			Enabled:       parseBoolOrDefault("EVENT_STREAM_ENABLED", false),
+			Prefix:        getEnvOrDefault("EVENT_STREAM_PREFIX", "events"),
+			MaxLen:        parseIntOrDefault("EVENT_STREAM_MAX_LEN", 100000),
+			Group:         getEnvOrDefault("EVENT_STREAM_GROUP", "insider-backend"),
+			Consumer:      getEnvOrDefault("EVENT_STREAM_CONSUMER", hostname()),
+			ClaimIdle:     parseDurationOrDefault("EVENT_STREAM_CLAIM_IDLE", time.Minute),
+			MaxDeliveries: parseIntOrDefault("EVENT_STREAM_MAX_DELIVERIES", 10),
+		},
+		Breaker: BreakerConfig{
+			MaxRequests:  parseIntOrDefault("BREAKER_MAX_REQUESTS", 5),
+			Interval:     parseDurationOrDefault("BREAKER_INTERVAL", time.Minute),
+			Timeout:      parseDurationOrDefault("BREAKER_TIMEOUT", 30*time.Second),
+			FailureRatio: parseFloatOrDefault("BREAKER_FAILURE_RATIO", 0.6),
+			Window:       getEnvOrDefault("BREAKER_WINDOW", "fixed"),
+			WindowSize:   parseIntOrDefault("BREAKER_WINDOW_SIZE", 100),
+		},
+		Worker: WorkerConfig{
+			Count:             parseIntOrDefault("WORKER_COUNT", 10),
+			Name:              getEnvOrDefault("WORKER_NAME", hostname()),
+			PollInterval:      parseDurationOrDefault("JOB_POLL_INTERVAL", time.Second),
+			VisibilityTimeout: parseDurationOrDefault("JOB_VISIBILITY_TIMEOUT", 5*time.Minute),
+			MaxAttempts:       parseIntOrDefault("JOB_MAX_ATTEMPTS", 5),
+			Retention:         parseDurationOrDefault("JOB_RETENTION", 24*time.Hour),
+		},
 	}
 
 	return cfg, nil
@@ -129,6 +316,32 @@ func parseIntOrDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func parseBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func parseFloatOrDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return name
+}
+
 func parseDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := time.ParseDuration(value); err == nil {
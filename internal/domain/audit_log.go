@@ -36,6 +36,8 @@ const (
 	EntityTypeUser        = "user"
 	EntityTypeTransaction = "transaction"
 	EntityTypeBalance     = "balance"
+	EntityTypeHold        = "hold"
+	EntityTypeSchedule    = "schedule"
 
 	// Actions
 	ActionCreate   = "create"
@@ -46,6 +48,11 @@ const (
 	ActionCredit   = "credit"
 	ActionDebit    = "debit"
 	ActionTransfer = "transfer"
+	ActionReverse  = "reverse"
+	ActionHold     = "hold"
+	ActionCapture  = "capture"
+	ActionVoid     = "void"
+	ActionExpire   = "expire"
 )
 
 // NewAuditLog creates a new audit log entry
@@ -151,19 +158,84 @@ type UserAuditDetails struct {
 type TransactionAuditDetails struct {
 	FromUserID  *uuid.UUID `json:"from_user_id,omitempty"`
 	ToUserID    *uuid.UUID `json:"to_user_id,omitempty"`
-	Amount      float64    `json:"amount"`
+	Amount      Money      `json:"amount"`
+	Currency    Currency   `json:"currency,omitempty"`
 	Type        string     `json:"type"`
 	Status      string     `json:"status,omitempty"`
 	OldStatus   string     `json:"old_status,omitempty"`
 	Description string     `json:"description,omitempty"`
 	ReferenceID string     `json:"reference_id,omitempty"`
+
+	ToCurrency   *Currency  `json:"to_currency,omitempty"`
+	ToAmount     *Money     `json:"to_amount,omitempty"`
+	FXRate       *string    `json:"fx_rate,omitempty"`
+	FXSpread     *string    `json:"fx_spread,omitempty"`
+	FXRateAt     *time.Time `json:"fx_rate_at,omitempty"`
+	FXRateSource *string    `json:"fx_rate_source,omitempty"`
+
+	ReversesTransactionID *uuid.UUID `json:"reverses_transaction_id,omitempty"`
+	ReversalTransactionID *uuid.UUID `json:"reversal_transaction_id,omitempty"`
+	ReversedAmount        *Money     `json:"reversed_amount,omitempty"`
+	Reason                string     `json:"reason,omitempty"`
 }
 
 // BalanceAuditDetails represents audit details for balance operations
 type BalanceAuditDetails struct {
 	UserID         uuid.UUID  `json:"user_id"`
-	Amount         float64    `json:"amount"`
-	PreviousAmount float64    `json:"previous_amount"`
+	Currency       Currency   `json:"currency,omitempty"`
+	Amount         Money      `json:"amount"`
+	PreviousAmount Money      `json:"previous_amount"`
 	TransactionID  *uuid.UUID `json:"transaction_id,omitempty"`
 	Operation      string     `json:"operation"`
+	FXRate         *string    `json:"fx_rate,omitempty"`
+}
+
+// HoldAuditDetails represents audit details for hold operations
+type HoldAuditDetails struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	Currency       Currency   `json:"currency"`
+	Amount         Money      `json:"amount"`
+	CapturedAmount Money      `json:"captured_amount,omitempty"`
+	Status         string     `json:"status"`
+	OldStatus      string     `json:"old_status,omitempty"`
+	TransactionID  *uuid.UUID `json:"transaction_id,omitempty"`
+	HeldAmount     Money      `json:"held_amount"`
+	Available      Money      `json:"available"`
+}
+
+// NewHoldAuditDetails builds audit details from a hold and the balance it reserves
+func NewHoldAuditDetails(h *Hold, balance *Balance) HoldAuditDetails {
+	return HoldAuditDetails{
+		UserID:         h.UserID,
+		Currency:       h.Currency,
+		Amount:         h.Amount,
+		CapturedAmount: h.CapturedAmount,
+		Status:         string(h.Status),
+		TransactionID:  h.TransactionID,
+		HeldAmount:     balance.HeldAmount,
+		Available:      balance.GetAvailable(),
+	}
+}
+
+// NewTransactionAuditDetails builds audit details from a transaction, including
+// any currency conversion that was applied to it
+func NewTransactionAuditDetails(t *Transaction) TransactionAuditDetails {
+	return TransactionAuditDetails{
+		FromUserID:   t.FromUserID,
+		ToUserID:     t.ToUserID,
+		Amount:       t.Amount,
+		Currency:     t.Currency,
+		Type:         string(t.Type),
+		Status:       string(t.Status),
+		Description:  t.Description,
+		ReferenceID:  t.ReferenceID,
+		ToCurrency:   t.ToCurrency,
+		ToAmount:     t.ToAmount,
+		FXRate:       t.FXRate,
+		FXSpread:     t.FXSpread,
+		FXRateAt:     t.FXRateAt,
+		FXRateSource: t.FXRateSource,
+
+		ReversesTransactionID: t.ReversesTransactionID,
+	}
 }
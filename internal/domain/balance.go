@@ -11,7 +11,9 @@ import (
 
 type Balance struct {
 	UserID        uuid.UUID    `json:"user_id" db:"user_id"`
-	Amount        float64      `json:"amount" db:"amount"`
+	Currency      Currency     `json:"currency" db:"currency"`
+	Amount        Money        `json:"amount" db:"amount"`
+	HeldAmount    Money        `json:"held_amount" db:"held_amount"`
 	LastUpdatedAt time.Time    `json:"last_updated_at" db:"last_updated_at"`
 	Version       int64        `json:"version" db:"version"`
 	mu            sync.RWMutex `json:"-"`
@@ -20,38 +22,45 @@ type Balance struct {
 type BalanceHistory struct {
 	ID             uuid.UUID `json:"id" db:"id"`
 	UserID         uuid.UUID `json:"user_id" db:"user_id"`
-	Amount         float64   `json:"amount" db:"amount"`
-	PreviousAmount float64   `json:"previous_amount" db:"previous_amount"`
+	Currency       Currency  `json:"currency" db:"currency"`
+	Amount         Money     `json:"amount" db:"amount"`
+	PreviousAmount Money     `json:"previous_amount" db:"previous_amount"`
 	TransactionID  uuid.UUID `json:"transaction_id" db:"transaction_id"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
 
 type BalanceSnapshot struct {
 	UserID    uuid.UUID `json:"user_id"`
-	Amount    float64   `json:"amount"`
+	Currency  Currency  `json:"currency"`
+	Amount    Money     `json:"amount"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
-// NewBalance creates a new balance for a user
-func NewBalance(userID uuid.UUID) *Balance {
+// NewBalance creates a new balance (wallet) for a user in the given currency
+func NewBalance(userID uuid.UUID, currency Currency) *Balance {
 	return &Balance{
 		UserID:        userID,
-		Amount:        0.0,
+		Currency:      currency,
+		Amount:        0,
 		LastUpdatedAt: time.Now(),
 		Version:       1,
 	}
 }
 
 // Credit adds amount to the balance (thread-safe)
-func (b *Balance) Credit(amount float64) error {
+func (b *Balance) Credit(amount Money) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if amount <= 0 {
+	if !amount.IsPositive() {
 		return fmt.Errorf("credit amount must be positive")
 	}
 
-	b.Amount += amount
+	if b.Amount.Add(amount) > MaxMoney {
+		return ErrAmountOverflow
+	}
+
+	b.Amount = b.Amount.Add(amount)
 	b.LastUpdatedAt = time.Now()
 	b.Version++
 
@@ -59,19 +68,46 @@ func (b *Balance) Credit(amount float64) error {
 }
 
 // Debit subtracts amount from the balance (thread-safe)
-func (b *Balance) Debit(amount float64) error {
+func (b *Balance) Debit(amount Money) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !amount.IsPositive() {
+		return fmt.Errorf("debit amount must be positive")
+	}
+
+	if b.available() < amount {
+		return fmt.Errorf("insufficient balance: have %s available, need %s", b.available(), amount)
+	}
+
+	b.Amount = b.Amount.Sub(amount)
+	b.LastUpdatedAt = time.Now()
+	b.Version++
+
+	return nil
+}
+
+// DebitHeld releases held and debits amount in one step, so a captured hold
+// pays for the debit with the funds it reserved (thread-safe). With nothing
+// held it is a plain Debit.
+func (b *Balance) DebitHeld(held, amount Money) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if amount <= 0 {
+	if !amount.IsPositive() {
 		return fmt.Errorf("debit amount must be positive")
 	}
 
-	if b.Amount < amount {
-		return fmt.Errorf("insufficient balance: have %.2f, need %.2f", b.Amount, amount)
+	if held.IsNegative() || held > b.HeldAmount {
+		return fmt.Errorf("cannot release %s, only %s is held", held, b.HeldAmount)
+	}
+
+	if b.available().Add(held) < amount {
+		return fmt.Errorf("insufficient balance: have %s available, need %s", b.available().Add(held), amount)
 	}
 
-	b.Amount -= amount
+	b.HeldAmount = b.HeldAmount.Sub(held)
+	b.Amount = b.Amount.Sub(amount)
 	b.LastUpdatedAt = time.Now()
 	b.Version++
 
@@ -79,17 +115,68 @@ func (b *Balance) Debit(amount float64) error {
 }
 
 // GetAmount returns the current balance amount (thread-safe)
-func (b *Balance) GetAmount() float64 {
+func (b *Balance) GetAmount() Money {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 	return b.Amount
 }
 
-// HasSufficientBalance checks if balance is sufficient for the given amount (thread-safe)
-func (b *Balance) HasSufficientBalance(amount float64) bool {
+// GetAvailable returns the amount not reserved by holds (thread-safe)
+func (b *Balance) GetAvailable() Money {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.available()
+}
+
+func (b *Balance) available() Money {
+	return b.Amount.Sub(b.HeldAmount)
+}
+
+// HasSufficientBalance checks if the available balance is sufficient for the given amount (thread-safe)
+func (b *Balance) HasSufficientBalance(amount Money) bool {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.Amount >= amount
+	return b.available() >= amount
+}
+
+// PlaceHold reserves amount of the available balance (thread-safe)
+func (b *Balance) PlaceHold(amount Money) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !amount.IsPositive() {
+		return fmt.Errorf("hold amount must be positive")
+	}
+
+	if b.available() < amount {
+		return fmt.Errorf("insufficient balance: have %s available, need %s", b.available(), amount)
+	}
+
+	b.HeldAmount = b.HeldAmount.Add(amount)
+	b.LastUpdatedAt = time.Now()
+	b.Version++
+
+	return nil
+}
+
+// ReleaseHold returns a reserved amount to the available balance (thread-safe)
+func (b *Balance) ReleaseHold(amount Money) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !amount.IsPositive() {
+		return fmt.Errorf("hold amount must be positive")
+	}
+
+	if b.HeldAmount < amount {
+		return fmt.Errorf("cannot release %s, only %s is held", amount, b.HeldAmount)
+	}
+
+	b.HeldAmount = b.HeldAmount.Sub(amount)
+	b.LastUpdatedAt = time.Now()
+	b.Version++
+
+	return nil
 }
 
 // GetSnapshot returns a snapshot of the current balance (thread-safe)
@@ -99,20 +186,25 @@ func (b *Balance) GetSnapshot() BalanceSnapshot {
 
 	return BalanceSnapshot{
 		UserID:    b.UserID,
+		Currency:  b.Currency,
 		Amount:    b.Amount,
 		Timestamp: b.LastUpdatedAt,
 	}
 }
 
 // SetAmount sets the balance amount directly (thread-safe) - use with caution
-func (b *Balance) SetAmount(amount float64) error {
+func (b *Balance) SetAmount(amount Money) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if amount < 0 {
+	if amount.IsNegative() {
 		return fmt.Errorf("balance amount cannot be negative")
 	}
 
+	if amount < b.HeldAmount {
+		return fmt.Errorf("balance amount cannot be less than the held amount %s", b.HeldAmount)
+	}
+
 	b.Amount = amount
 	b.LastUpdatedAt = time.Now()
 	b.Version++
@@ -122,9 +214,18 @@ func (b *Balance) SetAmount(amount float64) error {
 
 // Validate validates the balance
 func (b *Balance) Validate() error {
-	if b.Amount < 0 {
+	if !b.Currency.IsValid() {
+		return fmt.Errorf("unsupported currency: %s", b.Currency)
+	}
+	if b.Amount.IsNegative() {
 		return fmt.Errorf("balance amount cannot be negative")
 	}
+	if b.Amount > MaxMoney {
+		return ErrAmountOverflow
+	}
+	if b.HeldAmount.IsNegative() || b.HeldAmount > b.Amount {
+		return fmt.Errorf("held amount must be between 0 and the balance amount")
+	}
 	return nil
 }
 
@@ -132,7 +233,7 @@ func (b *Balance) Validate() error {
 func (b *Balance) IsEmpty() bool {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.Amount == 0
+	return b.Amount.IsZero()
 }
 
 // MarshalJSON customizes JSON marshaling (thread-safe)
@@ -140,19 +241,23 @@ func (b *Balance) MarshalJSON() ([]byte, error) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
+	// amount is the ledger amount; available is what can still be spent
 	type Alias Balance
 	return json.Marshal(&struct {
 		*Alias
+		Available Money `json:"available"`
 	}{
-		Alias: (*Alias)(b),
+		Alias:     (*Alias)(b),
+		Available: b.available(),
 	})
 }
 
 // NewBalanceHistory creates a new balance history entry
-func NewBalanceHistory(userID, transactionID uuid.UUID, newAmount, previousAmount float64) *BalanceHistory {
+func NewBalanceHistory(userID, transactionID uuid.UUID, currency Currency, newAmount, previousAmount Money) *BalanceHistory {
 	return &BalanceHistory{
 		ID:             uuid.New(),
 		UserID:         userID,
+		Currency:       currency,
 		Amount:         newAmount,
 		PreviousAmount: previousAmount,
 		TransactionID:  transactionID,
@@ -163,7 +268,7 @@ func NewBalanceHistory(userID, transactionID uuid.UUID, newAmount, previousAmoun
 // BalanceOperation represents an atomic balance operation
 type BalanceOperation struct {
 	UserID    uuid.UUID
-	Amount    float64
+	Amount    Money
 	Operation string // "credit" or "debit"
 }
 
@@ -190,7 +295,7 @@ func (bb *BalanceBatch) Validate() error {
 	}
 
 	for i, op := range bb.Operations {
-		if op.Amount <= 0 {
+		if !op.Amount.IsPositive() {
 			return fmt.Errorf("operation %d: amount must be positive", i)
 		}
 		if op.Operation != "credit" && op.Operation != "debit" {
@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit bounds how far ahead Next looks for a matching time
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// CronExpression is a standard five-field cron expression:
+// minute hour day-of-month month day-of-week. Each field accepts "*", values,
+// ranges ("1-5"), lists ("1,15") and steps ("*/15", "0-30/10"). Day of week
+// runs from 0 (Sunday) to 6; 7 is accepted as Sunday too. As in cron, when both
+// day fields are restricted a time matches if either of them does.
+type CronExpression struct {
+	expr       string
+	minute     cronField
+	hour       cronField
+	dayOfMonth cronField
+	month      cronField
+	dayOfWeek  cronField
+	// domAny and dowAny record whether the day fields were "*"
+	domAny bool
+	dowAny bool
+}
+
+// cronField is a bit set of the values a field matches
+type cronField uint64
+
+func (f cronField) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+// ParseCronExpression parses a five-field cron expression
+func ParseCronExpression(expr string) (*CronExpression, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &CronExpression{
+		expr:   expr,
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid cron minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid cron hour: %w", err)
+	}
+	if c.dayOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid cron day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid cron month: %w", err)
+	}
+	if c.dayOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid cron day of week: %w", err)
+	}
+
+	// Sunday may be written as 0 or 7
+	if c.dayOfWeek.has(7) {
+		c.dayOfWeek |= 1
+	}
+
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (cronField, error) {
+	var set cronField
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			parsed, err := strconv.Atoi(part[i+1:])
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = parsed
+		}
+
+		start, end := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			start = value
+			// "5/10" means every 10 starting at 5
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+
+	return set, nil
+}
+
+// Next returns the first matching time strictly after t, in t's location.
+// It returns the zero time if nothing matches within five years, which only
+// happens for impossible dates such as "0 0 30 2 *".
+func (c *CronExpression) Next(t time.Time) time.Time {
+	loc := t.Location()
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.Add(cronSearchLimit)
+
+	for next.Before(limit) {
+		if !c.month.has(int(next.Month())) {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.hour.has(next.Hour()) {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !c.minute.has(next.Minute()) {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+func (c *CronExpression) matchesDay(t time.Time) bool {
+	domMatch := c.dayOfMonth.has(t.Day())
+	dowMatch := c.dayOfWeek.has(int(t.Weekday()))
+
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowMatch
+	case c.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// String returns the expression as it was parsed
+func (c *CronExpression) String() string {
+	return c.expr
+}
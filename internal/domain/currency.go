@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency is an ISO-4217 alphabetic currency code
+type Currency string
+
+// DefaultCurrency is used when a request does not specify a currency
+const DefaultCurrency Currency = "USD"
+
+// supportedCurrencies lists the ISO-4217 codes accepted by the system.
+// Only currencies with two minor units are supported because Money has a fixed scale of 2.
+var supportedCurrencies = map[Currency]struct{}{
+	"AED": {}, "AUD": {}, "BGN": {}, "BRL": {}, "CAD": {}, "CHF": {},
+	"CNY": {}, "CZK": {}, "DKK": {}, "EUR": {}, "GBP": {}, "HKD": {},
+	"INR": {}, "MXN": {}, "NOK": {}, "NZD": {}, "PLN": {}, "RON": {},
+	"SAR": {}, "SEK": {}, "SGD": {}, "TRY": {}, "USD": {}, "ZAR": {},
+}
+
+// ParseCurrency normalizes and validates a currency code.
+// An empty code resolves to DefaultCurrency.
+func ParseCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+
+	currency := Currency(code)
+	if !currency.IsValid() {
+		return "", fmt.Errorf("unsupported currency: %s", code)
+	}
+
+	return currency, nil
+}
+
+// IsValid checks if the currency is a supported ISO-4217 code
+func (c Currency) IsValid() bool {
+	_, ok := supportedCurrencies[c]
+	return ok
+}
+
+// String returns the currency code
+func (c Currency) String() string {
+	return string(c)
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ExchangeRateScale is the number of decimal places kept for stored rates
+const ExchangeRateScale = 10
+
+// ExchangeRate is a quoted conversion rate from one currency into another.
+// Rate and Spread are decimal strings so they can be stored and audited exactly.
+type ExchangeRate struct {
+	From      Currency  `json:"from"`
+	To        Currency  `json:"to"`
+	Rate      string    `json:"rate"`
+	Spread    string    `json:"spread,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source,omitempty"`
+}
+
+// Validate validates the exchange rate quote
+func (r ExchangeRate) Validate() error {
+	if !r.From.IsValid() {
+		return fmt.Errorf("unsupported currency: %s", r.From)
+	}
+	if !r.To.IsValid() {
+		return fmt.Errorf("unsupported currency: %s", r.To)
+	}
+	if r.From == r.To {
+		return fmt.Errorf("exchange rate currencies must differ")
+	}
+	if r.Timestamp.IsZero() {
+		return fmt.Errorf("exchange rate timestamp is required")
+	}
+	_, err := r.EffectiveRate()
+	return err
+}
+
+// EffectiveRate returns the rate after the spread has been deducted: rate * (1 - spread)
+func (r ExchangeRate) EffectiveRate() (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid exchange rate: %q", r.Rate)
+	}
+
+	if r.Spread == "" {
+		return rate, nil
+	}
+
+	spread, ok := new(big.Rat).SetString(r.Spread)
+	if !ok || spread.Sign() < 0 || spread.Cmp(big.NewRat(1, 1)) >= 0 {
+		return nil, fmt.Errorf("invalid exchange rate spread: %q", r.Spread)
+	}
+
+	factor := new(big.Rat).Sub(big.NewRat(1, 1), spread)
+	return rate.Mul(rate, factor), nil
+}
+
+// Convert converts an amount in From currency into To currency using the
+// effective rate, rounding half away from zero to the nearest cent
+func (r ExchangeRate) Convert(amount Money) (Money, error) {
+	rate, err := r.EffectiveRate()
+	if err != nil {
+		return 0, err
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Cents()), rate)
+	cents := roundRat(converted)
+	if !cents.IsInt64() || Money(cents.Int64()) > MaxMoney {
+		return 0, ErrAmountOverflow
+	}
+
+	return Money(cents.Int64()), nil
+}
+
+// Inverse returns the quote for the opposite direction
+func (r ExchangeRate) Inverse() (ExchangeRate, error) {
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok || rate.Sign() <= 0 {
+		return ExchangeRate{}, fmt.Errorf("invalid exchange rate: %q", r.Rate)
+	}
+
+	return ExchangeRate{
+		From:      r.To,
+		To:        r.From,
+		Rate:      new(big.Rat).Inv(rate).FloatString(ExchangeRateScale),
+		Spread:    r.Spread,
+		Timestamp: r.Timestamp,
+		Source:    r.Source,
+	}, nil
+}
+
+// roundRat rounds a rational number half away from zero
+func roundRat(v *big.Rat) *big.Int {
+	num := new(big.Int).Abs(v.Num())
+	den := v.Denom()
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+
+	if v.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return quo
+}
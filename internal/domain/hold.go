@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type HoldStatus string
+
+const (
+	// HoldStatusActive reserves funds on the balance
+	HoldStatusActive HoldStatus = "active"
+	// HoldStatusCaptured has been turned into a debit or transfer. The funds
+	// stay reserved until that transaction is processed.
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusVoided   HoldStatus = "voided"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+// DefaultHoldTTL is how long a hold lasts when no expiry is requested
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+// Hold reserves part of a user's balance without moving it. An active hold
+// lowers the available balance until it is captured, voided or expires.
+type Hold struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	Currency       Currency   `json:"currency" db:"currency"`
+	Amount         Money      `json:"amount" db:"amount"`
+	CapturedAmount Money      `json:"captured_amount" db:"captured_amount"`
+	Status         HoldStatus `json:"status" db:"status"`
+	Description    string     `json:"description" db:"description"`
+	ReferenceID    string     `json:"reference_id" db:"reference_id"`
+	TransactionID  *uuid.UUID `json:"transaction_id,omitempty" db:"transaction_id"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type CreateHoldRequest struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	Amount      Money      `json:"amount"`
+	Currency    string     `json:"currency,omitempty"`
+	Description string     `json:"description"`
+	ReferenceID string     `json:"reference_id"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+type CaptureHoldRequest struct {
+	// Amount to capture; zero captures the whole hold
+	Amount Money `json:"amount,omitempty"`
+	// ToUserID turns the capture into a transfer instead of a debit
+	ToUserID    *uuid.UUID `json:"to_user_id,omitempty"`
+	Description string     `json:"description"`
+}
+
+// NewHold creates an active hold
+func NewHold(userID uuid.UUID, currency Currency, amount Money, expiresAt time.Time, description, referenceID string) (*Hold, error) {
+	now := time.Now()
+	hold := &Hold{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Currency:    currency,
+		Amount:      amount,
+		Status:      HoldStatusActive,
+		Description: description,
+		ReferenceID: referenceID,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := hold.Validate(); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// Validate validates the hold
+func (h *Hold) Validate() error {
+	if !h.Amount.IsPositive() {
+		return fmt.Errorf("hold amount must be greater than 0")
+	}
+	if h.Amount > MaxMoney {
+		return ErrAmountOverflow
+	}
+	if !h.Currency.IsValid() {
+		return fmt.Errorf("unsupported currency: %s", h.Currency)
+	}
+	if h.CapturedAmount.IsNegative() || h.CapturedAmount > h.Amount {
+		return fmt.Errorf("captured amount must be between 0 and the hold amount")
+	}
+	if !h.ExpiresAt.After(h.CreatedAt) {
+		return fmt.Errorf("hold must expire in the future")
+	}
+	return nil
+}
+
+// IsActive checks if the hold still reserves funds and can be captured or voided
+func (h *Hold) IsActive() bool {
+	return h.Status == HoldStatusActive
+}
+
+// IsExpired checks if an active hold has passed its expiry
+func (h *Hold) IsExpired(now time.Time) bool {
+	return h.IsActive() && !now.Before(h.ExpiresAt)
+}
+
+// Capture records that amount of the hold is being settled by transactionID.
+// The remainder of a partial capture is released with the rest of the hold.
+func (h *Hold) Capture(amount Money, transactionID uuid.UUID) error {
+	if !h.IsActive() {
+		return fmt.Errorf("hold cannot be captured, current status: %s", h.Status)
+	}
+	if h.IsExpired(time.Now()) {
+		return fmt.Errorf("hold expired at %s", h.ExpiresAt.Format(time.RFC3339))
+	}
+	if !amount.IsPositive() {
+		return fmt.Errorf("capture amount must be greater than 0")
+	}
+	if amount > h.Amount {
+		return fmt.Errorf("capture amount %s exceeds hold amount %s", amount, h.Amount)
+	}
+
+	h.Status = HoldStatusCaptured
+	h.CapturedAmount = amount
+	h.TransactionID = &transactionID
+	h.UpdatedAt = time.Now()
+	return nil
+}
+
+// Reopen makes a captured hold active again after its capture failed
+func (h *Hold) Reopen() {
+	h.Status = HoldStatusActive
+	h.CapturedAmount = 0
+	h.TransactionID = nil
+	h.UpdatedAt = time.Now()
+}
+
+// Void releases an active hold
+func (h *Hold) Void() error {
+	if !h.IsActive() {
+		return fmt.Errorf("hold cannot be voided, current status: %s", h.Status)
+	}
+
+	h.Status = HoldStatusVoided
+	h.UpdatedAt = time.Now()
+	return nil
+}
+
+// Expire releases an active hold that has passed its expiry
+func (h *Hold) Expire() error {
+	if !h.IsExpired(time.Now()) {
+		return fmt.Errorf("hold has not expired, status: %s, expires at: %s", h.Status, h.ExpiresAt.Format(time.RFC3339))
+	}
+
+	h.Status = HoldStatusExpired
+	h.UpdatedAt = time.Now()
+	return nil
+}
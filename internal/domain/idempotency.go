@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key accepted from clients
+const MaxIdempotencyKeyLength = 255
+
+type IdempotencyStatus string
+
+const (
+	// IdempotencyStatusInProgress is claimed by a request whose change was
+	// committed, or is being made, and whose response is not stored yet
+	IdempotencyStatusInProgress IdempotencyStatus = "in_progress"
+	IdempotencyStatusCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyRecord stores the response of a completed request so that a
+// retry with the same key can be answered without repeating its side effects
+type IdempotencyRecord struct {
+	UserID       uuid.UUID         `json:"user_id" db:"user_id"`
+	Key          string            `json:"key" db:"idempotency_key"`
+	Method       string            `json:"method" db:"method"`
+	Path         string            `json:"path" db:"path"`
+	RequestHash  string            `json:"request_hash" db:"request_hash"`
+	Status       IdempotencyStatus `json:"status" db:"status"`
+	StatusCode   int               `json:"status_code" db:"status_code"`
+	ContentType  string            `json:"content_type" db:"content_type"`
+	ResponseBody []byte            `json:"response_body" db:"response_body"`
+	CreatedAt    time.Time         `json:"created_at" db:"created_at"`
+}
+
+// NewIdempotencyRecord creates a record for a completed request
+func NewIdempotencyRecord(userID uuid.UUID, key, method, path, requestHash string, statusCode int, contentType string, responseBody []byte) *IdempotencyRecord {
+	return &IdempotencyRecord{
+		UserID:       userID,
+		Key:          key,
+		Method:       method,
+		Path:         path,
+		RequestHash:  requestHash,
+		Status:       IdempotencyStatusCompleted,
+		StatusCode:   statusCode,
+		ContentType:  contentType,
+		ResponseBody: responseBody,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// NewIdempotencyClaim creates the in progress record of a request that has
+// not completed yet
+func NewIdempotencyClaim(userID uuid.UUID, key, method, path, requestHash string) *IdempotencyRecord {
+	return &IdempotencyRecord{
+		UserID:       userID,
+		Key:          key,
+		Method:       method,
+		Path:         path,
+		RequestHash:  requestHash,
+		Status:       IdempotencyStatusInProgress,
+		ResponseBody: []byte{},
+		CreatedAt:    time.Now(),
+	}
+}
+
+// HashRequest fingerprints a request so replays with a different body can be detected
+func HashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{' '})
+	h.Write([]byte(path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// IsCompleted checks if the record holds the response of its request
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.Status == IdempotencyStatusCompleted
+}
+
+// Matches checks if the record was created for the same request
+func (r *IdempotencyRecord) Matches(requestHash string) bool {
+	return r.RequestHash == requestHash
+}
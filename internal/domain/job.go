@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "pending"
+	// JobStatusRunning is leased by a worker until LockedUntil
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	// JobStatusFailed failed with an error retrying cannot fix
+	JobStatusFailed JobStatus = "failed"
+	// JobStatusDead ran out of attempts and is dead-lettered
+	JobStatusDead JobStatus = "dead"
+)
+
+// Job is a unit of background work in the persistent job queue. Payload is
+// interpreted by the handler of its Type.
+type Job struct {
+	ID uuid.UUID `json:"id" db:"id"`
+	// Key identifies the work, so that it is queued only once while unfinished
+	Key         string          `json:"key" db:"key"`
+	Type        string          `json:"type" db:"type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      JobStatus       `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty" db:"last_error"`
+	ScheduledAt time.Time       `json:"scheduled_at" db:"scheduled_at"`
+	// LockedBy names the worker holding the lease of a running job
+	LockedBy    string     `json:"locked_by,omitempty" db:"locked_by"`
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	// LeaseID changes with every claim, so a worker whose lease expired
+	// cannot overwrite the outcome of the worker that claimed the job next
+	LeaseID     *uuid.UUID `json:"-" db:"lease_id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// NewJob creates a pending job due at scheduledAt
+func NewJob(jobType, key string, payload interface{}, maxAttempts int, scheduledAt time.Time) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s job payload: %w", jobType, err)
+	}
+	if maxAttempts <= 0 {
+		return nil, fmt.Errorf("max attempts must be positive")
+	}
+
+	now := time.Now()
+	return &Job{
+		ID:          uuid.New(),
+		Key:         key,
+		Type:        jobType,
+		Payload:     data,
+		Status:      JobStatusPending,
+		MaxAttempts: maxAttempts,
+		ScheduledAt: scheduledAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// LeaseExpired reports whether a claimed job was already started as often as
+// it may be, by workers that never reported back
+func (j *Job) LeaseExpired() bool {
+	return j.Attempts > j.MaxAttempts
+}
+
+// ExtendLease keeps a running job leased until lockedUntil
+func (j *Job) ExtendLease(lockedUntil, now time.Time) {
+	j.LockedUntil = &lockedUntil
+	j.UpdatedAt = now
+}
+
+// MarkCompleted records a successful attempt
+func (j *Job) MarkCompleted(now time.Time) {
+	j.Status = JobStatusCompleted
+	j.LastError = ""
+	j.release(now)
+	j.CompletedAt = &now
+}
+
+// MarkAttemptFailed records a failed attempt. The job is scheduled again
+// after backoff unless the failure is permanent, in which case it fails, or
+// it has run out of attempts, in which case it becomes dead.
+func (j *Job) MarkAttemptFailed(err error, permanent bool, now time.Time, backoff time.Duration) {
+	j.LastError = err.Error()
+	j.release(now)
+
+	switch {
+	case permanent:
+		j.Status = JobStatusFailed
+		j.CompletedAt = &now
+	case j.Attempts >= j.MaxAttempts:
+		j.Status = JobStatusDead
+		j.CompletedAt = &now
+	default:
+		j.Status = JobStatusPending
+		j.ScheduledAt = now.Add(backoff)
+	}
+}
+
+// Requeue queues a dead job again with a fresh retry budget
+func (j *Job) Requeue(now time.Time) error {
+	if j.Status != JobStatusDead {
+		return fmt.Errorf("job is %s, only dead jobs can be requeued", j.Status)
+	}
+
+	j.Status = JobStatusPending
+	j.Attempts = 0
+	j.LastError = ""
+	j.ScheduledAt = now
+	j.UpdatedAt = now
+	j.CompletedAt = nil
+	return nil
+}
+
+func (j *Job) release(now time.Time) {
+	j.LockedBy = ""
+	j.LockedUntil = nil
+	j.UpdatedAt = now
+}
+
+// JobAttempt records the outcome of one attempt at a job
+type JobAttempt struct {
+	ID      uuid.UUID `json:"id" db:"id"`
+	JobID   uuid.UUID `json:"job_id" db:"job_id"`
+	Attempt int       `json:"attempt" db:"attempt"`
+	// Worker names the worker that made the attempt
+	Worker     string    `json:"worker" db:"worker"`
+	Error      string    `json:"error,omitempty" db:"error"`
+	DurationMs int64     `json:"duration_ms" db:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// NewJobAttempt records the outcome of an attempt at a claimed job that took
+// duration
+func NewJobAttempt(j *Job, err error, duration time.Duration) *JobAttempt {
+	attempt := &JobAttempt{
+		ID:         uuid.New(),
+		JobID:      j.ID,
+		Attempt:    j.Attempts,
+		Worker:     j.LockedBy,
+		DurationMs: duration.Milliseconds(),
+		CreatedAt:  time.Now(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	return attempt
+}
+
+type JobDeadLetterResolution string
+
+const (
+	JobDeadLetterRequeued  JobDeadLetterResolution = "requeued"
+	JobDeadLetterDiscarded JobDeadLetterResolution = "discarded"
+)
+
+// JobDeadLetter keeps a job that ran out of attempts until it is requeued or
+// discarded
+type JobDeadLetter struct {
+	ID         uuid.UUID                `json:"id" db:"id"`
+	JobID      uuid.UUID                `json:"job_id" db:"job_id"`
+	Key        string                   `json:"key" db:"key"`
+	Type       string                   `json:"type" db:"type"`
+	Payload    json.RawMessage          `json:"payload" db:"payload"`
+	Attempts   int                      `json:"attempts" db:"attempts"`
+	LastError  string                   `json:"last_error" db:"last_error"`
+	CreatedAt  time.Time                `json:"created_at" db:"created_at"`
+	Resolution *JobDeadLetterResolution `json:"resolution,omitempty" db:"resolution"`
+	ResolvedAt *time.Time               `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+// NewJobDeadLetter creates the dead letter of a dead job
+func NewJobDeadLetter(j *Job) *JobDeadLetter {
+	return &JobDeadLetter{
+		ID:        uuid.New(),
+		JobID:     j.ID,
+		Key:       j.Key,
+		Type:      j.Type,
+		Payload:   j.Payload,
+		Attempts:  j.Attempts,
+		LastError: j.LastError,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,219 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerAccountType classifies ledger accounts
+type LedgerAccountType string
+
+const (
+	// LedgerAccountUser holds a user's wallet in one currency
+	LedgerAccountUser LedgerAccountType = "user"
+	// LedgerAccountExternal is the counterparty for money entering or leaving the system
+	LedgerAccountExternal LedgerAccountType = "external"
+	// LedgerAccountFX is the clearing account for currency conversions
+	LedgerAccountFX LedgerAccountType = "fx"
+)
+
+// LedgerAccount is an account in the double-entry ledger. A user account's
+// balance is the sum of its postings.
+type LedgerAccount struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	Code      string            `json:"code" db:"code"`
+	Type      LedgerAccountType `json:"type" db:"type"`
+	UserID    *uuid.UUID        `json:"user_id,omitempty" db:"user_id"`
+	Currency  Currency          `json:"currency" db:"currency"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+}
+
+// JournalEntry groups the postings of one business event. The postings of
+// an entry always sum to zero per currency.
+type JournalEntry struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" db:"transaction_id"`
+	Description   string     `json:"description" db:"description"`
+	Postings      []*Posting `json:"postings"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Posting moves an amount into (positive) or out of (negative) a ledger account
+type Posting struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	JournalEntryID uuid.UUID         `json:"journal_entry_id" db:"journal_entry_id"`
+	AccountID      uuid.UUID         `json:"account_id" db:"account_id"`
+	AccountType    LedgerAccountType `json:"account_type" db:"account_type"`
+	UserID         *uuid.UUID        `json:"user_id,omitempty" db:"user_id"`
+	Currency       Currency          `json:"currency" db:"currency"`
+	Amount         Money             `json:"amount" db:"amount"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+}
+
+// LedgerBalance is the balance of a user account derived from its postings
+type LedgerBalance struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Currency Currency  `json:"currency"`
+	Amount   Money     `json:"amount"`
+}
+
+// NewUserLedgerAccount creates the ledger account backing a user's wallet
+func NewUserLedgerAccount(userID uuid.UUID, currency Currency) *LedgerAccount {
+	return &LedgerAccount{
+		ID:        uuid.New(),
+		Code:      LedgerAccountCode(LedgerAccountUser, &userID, currency),
+		Type:      LedgerAccountUser,
+		UserID:    &userID,
+		Currency:  currency,
+		CreatedAt: time.Now(),
+	}
+}
+
+// NewSystemLedgerAccount creates an external or FX account for a currency
+func NewSystemLedgerAccount(accountType LedgerAccountType, currency Currency) *LedgerAccount {
+	return &LedgerAccount{
+		ID:        uuid.New(),
+		Code:      LedgerAccountCode(accountType, nil, currency),
+		Type:      accountType,
+		Currency:  currency,
+		CreatedAt: time.Now(),
+	}
+}
+
+// LedgerAccountCode returns the natural key of an account, e.g.
+// "user:<user_id>:USD" or "external:USD"
+func LedgerAccountCode(accountType LedgerAccountType, userID *uuid.UUID, currency Currency) string {
+	if userID != nil {
+		return fmt.Sprintf("%s:%s:%s", accountType, userID.String(), currency)
+	}
+	return fmt.Sprintf("%s:%s", accountType, currency)
+}
+
+// Account returns the ledger account the posting is made to
+func (p *Posting) Account() *LedgerAccount {
+	if p.UserID != nil {
+		return NewUserLedgerAccount(*p.UserID, p.Currency)
+	}
+	return NewSystemLedgerAccount(p.AccountType, p.Currency)
+}
+
+// NewJournalEntry creates a journal entry and validates that it balances
+func NewJournalEntry(transactionID *uuid.UUID, description string, postings ...*Posting) (*JournalEntry, error) {
+	entry := &JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: transactionID,
+		Description:   description,
+		CreatedAt:     time.Now(),
+	}
+
+	for _, posting := range postings {
+		posting.ID = uuid.New()
+		posting.JournalEntryID = entry.ID
+		posting.CreatedAt = entry.CreatedAt
+		entry.Postings = append(entry.Postings, posting)
+	}
+
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Validate checks that the entry has postings and that they sum to zero per currency
+func (e *JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("journal entry must have at least two postings")
+	}
+
+	sums := make(map[Currency]Money)
+	for _, posting := range e.Postings {
+		if !posting.Currency.IsValid() {
+			return fmt.Errorf("unsupported currency: %s", posting.Currency)
+		}
+		if posting.Amount.IsZero() {
+			return fmt.Errorf("posting amount cannot be zero")
+		}
+		if posting.AccountType == LedgerAccountUser && posting.UserID == nil {
+			return fmt.Errorf("user posting requires a user_id")
+		}
+		sums[posting.Currency] = sums[posting.Currency].Add(posting.Amount)
+	}
+
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("journal entry is not balanced: %s postings sum to %s", currency, sum)
+		}
+	}
+
+	return nil
+}
+
+// UserPosting creates a posting to a user's wallet
+func UserPosting(userID uuid.UUID, currency Currency, amount Money) *Posting {
+	return &Posting{
+		AccountType: LedgerAccountUser,
+		UserID:      &userID,
+		Currency:    currency,
+		Amount:      amount,
+	}
+}
+
+// SystemPosting creates a posting to an external or FX account
+func SystemPosting(accountType LedgerAccountType, currency Currency, amount Money) *Posting {
+	return &Posting{
+		AccountType: accountType,
+		Currency:    currency,
+		Amount:      amount,
+	}
+}
+
+// NewJournalEntryForTransaction builds the balanced postings of a transaction:
+//   - credit: external -> user
+//   - debit: user -> external
+//   - transfer: sender -> receiver, through the FX accounts when currencies differ
+func NewJournalEntryForTransaction(t *Transaction) (*JournalEntry, error) {
+	var postings []*Posting
+
+	switch t.Type {
+	case TransactionTypeCredit:
+		if t.ToUserID == nil {
+			return nil, fmt.Errorf("to_user_id is required for credit transaction")
+		}
+		postings = []*Posting{
+			SystemPosting(LedgerAccountExternal, t.Currency, t.Amount.Neg()),
+			UserPosting(*t.ToUserID, t.Currency, t.Amount),
+		}
+	case TransactionTypeDebit:
+		if t.FromUserID == nil {
+			return nil, fmt.Errorf("from_user_id is required for debit transaction")
+		}
+		postings = []*Posting{
+			UserPosting(*t.FromUserID, t.Currency, t.Amount.Neg()),
+			SystemPosting(LedgerAccountExternal, t.Currency, t.Amount),
+		}
+	case TransactionTypeTransfer:
+		if t.FromUserID == nil || t.ToUserID == nil {
+			return nil, fmt.Errorf("both from_user_id and to_user_id are required for transfer transaction")
+		}
+		if t.IsCrossCurrency() {
+			postings = []*Posting{
+				UserPosting(*t.FromUserID, t.Currency, t.Amount.Neg()),
+				SystemPosting(LedgerAccountFX, t.Currency, t.Amount),
+				SystemPosting(LedgerAccountFX, t.CreditCurrency(), t.CreditAmount().Neg()),
+				UserPosting(*t.ToUserID, t.CreditCurrency(), t.CreditAmount()),
+			}
+		} else {
+			postings = []*Posting{
+				UserPosting(*t.FromUserID, t.Currency, t.Amount.Neg()),
+				UserPosting(*t.ToUserID, t.Currency, t.Amount),
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid transaction type: %s", t.Type)
+	}
+
+	return NewJournalEntry(&t.ID, fmt.Sprintf("%s transaction %s", t.Type, t.ID), postings...)
+}
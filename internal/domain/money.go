@@ -0,0 +1,227 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact monetary amount expressed in minor units (cents).
+// It maps onto the DECIMAL(15,2) columns used by the schema and must be used
+// instead of float64 for anything that is stored, compared or summed.
+type Money int64
+
+const (
+	// MoneyScale is the number of decimal places carried by Money
+	MoneyScale = 2
+
+	// MaxMoney is the largest amount that fits into a DECIMAL(15,2) column
+	MaxMoney Money = 999999999999999
+
+	centsPerUnit = 100
+)
+
+var (
+	ErrInvalidAmount  = errors.New("invalid amount")
+	ErrSubCentAmount  = errors.New("amount cannot have more than 2 decimal places")
+	ErrAmountOverflow = errors.New("amount exceeds the maximum supported value")
+)
+
+// NewMoneyFromCents creates a Money value from an amount of minor units
+func NewMoneyFromCents(cents int64) Money {
+	return Money(cents)
+}
+
+// ParseMoney parses a decimal string such as "100", "100.5" or "-100.50".
+// Amounts with more than two decimal places are rejected rather than rounded.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, ErrInvalidAmount
+	}
+	if hasDot && fracPart == "" {
+		return 0, ErrInvalidAmount
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	// Trailing zeros beyond the scale carry no value, e.g. "1.500"
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > MoneyScale {
+		return 0, ErrSubCentAmount
+	}
+	fracPart += strings.Repeat("0", MoneyScale-len(fracPart))
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if len(intPart) > 13 {
+		return 0, ErrAmountOverflow
+	}
+
+	cents, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if Money(cents) > MaxMoney {
+		return 0, ErrAmountOverflow
+	}
+
+	if negative {
+		cents = -cents
+	}
+	return Money(cents), nil
+}
+
+// MustParseMoney is like ParseMoney but panics on error; intended for constants
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Cents returns the amount in minor units
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) Money {
+	return m + other
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) Money {
+	return m - other
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return -m
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m < 0
+}
+
+// Float64 returns an approximate float representation.
+// Only use it for metrics and logging, never for arithmetic.
+func (m Money) Float64() float64 {
+	return float64(m) / centsPerUnit
+}
+
+// String formats the amount with exactly two decimal places
+func (m Money) String() string {
+	sign := ""
+	cents := uint64(m)
+	if m < 0 {
+		sign = "-"
+		cents = uint64(-m)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/centsPerUnit, cents%centsPerUnit)
+}
+
+// MarshalJSON encodes the amount as a decimal string to avoid float rounding in clients
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON accepts either a decimal string ("10.50") or a JSON number (10.50).
+// Numbers are parsed from their literal text, never through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidAmount, s)
+		}
+		s = unquoted
+	} else if strings.ContainsAny(s, "eE") {
+		return fmt.Errorf("%w: exponent notation is not supported", ErrInvalidAmount)
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer so Money is written to DECIMAL columns exactly
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return fmt.Errorf("failed to scan money: %w", err)
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return fmt.Errorf("failed to scan money: %w", err)
+		}
+		*m = parsed
+		return nil
+	case int64:
+		*m = Money(v * centsPerUnit)
+		return nil
+	default:
+		return fmt.Errorf("failed to scan money: unsupported type %T", src)
+	}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
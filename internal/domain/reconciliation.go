@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalletState is what the different sources say a wallet should hold
+type WalletState struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Currency Currency  `json:"currency"`
+	// BalanceAmount is the stored balances row
+	BalanceAmount Money `json:"balance_amount"`
+	// TransactionAmount is recomputed from completed transactions
+	TransactionAmount Money `json:"transaction_amount"`
+	// HistoryAmount is the latest balance_history entry, if any
+	HistoryAmount *Money `json:"history_amount,omitempty"`
+}
+
+// Difference is how far the stored balance is from the completed transactions
+func (s *WalletState) Difference() Money {
+	return s.BalanceAmount.Sub(s.TransactionAmount)
+}
+
+// HasDrift checks if the stored balance disagrees with the completed transactions
+func (s *WalletState) HasDrift() bool {
+	return !s.Difference().IsZero()
+}
+
+// BalanceMismatch is a wallet whose stored balance has drifted, together with
+// the transactions whose balance updates look incomplete
+type BalanceMismatch struct {
+	WalletState
+	Drift          Money       `json:"drift"`
+	TransactionIDs []uuid.UUID `json:"transaction_ids"`
+	Repaired       bool        `json:"repaired"`
+}
+
+// ReconciliationReport is the result of one reconciliation run
+type ReconciliationReport struct {
+	UserID         *uuid.UUID         `json:"user_id,omitempty"`
+	Repair         bool               `json:"repair"`
+	WalletsChecked int                `json:"wallets_checked"`
+	Mismatches     []*BalanceMismatch `json:"mismatches"`
+	RepairedCount  int                `json:"repaired_count"`
+	StartedAt      time.Time          `json:"started_at"`
+	CompletedAt    time.Time          `json:"completed_at"`
+}
+
+// NewReconciliationReport starts a report
+func NewReconciliationReport(userID *uuid.UUID, repair bool) *ReconciliationReport {
+	return &ReconciliationReport{
+		UserID:     userID,
+		Repair:     repair,
+		Mismatches: []*BalanceMismatch{},
+		StartedAt:  time.Now(),
+	}
+}
+
+// HasMismatches checks if any drift was found
+func (r *ReconciliationReport) HasMismatches() bool {
+	return len(r.Mismatches) > 0
+}
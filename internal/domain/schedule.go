@@ -0,0 +1,251 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Recurrence string
+type ScheduleStatus string
+
+const (
+	RecurrenceNone    Recurrence = "none"
+	RecurrenceDaily   Recurrence = "daily"
+	RecurrenceWeekly  Recurrence = "weekly"
+	RecurrenceMonthly Recurrence = "monthly"
+	RecurrenceCron    Recurrence = "cron"
+)
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "active"
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+)
+
+// Schedule creates a transaction at NextRunAt, once or on a recurrence.
+// Occurrences missed while no scheduler was running are caught up one by one.
+// Cron expressions are evaluated in UTC.
+type Schedule struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	UserID      uuid.UUID       `json:"user_id" db:"user_id"`
+	FromUserID  *uuid.UUID      `json:"from_user_id,omitempty" db:"from_user_id"`
+	ToUserID    *uuid.UUID      `json:"to_user_id,omitempty" db:"to_user_id"`
+	Amount      Money           `json:"amount" db:"amount"`
+	Currency    Currency        `json:"currency" db:"currency"`
+	Type        TransactionType `json:"type" db:"type"`
+	Description string          `json:"description,omitempty" db:"description"`
+
+	Recurrence Recurrence     `json:"recurrence" db:"recurrence"`
+	CronExpr   string         `json:"cron,omitempty" db:"cron_expr"`
+	StartAt    time.Time      `json:"start_at" db:"start_at"`
+	EndAt      *time.Time     `json:"end_at,omitempty" db:"end_at"`
+	MaxRuns    *int           `json:"max_runs,omitempty" db:"max_runs"`
+	NextRunAt  *time.Time     `json:"next_run_at,omitempty" db:"next_run_at"`
+	RunCount   int            `json:"run_count" db:"run_count"`
+	Status     ScheduleStatus `json:"status" db:"status"`
+
+	LastRunAt         *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastTransactionID *uuid.UUID `json:"last_transaction_id,omitempty" db:"last_transaction_id"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type CreateScheduleRequest struct {
+	Type        string     `json:"type"`
+	FromUserID  *uuid.UUID `json:"from_user_id,omitempty"`
+	ToUserID    *uuid.UUID `json:"to_user_id,omitempty"`
+	Amount      Money      `json:"amount"`
+	Currency    string     `json:"currency,omitempty"`
+	Description string     `json:"description"`
+	// ExecuteAt is the first run; recurring schedules default to the next occurrence
+	ExecuteAt  *time.Time `json:"execute_at,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"`
+	Cron       string     `json:"cron,omitempty"`
+	EndAt      *time.Time `json:"end_at,omitempty"`
+	MaxRuns    *int       `json:"max_runs,omitempty"`
+}
+
+// NewSchedule creates an active schedule from a request
+func NewSchedule(userID uuid.UUID, req CreateScheduleRequest, now time.Time) (*Schedule, error) {
+	currency, err := ParseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	recurrence := Recurrence(req.Recurrence)
+	if recurrence == "" {
+		recurrence = RecurrenceNone
+	}
+
+	schedule := &Schedule{
+		ID:          uuid.New(),
+		UserID:      userID,
+		FromUserID:  req.FromUserID,
+		ToUserID:    req.ToUserID,
+		Amount:      req.Amount,
+		Currency:    currency,
+		Type:        TransactionType(req.Type),
+		Description: req.Description,
+		Recurrence:  recurrence,
+		CronExpr:    req.Cron,
+		EndAt:       req.EndAt,
+		MaxRuns:     req.MaxRuns,
+		Status:      ScheduleStatusActive,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	switch {
+	case req.ExecuteAt != nil:
+		schedule.StartAt = *req.ExecuteAt
+	case recurrence == RecurrenceNone:
+		return nil, fmt.Errorf("execute_at is required for a one-off schedule")
+	case recurrence == RecurrenceCron:
+		cron, err := ParseCronExpression(req.Cron)
+		if err != nil {
+			return nil, err
+		}
+		schedule.StartAt = cron.Next(now.UTC())
+	default:
+		schedule.StartAt = now
+	}
+	startAt := schedule.StartAt
+	schedule.NextRunAt = &startAt
+
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// Validate validates the schedule and the transaction it creates
+func (s *Schedule) Validate() error {
+	if _, err := s.NewTransaction(); err != nil {
+		return err
+	}
+
+	switch s.Recurrence {
+	case RecurrenceNone, RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+		if s.CronExpr != "" {
+			return fmt.Errorf("cron is only allowed with the cron recurrence")
+		}
+	case RecurrenceCron:
+		if _, err := ParseCronExpression(s.CronExpr); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid recurrence: %s", s.Recurrence)
+	}
+
+	if s.StartAt.IsZero() {
+		return fmt.Errorf("schedule has no run time")
+	}
+	if s.EndAt != nil && !s.EndAt.After(s.StartAt) {
+		return fmt.Errorf("end_at must be after the first run")
+	}
+	if s.MaxRuns != nil && *s.MaxRuns <= 0 {
+		return fmt.Errorf("max_runs must be greater than 0")
+	}
+
+	return nil
+}
+
+// NewTransaction creates the pending transaction for the current run
+func (s *Schedule) NewTransaction() (*Transaction, error) {
+	transaction, err := NewTransaction(s.FromUserID, s.ToUserID, s.Amount, s.Currency, s.Type, s.Description, "")
+	if err != nil {
+		return nil, err
+	}
+
+	scheduleID := s.ID
+	transaction.ScheduleID = &scheduleID
+	return transaction, nil
+}
+
+// IsDue checks if the schedule should run at now
+func (s *Schedule) IsDue(now time.Time) bool {
+	return s.Status == ScheduleStatusActive && s.NextRunAt != nil && !s.NextRunAt.After(now)
+}
+
+// RecordRun records that the current run created transactionID and moves the
+// schedule to its next occurrence, completing it when there is none.
+func (s *Schedule) RecordRun(transactionID uuid.UUID) error {
+	if s.NextRunAt == nil {
+		return fmt.Errorf("schedule has no pending run")
+	}
+
+	ranAt := *s.NextRunAt
+	s.RunCount++
+	s.LastRunAt = &ranAt
+	s.LastTransactionID = &transactionID
+	s.UpdatedAt = time.Now()
+
+	next, err := s.occurrence(ranAt)
+	if err != nil {
+		return err
+	}
+
+	if next.IsZero() ||
+		(s.MaxRuns != nil && s.RunCount >= *s.MaxRuns) ||
+		(s.EndAt != nil && next.After(*s.EndAt)) {
+		s.NextRunAt = nil
+		s.Status = ScheduleStatusCompleted
+		return nil
+	}
+
+	s.NextRunAt = &next
+	return nil
+}
+
+// occurrence returns the run following the one at previous, or the zero time
+// for a one-off schedule. Calendar recurrences count from StartAt so a monthly
+// schedule on the 31st keeps running on the last day of shorter months.
+func (s *Schedule) occurrence(previous time.Time) (time.Time, error) {
+	switch s.Recurrence {
+	case RecurrenceNone:
+		return time.Time{}, nil
+	case RecurrenceDaily:
+		return s.StartAt.AddDate(0, 0, s.RunCount), nil
+	case RecurrenceWeekly:
+		return s.StartAt.AddDate(0, 0, 7*s.RunCount), nil
+	case RecurrenceMonthly:
+		return addMonthsClamped(s.StartAt, s.RunCount), nil
+	case RecurrenceCron:
+		cron, err := ParseCronExpression(s.CronExpr)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return cron.Next(previous.UTC()), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid recurrence: %s", s.Recurrence)
+	}
+}
+
+// Cancel stops an active schedule
+func (s *Schedule) Cancel() error {
+	if s.Status != ScheduleStatusActive {
+		return fmt.Errorf("schedule cannot be cancelled, current status: %s", s.Status)
+	}
+
+	s.Status = ScheduleStatusCancelled
+	s.NextRunAt = nil
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// addMonthsClamped adds months to t, moving to the last day of the target
+// month when it is shorter than t's day
+func addMonthsClamped(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
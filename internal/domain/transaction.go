@@ -2,6 +2,7 @@ package domain
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,16 @@ import (
 type TransactionType string
 type TransactionStatus string
 
+var (
+	// ErrNotReversible is returned for a transaction whose state or kind does
+	// not allow reversing it
+	ErrNotReversible = errors.New("transaction cannot be reversed")
+	// ErrReversalExceedsAmount is returned for a reversal of more than what
+	// is left to reverse of a transaction
+	ErrReversalExceedsAmount  = errors.New("reversal amount exceeds reversible amount")
+	ErrReversalReasonRequired = errors.New("reason is required to reverse a transaction")
+)
+
 const (
 	TransactionTypeCredit   TransactionType = "credit"
 	TransactionTypeDebit    TransactionType = "debit"
@@ -22,24 +33,57 @@ const (
 	TransactionStatusCompleted TransactionStatus = "completed"
 	TransactionStatusFailed    TransactionStatus = "failed"
 	TransactionStatusCancelled TransactionStatus = "cancelled"
+
+	// A completed transaction moves to one of these once reversals complete
+	TransactionStatusPartiallyReversed TransactionStatus = "partially_reversed"
+	TransactionStatusReversed          TransactionStatus = "reversed"
 )
 
 type Transaction struct {
 	ID          uuid.UUID         `json:"id" db:"id"`
 	FromUserID  *uuid.UUID        `json:"from_user_id,omitempty" db:"from_user_id"`
 	ToUserID    *uuid.UUID        `json:"to_user_id,omitempty" db:"to_user_id"`
-	Amount      float64           `json:"amount" db:"amount"`
+	Amount      Money             `json:"amount" db:"amount"`
+	Currency    Currency          `json:"currency" db:"currency"`
 	Type        TransactionType   `json:"type" db:"type"`
 	Status      TransactionStatus `json:"status" db:"status"`
 	Description string            `json:"description,omitempty" db:"description"`
 	ReferenceID string            `json:"reference_id,omitempty" db:"reference_id"`
 	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+
+	// Currency conversion details, only set on cross-currency transfers
+	ToCurrency   *Currency  `json:"to_currency,omitempty" db:"to_currency"`
+	ToAmount     *Money     `json:"to_amount,omitempty" db:"to_amount"`
+	FXRate       *string    `json:"fx_rate,omitempty" db:"fx_rate"`
+	FXSpread     *string    `json:"fx_spread,omitempty" db:"fx_spread"`
+	FXRateAt     *time.Time `json:"fx_rate_at,omitempty" db:"fx_rate_at"`
+	FXRateSource *string    `json:"fx_rate_source,omitempty" db:"fx_rate_source"`
+
+	// Reversal tracking. A reversal is an ordinary compensating transaction
+	// linked to the original, which accumulates the amount reversed so far.
+	ReversesTransactionID *uuid.UUID `json:"reverses_transaction_id,omitempty" db:"reverses_transaction_id"`
+	ReversedAmount        Money      `json:"reversed_amount" db:"reversed_amount"`
+
+	// HoldID is set when the transaction captures a hold. The held funds are
+	// released when the transaction is processed.
+	HoldID *uuid.UUID `json:"hold_id,omitempty" db:"hold_id"`
+
+	// ScheduleID is set when the transaction was created by a schedule
+	ScheduleID *uuid.UUID `json:"schedule_id,omitempty" db:"schedule_id"`
+}
+
+type ReverseTransactionRequest struct {
+	// Amount to reverse; zero reverses everything not yet reversed
+	Amount Money  `json:"amount,omitempty"`
+	Reason string `json:"reason"`
 }
 
 type CreateTransactionRequest struct {
 	FromUserID  *uuid.UUID `json:"from_user_id,omitempty"`
 	ToUserID    *uuid.UUID `json:"to_user_id,omitempty"`
-	Amount      float64    `json:"amount"`
+	Amount      Money      `json:"amount"`
+	Currency    string     `json:"currency,omitempty"`
+	ToCurrency  string     `json:"to_currency,omitempty"`
 	Type        string     `json:"type"`
 	Description string     `json:"description,omitempty"`
 	ReferenceID string     `json:"reference_id,omitempty"`
@@ -56,12 +100,13 @@ type TransactionFilter struct {
 }
 
 // NewTransaction creates a new transaction with validation
-func NewTransaction(fromUserID, toUserID *uuid.UUID, amount float64, txType TransactionType, description, referenceID string) (*Transaction, error) {
+func NewTransaction(fromUserID, toUserID *uuid.UUID, amount Money, currency Currency, txType TransactionType, description, referenceID string) (*Transaction, error) {
 	transaction := &Transaction{
 		ID:          uuid.New(),
 		FromUserID:  fromUserID,
 		ToUserID:    toUserID,
 		Amount:      amount,
+		Currency:    currency,
 		Type:        txType,
 		Status:      TransactionStatusPending,
 		Description: description,
@@ -78,9 +123,16 @@ func NewTransaction(fromUserID, toUserID *uuid.UUID, amount float64, txType Tran
 
 // Validate validates transaction fields
 func (t *Transaction) Validate() error {
-	if t.Amount <= 0 {
+	if !t.Amount.IsPositive() {
 		return fmt.Errorf("amount must be greater than 0")
 	}
+	if t.Amount > MaxMoney {
+		return ErrAmountOverflow
+	}
+
+	if !t.Currency.IsValid() {
+		return fmt.Errorf("unsupported currency: %s", t.Currency)
+	}
 
 	switch t.Type {
 	case TransactionTypeCredit:
@@ -108,9 +160,93 @@ func (t *Transaction) Validate() error {
 		return fmt.Errorf("invalid transaction type: %s", t.Type)
 	}
 
+	if t.ToCurrency != nil {
+		if t.Type != TransactionTypeTransfer {
+			return fmt.Errorf("currency conversion is only supported for transfer transactions")
+		}
+		if !t.ToCurrency.IsValid() {
+			return fmt.Errorf("unsupported currency: %s", *t.ToCurrency)
+		}
+		if *t.ToCurrency == t.Currency {
+			return fmt.Errorf("to_currency must differ from currency")
+		}
+		if t.ToAmount == nil || !t.ToAmount.IsPositive() {
+			return fmt.Errorf("converted amount must be greater than 0")
+		}
+		if t.FXRate == nil || t.FXRateAt == nil {
+			return fmt.Errorf("exchange rate is required for currency conversion")
+		}
+	}
+
+	if t.HoldID != nil && t.Type == TransactionTypeCredit {
+		return fmt.Errorf("a hold can only be captured into a debit or transfer")
+	}
+
 	return nil
 }
 
+// ApplyExchangeRate turns the transaction into a cross-currency transfer.
+// The converted amount, rate, spread and rate timestamp are recorded so the
+// conversion can be audited later.
+func (t *Transaction) ApplyExchangeRate(rate ExchangeRate) error {
+	if t.Type != TransactionTypeTransfer {
+		return fmt.Errorf("currency conversion is only supported for transfer transactions")
+	}
+	if rate.From != t.Currency {
+		return fmt.Errorf("exchange rate is quoted from %s, transaction currency is %s", rate.From, t.Currency)
+	}
+	if err := rate.Validate(); err != nil {
+		return err
+	}
+
+	converted, err := rate.Convert(t.Amount)
+	if err != nil {
+		return err
+	}
+	if !converted.IsPositive() {
+		return fmt.Errorf("converted amount is too small")
+	}
+
+	toCurrency := rate.To
+	fxRate := rate.Rate
+	fxSpread := rate.Spread
+	if fxSpread == "" {
+		fxSpread = "0"
+	}
+	fxRateAt := rate.Timestamp
+	fxRateSource := rate.Source
+
+	t.ToCurrency = &toCurrency
+	t.ToAmount = &converted
+	t.FXRate = &fxRate
+	t.FXSpread = &fxSpread
+	t.FXRateAt = &fxRateAt
+	t.FXRateSource = &fxRateSource
+
+	return t.Validate()
+}
+
+// IsCrossCurrency checks if the transaction converts between currencies
+func (t *Transaction) IsCrossCurrency() bool {
+	return t.ToCurrency != nil && *t.ToCurrency != t.Currency
+}
+
+// CreditCurrency returns the currency credited to the receiving wallet
+func (t *Transaction) CreditCurrency() Currency {
+	if t.IsCrossCurrency() {
+		return *t.ToCurrency
+	}
+	return t.Currency
+}
+
+// CreditAmount returns the amount credited to the receiving wallet
+func (t *Transaction) CreditAmount() Money {
+	if t.IsCrossCurrency() && t.ToAmount != nil {
+		return *t.ToAmount
+	}
+	return t.Amount
+}
+
 // MarkCompleted marks the transaction as completed
 func (t *Transaction) MarkCompleted() {
 	t.Status = TransactionStatusCompleted
@@ -131,6 +267,99 @@ func (t *Transaction) IsCompleted() bool {
 	return t.Status == TransactionStatusCompleted
 }
 
+// IsSettled checks if the transaction has moved funds, whether or not it was reversed since
+func (t *Transaction) IsSettled() bool {
+	switch t.Status {
+	case TransactionStatusCompleted, TransactionStatusPartiallyReversed, TransactionStatusReversed:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsReversal checks if the transaction compensates another one
+func (t *Transaction) IsReversal() bool {
+	return t.ReversesTransactionID != nil
+}
+
+// ReversibleAmount returns how much of the transaction can still be reversed
+func (t *Transaction) ReversibleAmount() Money {
+	return t.Amount.Sub(t.ReversedAmount)
+}
+
+// CanBeReversed checks if amount can be reversed from the transaction
+func (t *Transaction) CanBeReversed(amount Money) error {
+	if !t.IsSettled() {
+		return fmt.Errorf("%w, current status: %s", ErrNotReversible, t.Status)
+	}
+	if t.IsReversal() {
+		return fmt.Errorf("%w: a reversal cannot be reversed", ErrNotReversible)
+	}
+	if t.IsCrossCurrency() {
+		return fmt.Errorf("%w: cross-currency transfers cannot be reversed", ErrNotReversible)
+	}
+	if !amount.IsPositive() {
+		return fmt.Errorf("%w: reversal amount must be greater than 0", ErrInvalidAmount)
+	}
+	if amount > t.ReversibleAmount() {
+		return fmt.Errorf("%w: %s requested, %s reversible", ErrReversalExceedsAmount, amount, t.ReversibleAmount())
+	}
+	return nil
+}
+
+// NewReversal creates the compensating transaction for amount of t. Funds
+// move in the opposite direction: a credit is undone by a debit, a debit by
+// a credit and a transfer by a transfer back to the sender.
+func (t *Transaction) NewReversal(amount Money, reason string) (*Transaction, error) {
+	if err := t.CanBeReversed(amount); err != nil {
+		return nil, err
+	}
+
+	var fromUserID, toUserID *uuid.UUID
+	var txType TransactionType
+	switch t.Type {
+	case TransactionTypeCredit:
+		fromUserID, txType = t.ToUserID, TransactionTypeDebit
+	case TransactionTypeDebit:
+		toUserID, txType = t.FromUserID, TransactionTypeCredit
+	case TransactionTypeTransfer:
+		fromUserID, toUserID, txType = t.ToUserID, t.FromUserID, TransactionTypeTransfer
+	default:
+		return nil, fmt.Errorf("invalid transaction type: %s", t.Type)
+	}
+
+	description := fmt.Sprintf("Reversal of %s", t.ID)
+	if reason != "" {
+		description += ": " + reason
+	}
+
+	reversal, err := NewTransaction(fromUserID, toUserID, amount, t.Currency, txType, description, "")
+	if err != nil {
+		return nil, err
+	}
+
+	originalID := t.ID
+	reversal.ReversesTransactionID = &originalID
+
+	return reversal, nil
+}
+
+// ApplyReversal records a completed reversal of amount on the original transaction
+func (t *Transaction) ApplyReversal(amount Money) error {
+	if amount > t.ReversibleAmount() {
+		return fmt.Errorf("%w: %s requested, %s reversible", ErrReversalExceedsAmount, amount, t.ReversibleAmount())
+	}
+
+	t.ReversedAmount = t.ReversedAmount.Add(amount)
+	if t.ReversedAmount == t.Amount {
+		t.Status = TransactionStatusReversed
+	} else {
+		t.Status = TransactionStatusPartiallyReversed
+	}
+
+	return nil
+}
+
 // IsPending checks if the transaction is pending
 func (t *Transaction) IsPending() bool {
 	return t.Status == TransactionStatusPending
@@ -187,6 +416,8 @@ func IsValidTransactionStatus(status string) bool {
 		string(TransactionStatusCompleted),
 		string(TransactionStatusFailed),
 		string(TransactionStatusCancelled),
+		string(TransactionStatusPartiallyReversed),
+		string(TransactionStatusReversed),
 	}
 
 	for _, validStatus := range validStatuses {
@@ -0,0 +1,295 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryStatusDead has failed permanently and has a dead letter
+	WebhookDeliveryStatusDead WebhookDeliveryStatus = "dead"
+)
+
+// Webhook retry backoff bounds and secret length limits
+const (
+	webhookBaseBackoff     = 10 * time.Second
+	webhookMaxBackoff      = time.Hour
+	webhookSecretBytes     = 32
+	minWebhookSecretLength = 16
+)
+
+// WebhookSubscription sends the events of the listed types to URL. Every
+// request is signed with Secret, which is only returned when the
+// subscription is created.
+type WebhookSubscription struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	URL         string    `json:"url" db:"url"`
+	Secret      string    `json:"-" db:"secret"`
+	EventTypes  []string  `json:"event_types" db:"event_types"`
+	Description string    `json:"description,omitempty" db:"description"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret signs the deliveries; one is generated when empty
+	Secret      string `json:"secret,omitempty"`
+	Description string `json:"description"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url,omitempty"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// NewWebhookSubscription creates an active subscription
+func NewWebhookSubscription(req CreateWebhookRequest) (*WebhookSubscription, error) {
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	now := time.Now()
+	subscription := &WebhookSubscription{
+		ID:          uuid.New(),
+		URL:         req.URL,
+		Secret:      secret,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// Validate validates the subscription
+func (s *WebhookSubscription) Validate() error {
+	parsed, err := url.Parse(s.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	if len(s.EventTypes) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+
+	if len(s.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minWebhookSecretLength)
+	}
+
+	return nil
+}
+
+// Apply applies the fields set in req
+func (s *WebhookSubscription) Apply(req UpdateWebhookRequest) error {
+	if req.URL != nil {
+		s.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		s.EventTypes = req.EventTypes
+	}
+	if req.Description != nil {
+		s.Description = *req.Description
+	}
+	if req.Active != nil {
+		s.Active = *req.Active
+	}
+	s.UpdatedAt = time.Now()
+
+	return s.Validate()
+}
+
+// Sign returns the signature of a delivery body sent at timestamp: the hex
+// HMAC-SHA256, keyed with the secret, of "<unix timestamp>.<body>". Signing
+// the timestamp lets receivers reject replayed requests.
+func (s *WebhookSubscription) Sign(timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// WebhookDelivery is one event to be sent to one subscription
+type WebhookDelivery struct {
+	ID                 uuid.UUID             `json:"id" db:"id"`
+	SubscriptionID     uuid.UUID             `json:"subscription_id" db:"subscription_id"`
+	EventID            uuid.UUID             `json:"event_id" db:"event_id"`
+	EventType          string                `json:"event_type" db:"event_type"`
+	Payload            json.RawMessage       `json:"payload" db:"payload"`
+	Status             WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts           int                   `json:"attempts" db:"attempts"`
+	LastError          string                `json:"last_error,omitempty" db:"last_error"`
+	LastResponseStatus *int                  `json:"last_response_status,omitempty" db:"last_response_status"`
+	NextAttemptAt      time.Time             `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt          time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time             `json:"updated_at" db:"updated_at"`
+	DeliveredAt        *time.Time            `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// NewWebhookDelivery creates a pending delivery due immediately
+func NewWebhookDelivery(subscriptionID, eventID uuid.UUID, eventType string, payload json.RawMessage) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         WebhookDeliveryStatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// MarkDelivered records a successful attempt
+func (d *WebhookDelivery) MarkDelivered(responseStatus int, now time.Time) {
+	d.Attempts++
+	d.Status = WebhookDeliveryStatusDelivered
+	d.LastError = ""
+	d.LastResponseStatus = &responseStatus
+	d.DeliveredAt = &now
+	d.UpdatedAt = now
+}
+
+// MarkAttemptFailed records a failed attempt. responseStatus is zero when no
+// response was received. The delivery is retried with exponential backoff
+// unless the failure is permanent or maxAttempts is reached, in which case it
+// becomes dead.
+func (d *WebhookDelivery) MarkAttemptFailed(err error, responseStatus int, permanent bool, now time.Time, maxAttempts int) {
+	d.Attempts++
+	d.LastError = err.Error()
+	d.LastResponseStatus = nil
+	if responseStatus != 0 {
+		d.LastResponseStatus = &responseStatus
+	}
+	d.UpdatedAt = now
+
+	if permanent || d.Attempts >= maxAttempts {
+		d.Status = WebhookDeliveryStatusDead
+		return
+	}
+
+	backoff := webhookBaseBackoff << uint(d.Attempts-1)
+	if backoff <= 0 || backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	d.NextAttemptAt = now.Add(backoff)
+}
+
+// Replay queues the delivery to be sent again with a fresh retry budget
+func (d *WebhookDelivery) Replay(now time.Time) error {
+	if d.Status == WebhookDeliveryStatusPending {
+		return fmt.Errorf("delivery is already pending")
+	}
+
+	d.Status = WebhookDeliveryStatusPending
+	d.Attempts = 0
+	d.LastError = ""
+	d.NextAttemptAt = now
+	d.UpdatedAt = now
+	return nil
+}
+
+// IsPermanentWebhookFailure reports whether an HTTP status means retrying the
+// same request cannot succeed. Timeouts and rate limiting are retried.
+func IsPermanentWebhookFailure(responseStatus int) bool {
+	if responseStatus == 408 || responseStatus == 429 {
+		return false
+	}
+	return responseStatus >= 400 && responseStatus < 500
+}
+
+// WebhookAttempt records one HTTP request of a delivery
+type WebhookAttempt struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	DeliveryID     uuid.UUID `json:"delivery_id" db:"delivery_id"`
+	ResponseStatus *int      `json:"response_status,omitempty" db:"response_status"`
+	Error          string    `json:"error,omitempty" db:"error"`
+	DurationMs     int64     `json:"duration_ms" db:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// NewWebhookAttempt records the outcome of a request that took duration
+func NewWebhookAttempt(deliveryID uuid.UUID, responseStatus int, err error, duration time.Duration) *WebhookAttempt {
+	attempt := &WebhookAttempt{
+		ID:         uuid.New(),
+		DeliveryID: deliveryID,
+		DurationMs: duration.Milliseconds(),
+		CreatedAt:  time.Now(),
+	}
+	if responseStatus != 0 {
+		attempt.ResponseStatus = &responseStatus
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	return attempt
+}
+
+// WebhookDeadLetter keeps a delivery that failed permanently until it is
+// replayed
+type WebhookDeadLetter struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	DeliveryID     uuid.UUID       `json:"delivery_id" db:"delivery_id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id" db:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id" db:"event_id"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	LastError      string          `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	ReplayedAt     *time.Time      `json:"replayed_at,omitempty" db:"replayed_at"`
+}
+
+// NewWebhookDeadLetter creates the dead letter of a dead delivery
+func NewWebhookDeadLetter(d *WebhookDelivery) *WebhookDeadLetter {
+	return &WebhookDeadLetter{
+		ID:             uuid.New(),
+		DeliveryID:     d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Attempts:       d.Attempts,
+		LastError:      d.LastError,
+		CreatedAt:      time.Now(),
+	}
+}
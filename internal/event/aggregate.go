@@ -0,0 +1,457 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"insider-backend/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Aggregate types recorded with snapshots
+const (
+	UserAggregateType    = "user"
+	BalanceAggregateType = "balance"
+)
+
+// Aggregate is state rebuilt by applying the events of one stream in version order
+type Aggregate interface {
+	AggregateType() string
+	GetVersion() int
+	Apply(event *Event) error
+}
+
+// UserAggregate is a user as described by the user event stream
+type UserAggregate struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Deleted   bool      `json:"deleted"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"`
+}
+
+// AggregateType returns the snapshot type of users
+func (a *UserAggregate) AggregateType() string {
+	return UserAggregateType
+}
+
+// GetVersion returns the version of the last applied event
+func (a *UserAggregate) GetVersion() int {
+	return a.Version
+}
+
+// Apply applies a user event
+func (a *UserAggregate) Apply(event *Event) error {
+	switch event.Type {
+	case UserCreatedEvent:
+		var data UserCreatedEventData
+		if err := event.GetData(&data); err != nil {
+			return err
+		}
+		a.ID, a.Username, a.Email, a.Role = data.UserID, data.Username, data.Email, data.Role
+		a.CreatedAt = event.CreatedAt
+	case UserUpdatedEvent:
+		var data UserUpdatedEventData
+		if err := event.GetData(&data); err != nil {
+			return err
+		}
+		a.ID, a.Username, a.Email, a.Role = data.UserID, data.Username, data.Email, data.Role
+	case UserDeletedEvent:
+		a.Deleted = true
+	default:
+		return fmt.Errorf("unexpected %s event in user stream", event.Type)
+	}
+
+	a.UpdatedAt = event.CreatedAt
+	a.Version = event.Version
+	return nil
+}
+
+// BalanceAggregate is a wallet as described by its balance event stream
+type BalanceAggregate struct {
+	UserID            uuid.UUID       `json:"user_id"`
+	Currency          domain.Currency `json:"currency"`
+	Amount            domain.Money    `json:"amount"`
+	TotalCredited     domain.Money    `json:"total_credited"`
+	TotalDebited      domain.Money    `json:"total_debited"`
+	LastTransactionID *uuid.UUID      `json:"last_transaction_id,omitempty"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	Version           int             `json:"version"`
+}
+
+// AggregateType returns the snapshot type of wallets
+func (a *BalanceAggregate) AggregateType() string {
+	return BalanceAggregateType
+}
+
+// GetVersion returns the version of the last applied event
+func (a *BalanceAggregate) GetVersion() int {
+	return a.Version
+}
+
+// Apply applies a balance event
+func (a *BalanceAggregate) Apply(event *Event) error {
+	if event.Type != BalanceCreditedEvent && event.Type != BalanceDebitedEvent {
+		return fmt.Errorf("unexpected %s event in balance stream", event.Type)
+	}
+
+	var data BalanceChangedEventData
+	if err := event.GetData(&data); err != nil {
+		return err
+	}
+
+	a.UserID = data.UserID
+	a.Currency = data.Currency
+	a.Amount = data.NewBalance
+	if event.Type == BalanceCreditedEvent {
+		a.TotalCredited = a.TotalCredited.Add(data.Amount)
+	} else {
+		a.TotalDebited = a.TotalDebited.Add(data.Amount)
+	}
+	a.LastTransactionID = data.TransactionID
+	a.UpdatedAt = event.CreatedAt
+	a.Version = event.Version
+	return nil
+}
+
+// SnapshotPolicy decides when an aggregate is snapshotted
+type SnapshotPolicy struct {
+	// Every is the number of events after which a new snapshot is taken; zero disables snapshots
+	Every int
+}
+
+// Due reports whether an aggregate at version needs a new snapshot when its
+// latest snapshot is at snapshotVersion
+func (p SnapshotPolicy) Due(snapshotVersion, version int) bool {
+	return p.Every > 0 && version-snapshotVersion >= p.Every
+}
+
+// LoadInfo describes how an aggregate was loaded
+type LoadInfo struct {
+	SnapshotVersion   int  `json:"snapshot_version"`
+	SnapshotDiscarded bool `json:"snapshot_discarded"`
+	EventsApplied     int  `json:"events_applied"`
+	SnapshotTaken     bool `json:"snapshot_taken"`
+}
+
+// AggregateLoader loads aggregates from their latest snapshot and the events
+// after it, and snapshots them as the policy requires
+type AggregateLoader struct {
+	store     EventStore
+	snapshots SnapshotStore
+	policy    SnapshotPolicy
+}
+
+// NewAggregateLoader creates a new aggregate loader
+func NewAggregateLoader(store EventStore, snapshots SnapshotStore, policy SnapshotPolicy) *AggregateLoader {
+	return &AggregateLoader{
+		store:     store,
+		snapshots: snapshots,
+		policy:    policy,
+	}
+}
+
+// LoadUser loads the user aggregate of a user
+func (l *AggregateLoader) LoadUser(userID uuid.UUID) (*UserAggregate, *LoadInfo, error) {
+	aggregate := &UserAggregate{}
+	info, err := l.Load(userID, func() Aggregate {
+		*aggregate = UserAggregate{}
+		return aggregate
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return aggregate, info, nil
+}
+
+// LoadBalance loads the balance aggregate of a user's wallet in currency
+func (l *AggregateLoader) LoadBalance(userID uuid.UUID, currency domain.Currency) (*BalanceAggregate, *LoadInfo, error) {
+	aggregate := &BalanceAggregate{}
+	info, err := l.Load(BalanceAggregateID(userID, currency), func() Aggregate {
+		*aggregate = BalanceAggregate{UserID: userID, Currency: currency}
+		return aggregate
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return aggregate, info, nil
+}
+
+// Load rebuilds an aggregate. reset returns the aggregate in its initial
+// state and is called again if a snapshot has to be discarded. A snapshot
+// newer than the stream, of another aggregate type, or whose data does not
+// match its version is deleted and the full stream is replayed instead.
+func (l *AggregateLoader) Load(aggregateID uuid.UUID, reset func() Aggregate) (*LoadInfo, error) {
+	info := &LoadInfo{}
+	aggregate := reset()
+
+	lastVersion, err := l.store.GetLastEventVersion(aggregateID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := l.snapshots.GetSnapshot(aggregateID)
+	if err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+		return nil, err
+	}
+	if snapshot != nil {
+		if err := restoreSnapshot(snapshot, aggregate, lastVersion); err != nil {
+			log.Warn().
+				Err(err).
+				Str("aggregate_id", aggregateID.String()).
+				Int("snapshot_version", snapshot.Version).
+				Int("last_version", lastVersion).
+				Msg("Discarding corrupt snapshot")
+
+			if err := l.snapshots.DeleteSnapshot(aggregateID); err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+				return nil, err
+			}
+			aggregate = reset()
+			info.SnapshotDiscarded = true
+		} else {
+			info.SnapshotVersion = snapshot.Version
+		}
+	}
+
+	events, err := l.store.GetEventsAfterVersion(aggregateID, aggregate.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if event.Version != aggregate.GetVersion()+1 {
+			return nil, fmt.Errorf("event stream of %s skips from version %d to %d", aggregateID, aggregate.GetVersion(), event.Version)
+		}
+		if err := aggregate.Apply(event); err != nil {
+			return nil, fmt.Errorf("failed to apply event %s: %w", event.ID, err)
+		}
+		info.EventsApplied++
+	}
+
+	if l.policy.Due(info.SnapshotVersion, aggregate.GetVersion()) {
+		if err := l.saveSnapshot(aggregateID, aggregate); err != nil {
+			log.Error().Err(err).Str("aggregate_id", aggregateID.String()).Msg("Failed to snapshot aggregate")
+		} else {
+			info.SnapshotTaken = true
+		}
+	}
+
+	return info, nil
+}
+
+func (l *AggregateLoader) saveSnapshot(aggregateID uuid.UUID, aggregate Aggregate) error {
+	snapshot, err := NewSnapshot(aggregateID, aggregate.AggregateType(), aggregate, aggregate.GetVersion())
+	if err != nil {
+		return fmt.Errorf("failed to build snapshot: %w", err)
+	}
+	return l.snapshots.SaveSnapshot(snapshot)
+}
+
+// restoreSnapshot loads snapshot into aggregate after checking it against the
+// last version of the stream
+func restoreSnapshot(snapshot *Snapshot, aggregate Aggregate, lastVersion int) error {
+	if snapshot.AggregateType != aggregate.AggregateType() {
+		return fmt.Errorf("snapshot is of type %s, not %s", snapshot.AggregateType, aggregate.AggregateType())
+	}
+	if snapshot.Version <= 0 || snapshot.Version > lastVersion {
+		return fmt.Errorf("snapshot version %d is outside the stream, which ends at %d", snapshot.Version, lastVersion)
+	}
+	if err := json.Unmarshal(snapshot.Data, aggregate); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if aggregate.GetVersion() != snapshot.Version {
+		return fmt.Errorf("snapshot data is at version %d, not %d", aggregate.GetVersion(), snapshot.Version)
+	}
+	return nil
+}
+
+// Snapshotter is a projection that snapshots user and balance aggregates as
+// their streams grow, so loading them stays cheap
+type Snapshotter struct {
+	loader *AggregateLoader
+	policy SnapshotPolicy
+}
+
+// SnapshotterName names the snapshotter projection and its checkpoint
+const SnapshotterName = "snapshots"
+
+// NewSnapshotter creates a snapshotter using loader
+func NewSnapshotter(loader *AggregateLoader) *Snapshotter {
+	return &Snapshotter{loader: loader, policy: loader.policy}
+}
+
+// GetName returns the name of the projection
+func (s *Snapshotter) GetName() string {
+	return SnapshotterName
+}
+
+// Handle loads the aggregate of every policy.Every-th event of a user or
+// wallet stream, which takes a snapshot if one is due
+func (s *Snapshotter) Handle(event *Event) error {
+	if s.policy.Every <= 0 || event.Version%s.policy.Every != 0 {
+		return nil
+	}
+
+	switch event.Type {
+	case UserCreatedEvent, UserUpdatedEvent, UserDeletedEvent:
+		_, _, err := s.loader.LoadUser(event.AggregateID)
+		return err
+	case BalanceCreditedEvent, BalanceDebitedEvent:
+		var data BalanceChangedEventData
+		if err := event.GetData(&data); err != nil {
+			return err
+		}
+		_, _, err := s.loader.LoadBalance(data.UserID, data.Currency)
+		return err
+	}
+
+	return nil
+}
+
+// Rebuild does nothing: snapshots are checked whenever they are loaded and a
+// replay replaces them as it reaches each aggregate
+func (s *Snapshotter) Rebuild() error {
+	return nil
+}
+
+// BalanceContribution is a balance event applied while reconstructing a wallet
+type BalanceContribution struct {
+	EventID       uuid.UUID    `json:"event_id"`
+	Version       int          `json:"version"`
+	Type          EventType    `json:"type"`
+	Amount        domain.Money `json:"amount"`
+	BalanceBefore domain.Money `json:"balance_before"`
+	BalanceAfter  domain.Money `json:"balance_after"`
+	TransactionID *uuid.UUID   `json:"transaction_id,omitempty"`
+	OccurredAt    time.Time    `json:"occurred_at"`
+}
+
+// ErrBalanceNotRecorded is returned when neither a snapshot nor an event of a
+// wallet's stream records it at the requested instant
+var ErrBalanceNotRecorded = errors.New("no balance event records the wallet at this time")
+
+// BalanceReconstruction is a wallet rebuilt as it was at an instant, with the
+// events that produced it after its starting snapshot
+type BalanceReconstruction struct {
+	Balance         *BalanceAggregate `json:"balance"`
+	At              time.Time         `json:"at"`
+	SnapshotVersion int               `json:"snapshot_version"`
+	OpeningBalance  domain.Money      `json:"opening_balance"`
+	// OpeningUnrecorded is set when the stream starts after the wallet
+	// already held funds, e.g. from before balance events were recorded; the
+	// opening balance is then the one its first event started from
+	OpeningUnrecorded bool                   `json:"opening_unrecorded,omitempty"`
+	Contributions     []*BalanceContribution `json:"contributions"`
+}
+
+// LoadBalanceAt rebuilds a user's wallet in currency as it was at an instant
+// by applying its balance events created up to then. With useSnapshot it
+// starts from the latest snapshot if that predates the instant; snapshots are
+// only read, never repaired. The stream is applied in version order and
+// stops at the first event created after the instant. A replay from the start
+// opens with the balance the first event started from. It fails with
+// ErrBalanceNotRecorded when no snapshot or event precedes the instant, as
+// the balance then is unknown.
+func (l *AggregateLoader) LoadBalanceAt(userID uuid.UUID, currency domain.Currency, at time.Time, useSnapshot bool) (*BalanceReconstruction, error) {
+	aggregateID := BalanceAggregateID(userID, currency)
+	aggregate := &BalanceAggregate{UserID: userID, Currency: currency}
+	result := &BalanceReconstruction{Balance: aggregate, At: at, Contributions: []*BalanceContribution{}}
+
+	if useSnapshot {
+		if err := l.restoreBalanceBefore(aggregateID, aggregate, at); err != nil {
+			return nil, err
+		}
+		result.SnapshotVersion = aggregate.Version
+		result.OpeningBalance = aggregate.Amount
+	}
+
+	events, err := l.store.GetEventsAfterVersion(aggregateID, aggregate.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if aggregate.Version == 0 {
+		if len(events) == 0 || events[0].CreatedAt.After(at) {
+			return nil, ErrBalanceNotRecorded
+		}
+
+		var first BalanceChangedEventData
+		if err := events[0].GetData(&first); err != nil {
+			return nil, err
+		}
+		aggregate.Amount = first.OldBalance
+		result.OpeningBalance = first.OldBalance
+		result.OpeningUnrecorded = !first.OldBalance.IsZero()
+	}
+
+	for _, event := range events {
+		if event.CreatedAt.After(at) {
+			break
+		}
+		if event.Version != aggregate.Version+1 {
+			return nil, fmt.Errorf("event stream of %s skips from version %d to %d", aggregateID, aggregate.Version, event.Version)
+		}
+
+		before := aggregate.Amount
+		if err := aggregate.Apply(event); err != nil {
+			return nil, fmt.Errorf("failed to apply event %s: %w", event.ID, err)
+		}
+
+		var data BalanceChangedEventData
+		if err := event.GetData(&data); err != nil {
+			return nil, err
+		}
+		result.Contributions = append(result.Contributions, &BalanceContribution{
+			EventID:       event.ID,
+			Version:       event.Version,
+			Type:          event.Type,
+			Amount:        data.Amount,
+			BalanceBefore: before,
+			BalanceAfter:  aggregate.Amount,
+			TransactionID: data.TransactionID,
+			OccurredAt:    event.CreatedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// restoreBalanceBefore loads the snapshot of a wallet into aggregate if it is
+// valid and its last event was created at or before at, leaving aggregate
+// untouched otherwise
+func (l *AggregateLoader) restoreBalanceBefore(aggregateID uuid.UUID, aggregate *BalanceAggregate, at time.Time) error {
+	snapshot, err := l.snapshots.GetSnapshot(aggregateID)
+	if errors.Is(err, ErrSnapshotNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lastVersion, err := l.store.GetLastEventVersion(aggregateID)
+	if err != nil {
+		return err
+	}
+
+	restored := *aggregate
+	if err := restoreSnapshot(snapshot, &restored, lastVersion); err != nil {
+		log.Warn().
+			Err(err).
+			Str("aggregate_id", aggregateID.String()).
+			Int("snapshot_version", snapshot.Version).
+			Msg("Ignoring corrupt snapshot for point-in-time load")
+		return nil
+	}
+	if restored.UpdatedAt.After(at) {
+		return nil
+	}
+
+	*aggregate = restored
+	return nil
+}
@@ -0,0 +1,480 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Subscriber defaults used when a SubscriberConfig leaves a field unset
+const (
+	defaultSubscriberQueueSize   = 1000
+	defaultSubscriberConcurrency = 1
+	defaultSubscriberMaxAttempts = 3
+	defaultSubscriberBackoff     = 100 * time.Millisecond
+	subscriberMaxBackoff         = 10 * time.Second
+)
+
+var (
+	// ErrBusClosed is returned when publishing to or subscribing on a bus
+	// that has been shut down
+	ErrBusClosed = errors.New("event bus is closed")
+	// ErrSubscriberQueueFull is returned by Publish when a subscriber's queue
+	// has no room for the event
+	ErrSubscriberQueueFull = errors.New("subscriber queue is full")
+)
+
+// DeadLetterHandler receives an event a subscriber gave up on, with the error
+// of its last attempt
+type DeadLetterHandler func(subscriber string, event *Event, err error)
+
+// SubscriberConfig configures how a subscriber receives events. Zero fields
+// take the bus defaults.
+type SubscriberConfig struct {
+	// Name identifies the subscriber in stats and logs; defaults to the handler type
+	Name string
+	// QueueSize is the number of events waiting for the subscriber
+	QueueSize int
+	// Concurrency is the number of events handled at once. One keeps the
+	// events of the subscriber in publish order.
+	Concurrency int
+	// MaxAttempts before a failing event is dead-lettered
+	MaxAttempts int
+	// Backoff before the first retry, doubled on each further retry
+	Backoff time.Duration
+	// DeadLetter receives dead-lettered events; they are logged when nil
+	DeadLetter DeadLetterHandler
+}
+
+// withDefaults fills the unset fields of c from defaults
+func (c SubscriberConfig) withDefaults(defaults SubscriberConfig) SubscriberConfig {
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaults.QueueSize
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaults.Concurrency
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaults.MaxAttempts
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = defaults.Backoff
+	}
+	if c.DeadLetter == nil {
+		c.DeadLetter = defaults.DeadLetter
+	}
+	return c
+}
+
+// SubscriberStats are the delivery counters of a subscriber
+type SubscriberStats struct {
+	Name         string      `json:"name"`
+	EventTypes   []EventType `json:"event_types"`
+	QueueLength  int         `json:"queue_length"`
+	QueueSize    int         `json:"queue_size"`
+	InFlight     int64       `json:"in_flight"`
+	Enqueued     int64       `json:"enqueued"`
+	Rejected     int64       `json:"rejected"`
+	Delivered    int64       `json:"delivered"`
+	Retried      int64       `json:"retried"`
+	DeadLettered int64       `json:"dead_lettered"`
+}
+
+// AsyncEventBus implements EventBus with a bounded queue and worker
+// goroutines per subscriber, so a slow or failing handler only delays its own
+// events. Publish never waits for a handler. When a subscriber's queue is
+// full, Publish enqueues the event for the other subscribers and fails with
+// ErrSubscriberQueueFull, leaving the caller to retry; handlers must
+// therefore tolerate seeing an event twice.
+type AsyncEventBus struct {
+	defaults    SubscriberConfig
+	subscribers []*subscriber
+	closed      bool
+	mu          sync.RWMutex
+}
+
+// NewAsyncEventBus creates an event bus whose subscribers take unset
+// configuration from defaults
+func NewAsyncEventBus(defaults SubscriberConfig) *AsyncEventBus {
+	defaults = defaults.withDefaults(SubscriberConfig{
+		QueueSize:   defaultSubscriberQueueSize,
+		Concurrency: defaultSubscriberConcurrency,
+		MaxAttempts: defaultSubscriberMaxAttempts,
+		Backoff:     defaultSubscriberBackoff,
+		DeadLetter:  logDeadLetter,
+	})
+
+	return &AsyncEventBus{defaults: defaults}
+}
+
+// Publish enqueues an event for every subscriber to its type
+func (b *AsyncEventBus) Publish(event *Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrBusClosed
+	}
+
+	var full []string
+	delivered := 0
+	for _, sub := range b.subscribers {
+		if !sub.wants(event.Type) {
+			continue
+		}
+		if !sub.enqueue(event) {
+			full = append(full, sub.name)
+			continue
+		}
+		delivered++
+	}
+
+	log.Debug().
+		Str("event_id", event.ID.String()).
+		Str("event_type", string(event.Type)).
+		Int("subscribers", delivered).
+		Msg("Event published")
+
+	if len(full) > 0 {
+		return fmt.Errorf("%w: %s", ErrSubscriberQueueFull, strings.Join(full, ", "))
+	}
+
+	return nil
+}
+
+// Subscribe registers a handler for an event type with the default
+// configuration. Subscribing a handler again adds the type to its existing
+// subscription.
+func (b *AsyncEventBus) Subscribe(eventType EventType, handler EventHandler) error {
+	return b.subscribe(handler, []EventType{eventType}, SubscriberConfig{})
+}
+
+// SubscribeWithConfig registers a handler for all of its EventTypes with its
+// own queue configuration
+func (b *AsyncEventBus) SubscribeWithConfig(handler EventHandler, config SubscriberConfig) error {
+	return b.subscribe(handler, handler.EventTypes(), config)
+}
+
+func (b *AsyncEventBus) subscribe(handler EventHandler, eventTypes []EventType, config SubscriberConfig) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBusClosed
+	}
+
+	sub := b.find(handler)
+	if sub == nil {
+		config = config.withDefaults(b.defaults)
+		if config.Name == "" {
+			config.Name = fmt.Sprintf("%T", handler)
+		}
+
+		sub = newSubscriber(handler, config)
+		b.subscribers = append(b.subscribers, sub)
+		sub.start()
+	}
+
+	sub.addTypes(eventTypes)
+
+	log.Info().
+		Str("subscriber", sub.name).
+		Interface("event_types", eventTypes).
+		Msg("Handler subscribed")
+
+	return nil
+}
+
+// Unsubscribe removes an event type from a handler's subscription. Once it has
+// no types left the subscriber stops taking events, and Unsubscribe waits for
+// it to handle the events already queued. It must not be called from the
+// handler itself.
+func (b *AsyncEventBus) Unsubscribe(eventType EventType, handler EventHandler) error {
+	b.mu.Lock()
+	sub := b.find(handler)
+	if sub == nil {
+		b.mu.Unlock()
+		return nil
+	}
+
+	sub.removeType(eventType)
+	if sub.hasTypes() {
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.remove(sub)
+	b.mu.Unlock()
+
+	sub.close()
+	sub.wait()
+
+	log.Info().
+		Str("subscriber", sub.name).
+		Msg("Handler unsubscribed")
+
+	return nil
+}
+
+// Shutdown stops the bus taking events and waits for every subscriber to
+// handle the events already queued. When ctx ends first, pending retries are
+// abandoned to the dead-letter handlers and ctx's error is returned. Its
+// signature matches a pkg/shutdown callback.
+func (b *AsyncEventBus) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subscribers := b.subscribers
+	b.subscribers = nil
+	b.mu.Unlock()
+
+	log.Info().Int("subscribers", len(subscribers)).Msg("Stopping event bus")
+
+	for _, sub := range subscribers {
+		sub.close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, sub := range subscribers {
+			sub.wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info().Msg("Event bus stopped")
+		return nil
+	case <-ctx.Done():
+		for _, sub := range subscribers {
+			sub.abort()
+		}
+		<-done
+		log.Warn().Msg("Event bus stopped before draining its queues")
+		return ctx.Err()
+	}
+}
+
+// Stats returns the delivery counters of every subscriber
+func (b *AsyncEventBus) Stats() []SubscriberStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := make([]SubscriberStats, len(b.subscribers))
+	for i, sub := range b.subscribers {
+		stats[i] = sub.stats()
+	}
+	return stats
+}
+
+func (b *AsyncEventBus) find(handler EventHandler) *subscriber {
+	for _, sub := range b.subscribers {
+		if sub.handler == handler {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (b *AsyncEventBus) remove(target *subscriber) {
+	for i, sub := range b.subscribers {
+		if sub == target {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// logDeadLetter is the default DeadLetterHandler
+func logDeadLetter(subscriber string, event *Event, err error) {
+	log.Error().
+		Err(err).
+		Str("subscriber", subscriber).
+		Str("event_id", event.ID.String()).
+		Str("event_type", string(event.Type)).
+		Msg("Event dead-lettered")
+}
+
+// subscriber is a handler with its own queue and workers. Its event types are
+// guarded by the bus lock.
+type subscriber struct {
+	name    string
+	handler EventHandler
+	config  SubscriberConfig
+	types   map[EventType]bool
+	queue   chan *Event
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	inFlight     int64
+	enqueued     int64
+	rejected     int64
+	delivered    int64
+	retried      int64
+	deadLettered int64
+}
+
+func newSubscriber(handler EventHandler, config SubscriberConfig) *subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &subscriber{
+		name:    config.Name,
+		handler: handler,
+		config:  config,
+		types:   make(map[EventType]bool),
+		queue:   make(chan *Event, config.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (s *subscriber) wants(eventType EventType) bool {
+	return s.types[eventType]
+}
+
+func (s *subscriber) addTypes(eventTypes []EventType) {
+	for _, eventType := range eventTypes {
+		s.types[eventType] = true
+	}
+}
+
+func (s *subscriber) removeType(eventType EventType) {
+	delete(s.types, eventType)
+}
+
+func (s *subscriber) hasTypes() bool {
+	return len(s.types) > 0
+}
+
+// enqueue queues an event without blocking, reporting whether there was room
+func (s *subscriber) enqueue(event *Event) bool {
+	select {
+	case s.queue <- event:
+		atomic.AddInt64(&s.enqueued, 1)
+		return true
+	default:
+		atomic.AddInt64(&s.rejected, 1)
+		return false
+	}
+}
+
+func (s *subscriber) start() {
+	for i := 0; i < s.config.Concurrency; i++ {
+		s.wg.Add(1)
+		go s.run()
+	}
+}
+
+// close stops the subscriber taking events. It must only be called once the
+// subscriber is no longer reachable from the bus.
+func (s *subscriber) close() {
+	close(s.queue)
+}
+
+// abort cancels pending retries
+func (s *subscriber) abort() {
+	s.cancel()
+}
+
+func (s *subscriber) wait() {
+	s.wg.Wait()
+	s.cancel()
+}
+
+func (s *subscriber) run() {
+	defer s.wg.Done()
+
+	for event := range s.queue {
+		s.deliver(event)
+	}
+}
+
+// deliver handles an event, retrying with exponential backoff up to
+// MaxAttempts before dead-lettering it
+func (s *subscriber) deliver(event *Event) {
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.handle(event); err == nil {
+			atomic.AddInt64(&s.delivered, 1)
+			return
+		}
+
+		if attempt >= s.config.MaxAttempts {
+			break
+		}
+
+		log.Warn().
+			Err(err).
+			Str("subscriber", s.name).
+			Str("event_id", event.ID.String()).
+			Int("attempt", attempt).
+			Msg("Event handler failed, retrying")
+
+		backoff := s.config.Backoff << uint(attempt-1)
+		if backoff <= 0 || backoff > subscriberMaxBackoff {
+			backoff = subscriberMaxBackoff
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("event bus stopped before retry: %w", err)
+			atomic.AddInt64(&s.deadLettered, 1)
+			s.config.DeadLetter(s.name, event, err)
+			return
+		}
+		atomic.AddInt64(&s.retried, 1)
+	}
+
+	atomic.AddInt64(&s.deadLettered, 1)
+	s.config.DeadLetter(s.name, event, err)
+}
+
+// handle runs the handler, turning a panic into an error so one bad event
+// cannot take a worker down
+func (s *subscriber) handle(event *Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event handler panicked: %v", r)
+		}
+	}()
+	return s.handler.Handle(event)
+}
+
+func (s *subscriber) stats() SubscriberStats {
+	eventTypes := make([]EventType, 0, len(s.types))
+	for eventType := range s.types {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Slice(eventTypes, func(i, j int) bool { return eventTypes[i] < eventTypes[j] })
+
+	return SubscriberStats{
+		Name:         s.name,
+		EventTypes:   eventTypes,
+		QueueLength:  len(s.queue),
+		QueueSize:    cap(s.queue),
+		InFlight:     atomic.LoadInt64(&s.inFlight),
+		Enqueued:     atomic.LoadInt64(&s.enqueued),
+		Rejected:     atomic.LoadInt64(&s.rejected),
+		Delivered:    atomic.LoadInt64(&s.delivered),
+		Retried:      atomic.LoadInt64(&s.retried),
+		DeadLettered: atomic.LoadInt64(&s.deadLettered),
+	}
+}
@@ -0,0 +1,55 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// AnyVersion appends to a stream whatever its current version
+const AnyVersion = -1
+
+// eventVersionConstraint is the unique index on (aggregate_id, version)
+const eventVersionConstraint = "idx_events_aggregate_version"
+
+// ErrConcurrencyConflict is returned when events are appended to a stream
+// that has moved past the version the caller expected
+var ErrConcurrencyConflict = errors.New("concurrency conflict")
+
+// ConcurrencyConflictError describes a concurrency conflict. It matches
+// ErrConcurrencyConflict with errors.Is.
+type ConcurrencyConflictError struct {
+	AggregateID     uuid.UUID
+	ExpectedVersion int
+	ActualVersion   int
+}
+
+func (e *ConcurrencyConflictError) Error() string {
+	return fmt.Sprintf("concurrency conflict on %s: expected version %d, stream is at %d", e.AggregateID, e.ExpectedVersion, e.ActualVersion)
+}
+
+// Is reports whether target is ErrConcurrencyConflict
+func (e *ConcurrencyConflictError) Is(target error) bool {
+	return target == ErrConcurrencyConflict
+}
+
+// IsVersionConflict reports whether err is a violation of the unique
+// (aggregate_id, version) index, meaning another writer took the version
+func IsVersionConflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == eventVersionConstraint
+}
+
+// assignVersions checks events belong to aggregateID and numbers them from
+// version+1
+func assignVersions(aggregateID uuid.UUID, version int, events []*Event) error {
+	for i, event := range events {
+		if event.AggregateID != aggregateID {
+			return fmt.Errorf("event %s belongs to aggregate %s, not %s", event.ID, event.AggregateID, aggregateID)
+		}
+		event.Version = version + i + 1
+	}
+	return nil
+}
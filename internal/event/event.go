@@ -2,6 +2,7 @@ package event
 
 import (
 	"encoding/json"
+	"insider-backend/internal/domain"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,10 +19,35 @@ const (
 	TransactionCompletedEvent EventType = "transaction.completed"
 	TransactionFailedEvent    EventType = "transaction.failed"
 	TransactionCancelledEvent EventType = "transaction.cancelled"
+	TransactionReversedEvent  EventType = "transaction.reversed"
 	BalanceCreditedEvent      EventType = "balance.credited"
 	BalanceDebitedEvent       EventType = "balance.debited"
 )
 
+// KnownEventTypes lists every event type the application emits
+var KnownEventTypes = []EventType{
+	UserCreatedEvent,
+	UserUpdatedEvent,
+	UserDeletedEvent,
+	TransactionCreatedEvent,
+	TransactionCompletedEvent,
+	TransactionFailedEvent,
+	TransactionCancelledEvent,
+	TransactionReversedEvent,
+	BalanceCreditedEvent,
+	BalanceDebitedEvent,
+}
+
+// IsKnownEventType reports whether eventType is emitted by the application
+func IsKnownEventType(eventType string) bool {
+	for _, known := range KnownEventTypes {
+		if string(known) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
 // Event represents a domain event
 type Event struct {
 	ID          uuid.UUID       `json:"id"`
@@ -73,10 +99,13 @@ type EventHandler interface {
 // EventStore defines the interface for storing and retrieving events
 type EventStore interface {
 	SaveEvent(event *Event) error
+	AppendEvents(aggregateID uuid.UUID, expectedVersion int, events ...*Event) error
 	GetEvents(aggregateID uuid.UUID) ([]*Event, error)
 	GetEventsByType(eventType EventType, limit, offset int) ([]*Event, error)
 	GetEventsAfter(timestamp time.Time, limit int) ([]*Event, error)
+	GetEventsAfterVersion(aggregateID uuid.UUID, version int) ([]*Event, error)
 	GetLastEventVersion(aggregateID uuid.UUID) (int, error)
+	QueryEvents(query EventQuery) (*EventPage, error)
 }
 
 // EventBus defines the interface for publishing and subscribing to events
@@ -125,14 +154,37 @@ type UserDeletedEventData struct {
 
 // TransactionCreatedEventData represents data for transaction created events
 type TransactionCreatedEventData struct {
-	TransactionID uuid.UUID  `json:"transaction_id"`
-	FromUserID    *uuid.UUID `json:"from_user_id"`
-	ToUserID      *uuid.UUID `json:"to_user_id"`
-	Amount        float64    `json:"amount"`
-	Type          string     `json:"type"`
-	Status        string     `json:"status"`
-	Description   string     `json:"description"`
-	ReferenceID   string     `json:"reference_id"`
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	FromUserID    *uuid.UUID      `json:"from_user_id"`
+	ToUserID      *uuid.UUID      `json:"to_user_id"`
+	Amount        domain.Money    `json:"amount"`
+	Currency      domain.Currency `json:"currency"`
+	Type          string          `json:"type"`
+	Status        string          `json:"status"`
+	Description   string          `json:"description"`
+	ReferenceID   string          `json:"reference_id"`
+
+	ReversesTransactionID *uuid.UUID `json:"reverses_transaction_id,omitempty"`
+	HoldID                *uuid.UUID `json:"hold_id,omitempty"`
+	ScheduleID            *uuid.UUID `json:"schedule_id,omitempty"`
+}
+
+// NewTransactionCreatedEventData builds the created event data of a transaction
+func NewTransactionCreatedEventData(t *domain.Transaction) TransactionCreatedEventData {
+	return TransactionCreatedEventData{
+		TransactionID:         t.ID,
+		FromUserID:            t.FromUserID,
+		ToUserID:              t.ToUserID,
+		Amount:                t.Amount,
+		Currency:              t.Currency,
+		Type:                  string(t.Type),
+		Status:                string(t.Status),
+		Description:           t.Description,
+		ReferenceID:           t.ReferenceID,
+		ReversesTransactionID: t.ReversesTransactionID,
+		HoldID:                t.HoldID,
+		ScheduleID:            t.ScheduleID,
+	}
 }
 
 // TransactionStatusChangedEventData represents data for transaction status change events
@@ -145,12 +197,20 @@ type TransactionStatusChangedEventData struct {
 
 // BalanceChangedEventData represents data for balance change events
 type BalanceChangedEventData struct {
-	UserID        uuid.UUID  `json:"user_id"`
-	OldBalance    float64    `json:"old_balance"`
-	NewBalance    float64    `json:"new_balance"`
-	Amount        float64    `json:"amount"`
-	Operation     string     `json:"operation"`
-	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
+	UserID        uuid.UUID       `json:"user_id"`
+	Currency      domain.Currency `json:"currency"`
+	OldBalance    domain.Money    `json:"old_balance"`
+	NewBalance    domain.Money    `json:"new_balance"`
+	Amount        domain.Money    `json:"amount"`
+	Operation     string          `json:"operation"`
+	TransactionID *uuid.UUID      `json:"transaction_id,omitempty"`
+}
+
+// BalanceAggregateID returns the aggregate ID of the balance events of a
+// user's wallet in currency. It is derived from both, so every wallet has its
+// own stream separate from the user's.
+func BalanceAggregateID(userID uuid.UUID, currency domain.Currency) uuid.UUID {
+	return uuid.NewSHA1(userID, []byte(currency))
 }
 
 // Snapshot represents a point-in-time snapshot of an aggregate
@@ -212,6 +272,7 @@ type EventQuery struct {
 	Filter EventFilter `json:"filter"`
 	SortBy string      `json:"sort_by"`
 	Order  string      `json:"order"`
+	Cursor string      `json:"cursor,omitempty"`
 }
 
 // EventReplay allows replaying events for rebuilding projections
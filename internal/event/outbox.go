@@ -0,0 +1,118 @@
+package event
+
+import (
+	"fmt"
+	"time"
+)
+
+// OutboxStatus represents the delivery state of an outbox message
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusDelivered OutboxStatus = "delivered"
+	OutboxStatusFailed    OutboxStatus = "failed"
+)
+
+// Outbox retry backoff bounds
+const (
+	outboxBaseBackoff = time.Second
+	outboxMaxBackoff  = 5 * time.Minute
+)
+
+// OutboxMessage is an event waiting to be delivered to an EventPublisher. It
+// is written in the same database transaction as the event and the state
+// change it describes, so no committed change goes unannounced.
+type OutboxMessage struct {
+	ID            int64        `json:"id"`
+	Event         *Event       `json:"event"`
+	Status        OutboxStatus `json:"status"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error,omitempty"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	DeliveredAt   *time.Time   `json:"delivered_at,omitempty"`
+}
+
+// MarkDelivered records a successful delivery
+func (m *OutboxMessage) MarkDelivered(now time.Time) {
+	m.Attempts++
+	m.Status = OutboxStatusDelivered
+	m.LastError = ""
+	m.DeliveredAt = &now
+}
+
+// MarkAttemptFailed records a failed delivery. The message is retried with
+// exponential backoff and parked as failed after maxAttempts.
+func (m *OutboxMessage) MarkAttemptFailed(err error, now time.Time, maxAttempts int) {
+	m.Attempts++
+	m.LastError = err.Error()
+
+	if m.Attempts >= maxAttempts {
+		m.Status = OutboxStatusFailed
+		return
+	}
+
+	backoff := outboxBaseBackoff << uint(m.Attempts-1)
+	if backoff <= 0 || backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	m.NextAttemptAt = now.Add(backoff)
+}
+
+// BusPublisher delivers events to the subscribers of an in-process EventBus
+type BusPublisher struct {
+	bus EventBus
+}
+
+// NewBusPublisher creates a publisher for bus
+func NewBusPublisher(bus EventBus) *BusPublisher {
+	return &BusPublisher{bus: bus}
+}
+
+// Publish publishes an event on the bus
+func (p *BusPublisher) Publish(event *Event) error {
+	return p.bus.Publish(event)
+}
+
+// PublishBatch publishes events on the bus in order, stopping at the first failure
+func (p *BusPublisher) PublishBatch(events []*Event) error {
+	for _, event := range events {
+		if err := p.bus.Publish(event); err != nil {
+			return fmt.Errorf("failed to publish event %s: %w", event.ID, err)
+		}
+	}
+	return nil
+}
+
+// MultiPublisher delivers every event to each of its publishers in turn
+type MultiPublisher struct {
+	publishers []EventPublisher
+}
+
+// NewMultiPublisher creates a publisher fanning out to publishers
+func NewMultiPublisher(publishers ...EventPublisher) *MultiPublisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+// Publish publishes an event to every publisher, stopping at the first
+// failure. Publishers before the failing one see the event again when it is
+// retried.
+func (p *MultiPublisher) Publish(event *Event) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishBatch publishes events to every publisher in order, stopping at the first failure
+func (p *MultiPublisher) PublishBatch(events []*Event) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.PublishBatch(events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package event
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrProjectionNotFound is returned for a projection that is not registered
+var ErrProjectionNotFound = errors.New("projection not found")
+
+// defaultReplayBatchSize is the page size of replays and catch-ups that do not set one
+const defaultReplayBatchSize = 500
+
+// eventCursor pages through events in creation order. Events created in the
+// same microsecond can straddle a page boundary, so each page is read from
+// just before the current position and the events already seen there are
+// skipped.
+type eventCursor struct {
+	store    EventStore
+	position time.Time
+	seen     map[uuid.UUID]bool
+}
+
+// newEventCursor creates a cursor positioned after lastEventID, created at position
+func newEventCursor(store EventStore, position time.Time, lastEventID uuid.UUID) *eventCursor {
+	cursor := &eventCursor{store: store, position: position, seen: make(map[uuid.UUID]bool)}
+	if lastEventID != uuid.Nil {
+		cursor.seen[lastEventID] = true
+	}
+	return cursor
+}
+
+// next reads the next page of at most limit events and reports whether more may follow
+func (c *eventCursor) next(limit int) ([]*Event, bool, error) {
+	from := c.position
+	if len(c.seen) > 0 {
+		from = from.Add(-time.Microsecond)
+	}
+
+	events, err := c.store.GetEventsAfter(from, limit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	fresh := make([]*Event, 0, len(events))
+	for _, event := range events {
+		if c.seen[event.ID] {
+			continue
+		}
+		fresh = append(fresh, event)
+	}
+
+	more := len(events) == limit
+	if more && len(fresh) == 0 {
+		return nil, false, fmt.Errorf("more than %d events created at %s", limit, c.position.Format(time.RFC3339Nano))
+	}
+
+	return fresh, more, nil
+}
+
+// advance moves the cursor past event
+func (c *eventCursor) advance(event *Event) {
+	if !event.CreatedAt.Equal(c.position) {
+		c.position = event.CreatedAt
+		c.seen = make(map[uuid.UUID]bool)
+	}
+	c.seen[event.ID] = true
+}
+
+// ProjectionCheckpoint records how far a projection has read the event stream
+type ProjectionCheckpoint struct {
+	Name            string    `json:"name"`
+	Position        time.Time `json:"position"`
+	LastEventID     uuid.UUID `json:"last_event_id"`
+	EventsProcessed int64     `json:"events_processed"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// CheckpointStore defines the interface for storing projection checkpoints
+type CheckpointStore interface {
+	// GetCheckpoint returns the checkpoint of a projection, positioned before
+	// the first event when none was saved
+	GetCheckpoint(name string) (*ProjectionCheckpoint, error)
+	SaveCheckpoint(checkpoint *ProjectionCheckpoint) error
+}
+
+// PostgresCheckpointStore implements CheckpointStore using PostgreSQL
+type PostgresCheckpointStore struct {
+	db *sql.DB
+}
+
+// NewPostgresCheckpointStore creates a new PostgreSQL checkpoint store
+func NewPostgresCheckpointStore(db *sql.DB) *PostgresCheckpointStore {
+	return &PostgresCheckpointStore{db: db}
+}
+
+// GetCheckpoint retrieves the checkpoint of a projection
+func (s *PostgresCheckpointStore) GetCheckpoint(name string) (*ProjectionCheckpoint, error) {
+	query := `
+		SELECT name, position, last_event_id, events_processed, updated_at
+		FROM projection_checkpoints
+		WHERE name = $1`
+
+	checkpoint := &ProjectionCheckpoint{}
+	var lastEventID uuid.NullUUID
+	err := s.db.QueryRow(query, name).Scan(
+		&checkpoint.Name,
+		&checkpoint.Position,
+		&lastEventID,
+		&checkpoint.EventsProcessed,
+		&checkpoint.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &ProjectionCheckpoint{Name: name}, nil
+		}
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	checkpoint.LastEventID = lastEventID.UUID
+
+	return checkpoint, nil
+}
+
+// SaveCheckpoint saves the checkpoint of a projection
+func (s *PostgresCheckpointStore) SaveCheckpoint(checkpoint *ProjectionCheckpoint) error {
+	query := `
+		INSERT INTO projection_checkpoints (name, position, last_event_id, events_processed, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name)
+		DO UPDATE SET
+			position = EXCLUDED.position,
+			last_event_id = EXCLUDED.last_event_id,
+			events_processed = EXCLUDED.events_processed,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err := s.db.Exec(query,
+		checkpoint.Name,
+		checkpoint.Position,
+		uuid.NullUUID{UUID: checkpoint.LastEventID, Valid: checkpoint.LastEventID != uuid.Nil},
+		checkpoint.EventsProcessed,
+		checkpoint.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// ProjectionRunner keeps projections up to date by tailing the event store.
+// Each projection has its own checkpoint and is fed every event after it.
+// Checkpoints are saved after each page, so events may be handled again
+// after a failure or restart and Handle must be idempotent.
+type ProjectionRunner struct {
+	store       EventStore
+	service     *EventService
+	checkpoints CheckpointStore
+	projections []ProjectionHandler
+	batchSize   int
+	lag         time.Duration
+
+	// mu keeps catch-ups and rebuilds from running at the same time
+	mu sync.Mutex
+}
+
+// NewProjectionRunner creates a projection runner reading batchSize events at
+// a time. Events younger than lag are left for a later run, so events
+// committed late by a slower transaction are not skipped.
+func NewProjectionRunner(store EventStore, service *EventService, checkpoints CheckpointStore, batchSize int, lag time.Duration) *ProjectionRunner {
+	if batchSize <= 0 {
+		batchSize = defaultReplayBatchSize
+	}
+
+	return &ProjectionRunner{
+		store:       store,
+		service:     service,
+		checkpoints: checkpoints,
+		batchSize:   batchSize,
+		lag:         lag,
+	}
+}
+
+// Register adds a projection to the runner. Projections must be registered
+// before the runner is first used.
+func (r *ProjectionRunner) Register(projection ProjectionHandler) {
+	r.projections = append(r.projections, projection)
+}
+
+// CatchUp feeds every projection the events after its checkpoint and returns
+// how many events were handled in total
+func (r *ProjectionRunner) CatchUp(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := 0
+	for _, projection := range r.projections {
+		handled, err := r.catchUp(ctx, projection)
+		total += handled
+		if err != nil {
+			return total, fmt.Errorf("projection %s: %w", projection.GetName(), err)
+		}
+	}
+
+	return total, nil
+}
+
+func (r *ProjectionRunner) catchUp(ctx context.Context, projection ProjectionHandler) (int, error) {
+	checkpoint, err := r.checkpoints.GetCheckpoint(projection.GetName())
+	if err != nil {
+		return 0, err
+	}
+
+	cursor := newEventCursor(r.store, checkpoint.Position, checkpoint.LastEventID)
+	cutoff := time.Now().Add(-r.lag)
+	handled := 0
+
+	for {
+		events, more, err := cursor.next(r.batchSize)
+		if err != nil {
+			return handled, err
+		}
+
+		pageHandled := 0
+		var handleErr error
+		for _, event := range events {
+			if event.CreatedAt.After(cutoff) {
+				more = false
+				break
+			}
+			if handleErr = projection.Handle(event); handleErr != nil {
+				handleErr = fmt.Errorf("failed to handle event %s: %w", event.ID, handleErr)
+				break
+			}
+			cursor.advance(event)
+			checkpoint.Position = event.CreatedAt
+			checkpoint.LastEventID = event.ID
+			pageHandled++
+		}
+
+		if pageHandled > 0 {
+			checkpoint.EventsProcessed += int64(pageHandled)
+			checkpoint.UpdatedAt = time.Now()
+			if err := r.checkpoints.SaveCheckpoint(checkpoint); err != nil {
+				return handled, err
+			}
+			handled += pageHandled
+		}
+
+		if handleErr != nil {
+			return handled, handleErr
+		}
+
+		if !more {
+			return handled, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return handled, ctx.Err()
+		default:
+		}
+	}
+}
+
+// Rebuild clears a projection and replays every event into it, returning how
+// many events were handled
+func (r *ProjectionRunner) Rebuild(ctx context.Context, name string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	projection := r.find(name)
+	if projection == nil {
+		return 0, ErrProjectionNotFound
+	}
+
+	checkpoint := &ProjectionCheckpoint{Name: name, UpdatedAt: time.Now()}
+	if err := r.checkpoints.SaveCheckpoint(checkpoint); err != nil {
+		return 0, err
+	}
+
+	if err := projection.Rebuild(); err != nil {
+		return 0, fmt.Errorf("failed to reset projection: %w", err)
+	}
+
+	cutoff := time.Now().Add(-r.lag)
+	replay := EventReplay{
+		ToTime:    &cutoff,
+		BatchSize: r.batchSize,
+	}
+
+	err := r.service.ReplayEvents(ctx, replay, func(event *Event) error {
+		if err := projection.Handle(event); err != nil {
+			return err
+		}
+		checkpoint.Position = event.CreatedAt
+		checkpoint.LastEventID = event.ID
+		checkpoint.EventsProcessed++
+		return nil
+	})
+
+	// Save the progress even when the replay failed, so tailing resumes from there
+	checkpoint.UpdatedAt = time.Now()
+	if saveErr := r.checkpoints.SaveCheckpoint(checkpoint); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if err != nil {
+		return int(checkpoint.EventsProcessed), err
+	}
+
+	log.Info().
+		Str("projection", name).
+		Int64("events_processed", checkpoint.EventsProcessed).
+		Msg("Projection rebuilt")
+
+	return int(checkpoint.EventsProcessed), nil
+}
+
+// Checkpoints returns the checkpoint of every registered projection
+func (r *ProjectionRunner) Checkpoints() ([]*ProjectionCheckpoint, error) {
+	checkpoints := make([]*ProjectionCheckpoint, 0, len(r.projections))
+	for _, projection := range r.projections {
+		checkpoint, err := r.checkpoints.GetCheckpoint(projection.GetName())
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	return checkpoints, nil
+}
+
+func (r *ProjectionRunner) find(name string) ProjectionHandler {
+	for _, projection := range r.projections {
+		if projection.GetName() == name {
+			return projection
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package event
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Event query sort orders
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// defaultEventQueryLimit is the page size of queries that do not set one
+const defaultEventQueryLimit = 20
+
+// ErrInvalidCursor is returned for a cursor that was not issued by QueryEvents
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EventPage is a page of events matching a query. NextCursor points after the
+// last event of the page and is empty when the page has none.
+type EventPage struct {
+	Events     []*Event `json:"events"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	HasMore    bool     `json:"has_more"`
+}
+
+// EncodeEventCursor returns the cursor pointing after an event created at
+// createdAt with id
+func EncodeEventCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeEventCursor reverses EncodeEventCursor
+func decodeEventCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	return createdAt, id, nil
+}
+
+// Validate checks the sort options of the query
+func (q EventQuery) Validate() error {
+	if q.SortBy != "" && q.SortBy != "created_at" {
+		return fmt.Errorf("unsupported sort field: %s", q.SortBy)
+	}
+	if q.Order != "" && q.Order != SortAscending && q.Order != SortDescending {
+		return fmt.Errorf("order must be %s or %s", SortAscending, SortDescending)
+	}
+	if q.Filter.FromTime != nil && q.Filter.ToTime != nil && q.Filter.ToTime.Before(*q.Filter.FromTime) {
+		return fmt.Errorf("to_time must not be before from_time")
+	}
+	return nil
+}
+
+// QueryEvents returns a page of the events matching query, ordered by creation
+// time and then ID, newest first unless the order is ascending. Pages are
+// continued with the cursor of the previous page, which stays valid while
+// new events are appended.
+func (s *PostgresEventStore) QueryEvents(query EventQuery) (*EventPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	filter := query.Filter
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultEventQueryLimit
+	}
+
+	direction, comparison := "DESC", "<"
+	if query.Order == SortAscending {
+		direction, comparison = "ASC", ">"
+	}
+
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.AggregateID != nil {
+		conditions = append(conditions, "aggregate_id = "+arg(*filter.AggregateID))
+	}
+	if len(filter.EventTypes) > 0 {
+		types := make([]string, len(filter.EventTypes))
+		for i, eventType := range filter.EventTypes {
+			types[i] = string(eventType)
+		}
+		conditions = append(conditions, "type = ANY("+arg(pq.Array(types))+")")
+	}
+	if filter.FromTime != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.FromTime))
+	}
+	if filter.ToTime != nil {
+		conditions = append(conditions, "created_at <= "+arg(*filter.ToTime))
+	}
+	if query.Cursor != "" {
+		createdAt, id, err := decodeEventCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s (%s, %s)", comparison, arg(createdAt), arg(id)))
+	}
+
+	sqlQuery := `
+		SELECT id, type, aggregate_id, data, metadata, version, created_at
+		FROM events`
+	if len(conditions) > 0 {
+		sqlQuery += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	sqlQuery += fmt.Sprintf("\n\t\tORDER BY created_at %s, id %s\n\t\tLIMIT %s", direction, direction, arg(limit+1))
+	if filter.Offset > 0 {
+		sqlQuery += " OFFSET " + arg(filter.Offset)
+	}
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*Event, 0, limit)
+	for rows.Next() {
+		event := &Event{}
+		var metadataJSON []byte
+
+		err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateID,
+			&event.Data,
+			&metadataJSON,
+			&event.Version,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+
+		if err := json.Unmarshal(metadataJSON, &event.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	page := &EventPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.HasMore = true
+	}
+	if len(page.Events) > 0 {
+		last := page.Events[len(page.Events)-1]
+		page.NextCursor = EncodeEventCursor(last.CreatedAt, last.ID)
+	}
+
+	return page, nil
+}
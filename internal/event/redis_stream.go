@@ -0,0 +1,583 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+)
+
+// Redis Streams defaults used when a RedisStreamConfig leaves a field unset
+const (
+	defaultStreamPrefix        = "events"
+	defaultStreamTimeout       = 5 * time.Second
+	defaultStreamBlock         = 2 * time.Second
+	defaultStreamBatchSize     = 100
+	defaultStreamClaimIdle     = time.Minute
+	defaultStreamMaxDeliveries = 10
+	streamErrorBackoff         = time.Second
+)
+
+// streamEventField is the stream entry field holding the event as JSON
+const streamEventField = "event"
+
+// StreamClient is the part of the Redis client used by the Redis Streams
+// backend. *redis.Client and *redis.ClusterClient satisfy it, and so does a
+// client connected to an in-process fake server.
+type StreamClient interface {
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+	XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd
+	XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
+	XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) *redis.XPendingExtCmd
+	XRange(ctx context.Context, stream, start, stop string) *redis.XMessageSliceCmd
+	TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	Do(ctx context.Context, args ...interface{}) *redis.Cmd
+}
+
+// RedisStreamConfig configures the Redis Streams backend. Zero fields take
+// the defaults.
+type RedisStreamConfig struct {
+	// Prefix of the stream keys; each event type has its own stream
+	Prefix string
+	// MaxLen is the approximate number of entries kept per stream; zero keeps all
+	MaxLen int64
+	// Timeout of a publish
+	Timeout time.Duration
+	// Group is the consumer group of a subscriber. Subscribers in the same
+	// group share the events, each handled by one of them.
+	Group string
+	// Consumer names a subscriber within its group and must be unique and
+	// stable across restarts of the same replica
+	Consumer string
+	// Block is how long a read waits for new entries
+	Block time.Duration
+	// BatchSize is the number of entries read or reclaimed at once
+	BatchSize int64
+	// ClaimIdle is how long an entry stays unacknowledged before another
+	// consumer reclaims it, which is also the interval between reclaims
+	ClaimIdle time.Duration
+	// MaxDeliveries before an entry is moved to the dead-letter stream
+	MaxDeliveries int64
+}
+
+func (c RedisStreamConfig) withDefaults() RedisStreamConfig {
+	if c.Prefix == "" {
+		c.Prefix = defaultStreamPrefix
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultStreamTimeout
+	}
+	if c.Block <= 0 {
+		c.Block = defaultStreamBlock
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultStreamBatchSize
+	}
+	if c.ClaimIdle <= 0 {
+		c.ClaimIdle = defaultStreamClaimIdle
+	}
+	if c.MaxDeliveries <= 0 {
+		c.MaxDeliveries = defaultStreamMaxDeliveries
+	}
+	return c
+}
+
+// streamKey returns the stream holding the events of a type
+func (c RedisStreamConfig) streamKey(eventType EventType) string {
+	return c.Prefix + ":" + string(eventType)
+}
+
+// deadLetterKey returns the stream holding entries given up on
+func (c RedisStreamConfig) deadLetterKey() string {
+	return c.Prefix + ":dead-letter"
+}
+
+// RedisStreamPublisher implements EventPublisher by appending events to Redis
+// Streams, one stream per event type
+type RedisStreamPublisher struct {
+	client StreamClient
+	config RedisStreamConfig
+}
+
+// NewRedisStreamPublisher creates a publisher appending to client's streams
+func NewRedisStreamPublisher(client StreamClient, config RedisStreamConfig) *RedisStreamPublisher {
+	return &RedisStreamPublisher{client: client, config: config.withDefaults()}
+}
+
+// Publish appends an event to the stream of its type
+func (p *RedisStreamPublisher) Publish(event *Event) error {
+	args, err := p.addArgs(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	defer cancel()
+
+	if err := p.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("failed to publish event %s: %w", event.ID, err)
+	}
+
+	return nil
+}
+
+// PublishBatch appends events to their streams in one MULTI/EXEC
+// transaction, so either all of them are published or none is
+func (p *RedisStreamPublisher) PublishBatch(events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	batch := make([]*redis.XAddArgs, len(events))
+	for i, event := range events {
+		args, err := p.addArgs(event)
+		if err != nil {
+			return err
+		}
+		batch[i] = args
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	defer cancel()
+
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, args := range batch {
+			pipe.XAdd(ctx, args)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %d events: %w", len(events), err)
+	}
+
+	return nil
+}
+
+func (p *RedisStreamPublisher) addArgs(event *Event) (*redis.XAddArgs, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event %s: %w", event.ID, err)
+	}
+
+	return &redis.XAddArgs{
+		Stream: p.config.streamKey(event.Type),
+		MaxLen: p.config.MaxLen,
+		Approx: p.config.MaxLen > 0,
+		Values: map[string]interface{}{
+			"id":             event.ID.String(),
+			"type":           string(event.Type),
+			streamEventField: data,
+		},
+	}, nil
+}
+
+// RedisStreamSubscriber implements EventSubscriber by reading Redis Streams
+// through a consumer group. An entry is acknowledged once the handler
+// succeeds. Entries left unacknowledged by a failing handler or a crashed
+// consumer are reclaimed by a consumer of the group after ClaimIdle, and moved
+// to the dead-letter stream after MaxDeliveries. Delivery is at least once,
+// and the handler may be called concurrently by the read and reclaim loops.
+type RedisStreamSubscriber struct {
+	client  StreamClient
+	config  RedisStreamConfig
+	streams []string
+	handler func(*Event) error
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// NewRedisStreamSubscriber creates a subscriber reading client's streams
+func NewRedisStreamSubscriber(client StreamClient, config RedisStreamConfig) *RedisStreamSubscriber {
+	return &RedisStreamSubscriber{client: client, config: config.withDefaults()}
+}
+
+// Subscribe sets the event types to read and their handler. It must be called
+// before Start.
+func (s *RedisStreamSubscriber) Subscribe(eventTypes []EventType, handler func(*Event) error) error {
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	if s.config.Group == "" || s.config.Consumer == "" {
+		return fmt.Errorf("consumer group and consumer name are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return fmt.Errorf("subscriber is already started")
+	}
+
+	streams := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		streams[i] = s.config.streamKey(eventType)
+	}
+
+	s.streams = streams
+	s.handler = handler
+
+	return nil
+}
+
+// Unsubscribe stops the subscriber and drops its subscription. Entries it
+// has not acknowledged are reclaimed by the rest of its group.
+func (s *RedisStreamSubscriber) Unsubscribe() error {
+	if err := s.Stop(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.streams = nil
+	s.handler = nil
+
+	return nil
+}
+
+// Start creates the consumer group on every stream if needed and starts
+// reading. A new group receives the events published after it is created.
+func (s *RedisStreamSubscriber) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.handler == nil {
+		return fmt.Errorf("subscriber has no subscription")
+	}
+	if s.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	for _, stream := range s.streams {
+		if err := s.client.XGroupCreateMkStream(ctx, stream, s.config.Group, "$").Err(); err != nil && !isBusyGroup(err) {
+			cancel()
+			return fmt.Errorf("failed to create consumer group on %s: %w", stream, err)
+		}
+	}
+
+	s.cancel = cancel
+	s.wg.Add(2)
+	go s.readLoop(ctx)
+	go s.reclaimLoop(ctx)
+
+	log.Info().
+		Str("group", s.config.Group).
+		Str("consumer", s.config.Consumer).
+		Strs("streams", s.streams).
+		Msg("Redis stream subscriber started")
+
+	return nil
+}
+
+// Stop stops reading and waits for the entries being handled
+func (s *RedisStreamSubscriber) Stop() error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	s.wg.Wait()
+
+	log.Info().
+		Str("group", s.config.Group).
+		Str("consumer", s.config.Consumer).
+		Msg("Redis stream subscriber stopped")
+
+	return nil
+}
+
+// Shutdown stops the subscriber. Its signature matches a pkg/shutdown callback.
+func (s *RedisStreamSubscriber) Shutdown(ctx context.Context) error {
+	return s.Stop()
+}
+
+// readLoop reads new entries for the group and handles them
+func (s *RedisStreamSubscriber) readLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ids := make([]string, 0, 2*len(s.streams))
+	ids = append(ids, s.streams...)
+	for range s.streams {
+		ids = append(ids, ">")
+	}
+
+	for ctx.Err() == nil {
+		streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.config.Group,
+			Consumer: s.config.Consumer,
+			Streams:  ids,
+			Count:    s.config.BatchSize,
+			Block:    s.config.Block,
+		}).Result()
+		if err != nil {
+			if err == redis.Nil || ctx.Err() != nil {
+				continue
+			}
+			log.Error().Err(err).Str("group", s.config.Group).Msg("Failed to read event streams")
+			sleepContext(ctx, streamErrorBackoff)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				s.handle(ctx, stream.Stream, message)
+			}
+		}
+	}
+}
+
+// reclaimLoop periodically takes over the entries other consumers of the
+// group left unacknowledged for ClaimIdle
+func (s *RedisStreamSubscriber) reclaimLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.ClaimIdle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, stream := range s.streams {
+				if err := s.reclaim(ctx, stream); err != nil && ctx.Err() == nil {
+					log.Error().Err(err).Str("stream", stream).Msg("Failed to reclaim pending events")
+				}
+			}
+		}
+	}
+}
+
+// reclaim dead-letters the idle entries of a stream delivered MaxDeliveries
+// times, then claims and handles the other idle entries
+func (s *RedisStreamSubscriber) reclaim(ctx context.Context, stream string) error {
+	pending, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  s.config.Group,
+		Idle:   s.config.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  s.config.BatchSize,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list pending entries: %w", err)
+	}
+
+	for _, entry := range pending {
+		if entry.RetryCount < s.config.MaxDeliveries {
+			continue
+		}
+		if err := s.deadLetter(ctx, stream, entry); err != nil {
+			return err
+		}
+	}
+
+	start := "0-0"
+	for {
+		messages, next, err := s.autoClaim(ctx, stream, start)
+		if err != nil {
+			return fmt.Errorf("failed to claim pending entries: %w", err)
+		}
+
+		for _, message := range messages {
+			s.handle(ctx, stream, message)
+		}
+
+		if next == "0-0" || next == "" || ctx.Err() != nil {
+			return nil
+		}
+		start = next
+	}
+}
+
+// autoClaim claims the idle entries of a stream from start and returns them
+// with the ID to continue from. XAUTOCLAIM is sent as a raw command because
+// the client only parses the Redis 6.2 reply; Redis 7 appends the IDs of
+// deleted entries. Entries deleted from the stream are acknowledged.
+func (s *RedisStreamSubscriber) autoClaim(ctx context.Context, stream, start string) ([]redis.XMessage, string, error) {
+	reply, err := s.client.Do(ctx, "XAUTOCLAIM", stream, s.config.Group, s.config.Consumer,
+		s.config.ClaimIdle.Milliseconds(), start, "COUNT", s.config.BatchSize).Slice()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(reply) < 2 {
+		return nil, "", fmt.Errorf("unexpected XAUTOCLAIM reply of %d elements", len(reply))
+	}
+
+	next, ok := reply[0].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("unexpected XAUTOCLAIM cursor %T", reply[0])
+	}
+	entries, ok := reply[1].([]interface{})
+	if !ok {
+		return nil, "", fmt.Errorf("unexpected XAUTOCLAIM entries %T", reply[1])
+	}
+
+	messages := make([]redis.XMessage, 0, len(entries))
+	for _, entry := range entries {
+		message, err := parseStreamEntry(entry)
+		if err != nil {
+			return nil, "", err
+		}
+		// Redis 6.2 returns deleted entries without fields
+		if message.Values == nil {
+			s.ack(ctx, stream, message.ID)
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	if len(reply) > 2 {
+		deleted, _ := reply[2].([]interface{})
+		for _, id := range deleted {
+			if id, ok := id.(string); ok {
+				s.ack(ctx, stream, id)
+			}
+		}
+	}
+
+	return messages, next, nil
+}
+
+// deadLetter copies a pending entry to the dead-letter stream and
+// acknowledges it
+func (s *RedisStreamSubscriber) deadLetter(ctx context.Context, stream string, entry redis.XPendingExt) error {
+	messages, err := s.client.XRange(ctx, stream, entry.ID, entry.ID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read entry %s: %w", entry.ID, err)
+	}
+
+	// An entry trimmed from its stream is only acknowledged
+	if len(messages) > 0 {
+		values := map[string]interface{}{
+			"stream":     stream,
+			"entry_id":   entry.ID,
+			"group":      s.config.Group,
+			"deliveries": entry.RetryCount,
+		}
+		for field, value := range messages[0].Values {
+			values[field] = value
+		}
+
+		if err := s.client.XAdd(ctx, &redis.XAddArgs{Stream: s.config.deadLetterKey(), Values: values}).Err(); err != nil {
+			return fmt.Errorf("failed to dead-letter entry %s: %w", entry.ID, err)
+		}
+	}
+
+	if err := s.client.XAck(ctx, stream, s.config.Group, entry.ID).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge entry %s: %w", entry.ID, err)
+	}
+
+	log.Error().
+		Str("stream", stream).
+		Str("entry_id", entry.ID).
+		Int64("deliveries", entry.RetryCount).
+		Msg("Event entry dead-lettered")
+
+	return nil
+}
+
+// handle decodes an entry, runs the handler and acknowledges the entry if it
+// succeeds. An entry that cannot be decoded is acknowledged and dropped.
+func (s *RedisStreamSubscriber) handle(ctx context.Context, stream string, message redis.XMessage) {
+	event, err := decodeStreamEvent(message)
+	if err != nil {
+		log.Error().Err(err).Str("stream", stream).Str("entry_id", message.ID).Msg("Dropping malformed event entry")
+		s.ack(ctx, stream, message.ID)
+		return
+	}
+
+	if err := s.handler(event); err != nil {
+		log.Warn().
+			Err(err).
+			Str("stream", stream).
+			Str("entry_id", message.ID).
+			Str("event_id", event.ID.String()).
+			Msg("Event handler failed, entry left pending")
+		return
+	}
+
+	s.ack(ctx, stream, message.ID)
+}
+
+func (s *RedisStreamSubscriber) ack(ctx context.Context, stream, id string) {
+	if err := s.client.XAck(ctx, stream, s.config.Group, id).Err(); err != nil {
+		log.Error().Err(err).Str("stream", stream).Str("entry_id", id).Msg("Failed to acknowledge event entry")
+	}
+}
+
+// decodeStreamEvent decodes the event of a stream entry
+func decodeStreamEvent(message redis.XMessage) (*Event, error) {
+	raw, ok := message.Values[streamEventField].(string)
+	if !ok {
+		return nil, errors.New("entry has no event field")
+	}
+
+	event := &Event{}
+	if err := json.Unmarshal([]byte(raw), event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	return event, nil
+}
+
+// parseStreamEntry parses an [id, [field, value, ...]] stream entry. The
+// values of an entry without fields are nil.
+func parseStreamEntry(entry interface{}) (redis.XMessage, error) {
+	parts, ok := entry.([]interface{})
+	if !ok || len(parts) != 2 {
+		return redis.XMessage{}, fmt.Errorf("unexpected stream entry %v", entry)
+	}
+	id, ok := parts[0].(string)
+	if !ok {
+		return redis.XMessage{}, fmt.Errorf("unexpected stream entry ID %T", parts[0])
+	}
+
+	message := redis.XMessage{ID: id}
+	fields, ok := parts[1].([]interface{})
+	if !ok {
+		return message, nil
+	}
+
+	message.Values = make(map[string]interface{}, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		field, ok := fields[i].(string)
+		if !ok {
+			return redis.XMessage{}, fmt.Errorf("unexpected field of stream entry %s", id)
+		}
+		message.Values[field] = fields[i+1]
+	}
+
+	return message, nil
+}
+
+// isBusyGroup reports whether err says the consumer group already exists
+func isBusyGroup(err error) bool {
+	return strings.HasPrefix(err.Error(), "BUSYGROUP")
+}
+
+// sleepContext waits for d or until ctx ends
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
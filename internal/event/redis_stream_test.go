@@ -0,0 +1,247 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const testStreamGroup = "test-group"
+
+// newTestStreamClient returns a client connected to an in-process fake Redis
+func newTestStreamClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func testStreamConfig(consumer string) RedisStreamConfig {
+	return RedisStreamConfig{
+		Prefix:        "test-events",
+		Group:         testStreamGroup,
+		Consumer:      consumer,
+		Block:         50 * time.Millisecond,
+		ClaimIdle:     100 * time.Millisecond,
+		MaxDeliveries: 3,
+	}
+}
+
+func newTestEvent(t *testing.T, eventType EventType) *Event {
+	t.Helper()
+
+	event, err := NewEvent(eventType, uuid.New(), map[string]string{"key": "value"}, Metadata{Source: "test"}, 1)
+	if err != nil {
+		t.Fatalf("NewEvent() error = %v", err)
+	}
+	return event
+}
+
+// eventRecorder is a subscriber handler that records the events it handled
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []*Event
+	fail   error
+}
+
+func (r *eventRecorder) handle(event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+	return r.fail
+}
+
+func (r *eventRecorder) ids() map[uuid.UUID]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make(map[uuid.UUID]int, len(r.events))
+	for _, event := range r.events {
+		ids[event.ID]++
+	}
+	return ids
+}
+
+// startTestSubscriber starts a subscriber to eventTypes that stops when the
+// test ends
+func startTestSubscriber(t *testing.T, client StreamClient, config RedisStreamConfig, recorder *eventRecorder, eventTypes ...EventType) {
+	t.Helper()
+
+	subscriber := NewRedisStreamSubscriber(client, config)
+	if err := subscriber.Subscribe(eventTypes, recorder.handle); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := subscriber.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { subscriber.Stop() })
+}
+
+// waitFor polls condition until it holds or the test times out
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func pendingCount(t *testing.T, client *redis.Client, stream string) int64 {
+	t.Helper()
+
+	pending, err := client.XPending(context.Background(), stream, testStreamGroup).Result()
+	if err != nil {
+		t.Fatalf("XPending(%s) error = %v", stream, err)
+	}
+	return pending.Count
+}
+
+func TestRedisStreamPublishConsumeAck(t *testing.T) {
+	client := newTestStreamClient(t)
+	config := testStreamConfig("consumer-1")
+
+	recorder := &eventRecorder{}
+	startTestSubscriber(t, client, config, recorder, TransactionCreatedEvent)
+
+	event := newTestEvent(t, TransactionCreatedEvent)
+	if err := NewRedisStreamPublisher(client, config).Publish(event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	waitFor(t, "the event to be handled", func() bool { return recorder.ids()[event.ID] == 1 })
+
+	recorder.mu.Lock()
+	handled := recorder.events[0]
+	recorder.mu.Unlock()
+	if handled.Type != event.Type || handled.AggregateID != event.AggregateID || string(handled.Data) != string(event.Data) {
+		t.Errorf("handled event = %+v, want %+v", handled, event)
+	}
+
+	stream := config.withDefaults().streamKey(TransactionCreatedEvent)
+	waitFor(t, "the entry to be acknowledged", func() bool { return pendingCount(t, client, stream) == 0 })
+}
+
+func TestRedisStreamPublishBatch(t *testing.T) {
+	client := newTestStreamClient(t)
+	config := testStreamConfig("consumer-1")
+
+	recorder := &eventRecorder{}
+	startTestSubscriber(t, client, config, recorder, TransactionCreatedEvent, BalanceCreditedEvent)
+
+	events := []*Event{
+		newTestEvent(t, TransactionCreatedEvent),
+		newTestEvent(t, BalanceCreditedEvent),
+		newTestEvent(t, TransactionCreatedEvent),
+	}
+	publisher := NewRedisStreamPublisher(client, config)
+	if err := publisher.PublishBatch(events); err != nil {
+		t.Fatalf("PublishBatch() error = %v", err)
+	}
+	if err := publisher.PublishBatch(nil); err != nil {
+		t.Errorf("PublishBatch(nil) error = %v", err)
+	}
+
+	keys := config.withDefaults()
+	tests := []struct {
+		eventType EventType
+		want      int64
+	}{
+		{TransactionCreatedEvent, 2},
+		{BalanceCreditedEvent, 1},
+	}
+	for _, tt := range tests {
+		if got := client.XLen(context.Background(), keys.streamKey(tt.eventType)).Val(); got != tt.want {
+			t.Errorf("XLen(%s) = %d, want %d", tt.eventType, got, tt.want)
+		}
+	}
+
+	waitFor(t, "every event to be handled", func() bool { return len(recorder.ids()) == len(events) })
+	for _, event := range events {
+		if got := recorder.ids()[event.ID]; got != 1 {
+			t.Errorf("event %s handled %d times, want 1", event.ID, got)
+		}
+	}
+}
+
+func TestRedisStreamReclaimAfterConsumerCrash(t *testing.T) {
+	ctx := context.Background()
+	client := newTestStreamClient(t)
+	config := testStreamConfig("survivor")
+	stream := config.withDefaults().streamKey(TransactionCreatedEvent)
+
+	if err := client.XGroupCreateMkStream(ctx, stream, testStreamGroup, "$").Err(); err != nil {
+		t.Fatalf("XGroupCreateMkStream() error = %v", err)
+	}
+
+	event := newTestEvent(t, TransactionCreatedEvent)
+	if err := NewRedisStreamPublisher(client, config).Publish(event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	// A consumer reads the entry and crashes before acknowledging it
+	read, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    testStreamGroup,
+		Consumer: "crashed",
+		Streams:  []string{stream, ">"},
+		Count:    10,
+		Block:    -1,
+	}).Result()
+	if err != nil || len(read) != 1 || len(read[0].Messages) != 1 {
+		t.Fatalf("XReadGroup() = %v, %v, want one entry", read, err)
+	}
+	if got := pendingCount(t, client, stream); got != 1 {
+		t.Fatalf("pending entries = %d, want 1", got)
+	}
+
+	recorder := &eventRecorder{}
+	startTestSubscriber(t, client, config, recorder, TransactionCreatedEvent)
+
+	waitFor(t, "the entry to be reclaimed", func() bool { return recorder.ids()[event.ID] > 0 })
+	waitFor(t, "the reclaimed entry to be acknowledged", func() bool { return pendingCount(t, client, stream) == 0 })
+}
+
+func TestRedisStreamDeadLettersAfterMaxDeliveries(t *testing.T) {
+	client := newTestStreamClient(t)
+	config := testStreamConfig("consumer-1")
+	keys := config.withDefaults()
+
+	recorder := &eventRecorder{fail: errors.New("handler failed")}
+	startTestSubscriber(t, client, config, recorder, TransactionCreatedEvent)
+
+	event := newTestEvent(t, TransactionCreatedEvent)
+	if err := NewRedisStreamPublisher(client, config).Publish(event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	waitFor(t, "the entry to be dead-lettered", func() bool {
+		return client.XLen(context.Background(), keys.deadLetterKey()).Val() == 1
+	})
+
+	entries, err := client.XRange(context.Background(), keys.deadLetterKey(), "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	if got := entries[0].Values["id"]; got != event.ID.String() {
+		t.Errorf("dead letter id = %v, want %s", got, event.ID)
+	}
+	if got := entries[0].Values["stream"]; got != keys.streamKey(TransactionCreatedEvent) {
+		t.Errorf("dead letter stream = %v, want %s", got, keys.streamKey(TransactionCreatedEvent))
+	}
+	if got := pendingCount(t, client, keys.streamKey(TransactionCreatedEvent)); got != 0 {
+		t.Errorf("pending entries = %d, want 0", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -43,6 +44,9 @@ func (s *PostgresEventStore) SaveEvent(event *Event) error {
 	)
 
 	if err != nil {
+		if IsVersionConflict(err) {
+			return s.conflict(event.AggregateID, event.Version-1)
+		}
 		return fmt.Errorf("failed to save event: %w", err)
 	}
 
@@ -56,6 +60,78 @@ func (s *PostgresEventStore) SaveEvent(event *Event) error {
 	return nil
 }
 
+// AppendEvents saves events to an aggregate's stream in one database
+// transaction, numbering them from expectedVersion+1. It returns a
+// ConcurrencyConflictError if the stream is no longer at expectedVersion;
+// pass AnyVersion to append whatever the version. Appends to a stream are
+// serialized with the same advisory lock as the outbox.
+func (s *PostgresEventStore) AppendEvents(aggregateID uuid.UUID, expectedVersion int, events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, aggregateID.String()); err != nil {
+		return fmt.Errorf("failed to lock event stream: %w", err)
+	}
+
+	var version int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM events WHERE aggregate_id = $1`, aggregateID).Scan(&version); err != nil {
+		return fmt.Errorf("failed to get last event version: %w", err)
+	}
+	if expectedVersion != AnyVersion && version != expectedVersion {
+		return &ConcurrencyConflictError{AggregateID: aggregateID, ExpectedVersion: expectedVersion, ActualVersion: version}
+	}
+
+	if err := assignVersions(aggregateID, version, events); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO events (id, type, aggregate_id, data, metadata, version, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	for _, event := range events {
+		metadataJSON, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+
+		_, err = tx.Exec(query,
+			event.ID,
+			event.Type,
+			event.AggregateID,
+			event.Data,
+			metadataJSON,
+			event.Version,
+			event.CreatedAt,
+		)
+		if err != nil {
+			if IsVersionConflict(err) {
+				return s.conflict(aggregateID, version)
+			}
+			return fmt.Errorf("failed to save event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit events: %w", err)
+	}
+
+	log.Debug().
+		Str("aggregate_id", aggregateID.String()).
+		Int("from_version", version+1).
+		Int("events", len(events)).
+		Msg("Events appended")
+
+	return nil
+}
+
 // GetEvents retrieves all events for a specific aggregate
 func (s *PostgresEventStore) GetEvents(aggregateID uuid.UUID) ([]*Event, error) {
 	query := `
@@ -147,7 +223,7 @@ func (s *PostgresEventStore) GetEventsAfter(timestamp time.Time, limit int) ([]*
 		SELECT id, type, aggregate_id, data, metadata, version, created_at
 		FROM events 
 		WHERE created_at > $1
-		ORDER BY created_at ASC
+		ORDER BY created_at ASC, id ASC
 		LIMIT $2`
 
 	rows, err := s.db.Query(query, timestamp, limit)
@@ -184,101 +260,76 @@ func (s *PostgresEventStore) GetEventsAfter(timestamp time.Time, limit int) ([]*
 	return events, nil
 }
 
-// GetLastEventVersion retrieves the last event version for an aggregate
-func (s *PostgresEventStore) GetLastEventVersion(aggregateID uuid.UUID) (int, error) {
-	query := `
-		SELECT COALESCE(MAX(version), 0) 
-		FROM events 
-		WHERE aggregate_id = $1`
-
-	var version int
-	err := s.db.QueryRow(query, aggregateID).Scan(&version)
+// conflict builds the error for an append that lost the race for a version
+func (s *PostgresEventStore) conflict(aggregateID uuid.UUID, expectedVersion int) error {
+	actual, err := s.GetLastEventVersion(aggregateID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get last event version: %w", err)
+		actual = AnyVersion
 	}
-
-	return version, nil
-}
-
-// InMemoryEventBus implements EventBus using in-memory storage
-type InMemoryEventBus struct {
-	handlers map[EventType][]EventHandler
+	return &ConcurrencyConflictError{AggregateID: aggregateID, ExpectedVersion: expectedVersion, ActualVersion: actual}
 }
 
-// NewInMemoryEventBus creates a new in-memory event bus
-func NewInMemoryEventBus() *InMemoryEventBus {
-	return &InMemoryEventBus{
-		handlers: make(map[EventType][]EventHandler),
-	}
-}
+// GetEventsAfterVersion retrieves the events of an aggregate after a version
+func (s *PostgresEventStore) GetEventsAfterVersion(aggregateID uuid.UUID, version int) ([]*Event, error) {
+	query := `
+		SELECT id, type, aggregate_id, data, metadata, version, created_at
+		FROM events
+		WHERE aggregate_id = $1 AND version > $2
+		ORDER BY version ASC`
 
-// Publish publishes an event to all registered handlers
-func (b *InMemoryEventBus) Publish(event *Event) error {
-	handlers, exists := b.handlers[event.Type]
-	if !exists {
-		log.Debug().
-			Str("event_type", string(event.Type)).
-			Msg("No handlers registered for event type")
-		return nil
+	rows, err := s.db.Query(query, aggregateID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events after version: %w", err)
 	}
+	defer rows.Close()
 
-	for _, handler := range handlers {
-		go func(h EventHandler) {
-			if err := h.Handle(event); err != nil {
-				log.Error().
-					Err(err).
-					Str("event_id", event.ID.String()).
-					Str("event_type", string(event.Type)).
-					Msg("Failed to handle event")
-			}
-		}(handler)
-	}
+	var events []*Event
+	for rows.Next() {
+		event := &Event{}
+		var metadataJSON []byte
 
-	log.Debug().
-		Str("event_id", event.ID.String()).
-		Str("event_type", string(event.Type)).
-		Int("handlers", len(handlers)).
-		Msg("Event published")
+		err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateID,
+			&event.Data,
+			&metadataJSON,
+			&event.Version,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
 
-	return nil
-}
+		if err := json.Unmarshal(metadataJSON, &event.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
 
-// Subscribe registers a handler for specific event types
-func (b *InMemoryEventBus) Subscribe(eventType EventType, handler EventHandler) error {
-	if b.handlers[eventType] == nil {
-		b.handlers[eventType] = make([]EventHandler, 0)
+		events = append(events, event)
 	}
 
-	b.handlers[eventType] = append(b.handlers[eventType], handler)
-
-	log.Info().
-		Str("event_type", string(eventType)).
-		Msg("Handler subscribed")
-
-	return nil
+	return events, nil
 }
 
-// Unsubscribe removes a handler for specific event types
-func (b *InMemoryEventBus) Unsubscribe(eventType EventType, handler EventHandler) error {
-	handlers, exists := b.handlers[eventType]
-	if !exists {
-		return nil
-	}
+// GetLastEventVersion retrieves the last event version for an aggregate
+func (s *PostgresEventStore) GetLastEventVersion(aggregateID uuid.UUID) (int, error) {
+	query := `
+		SELECT COALESCE(MAX(version), 0) 
+		FROM events 
+		WHERE aggregate_id = $1`
 
-	for i, h := range handlers {
-		if h == handler {
-			b.handlers[eventType] = append(handlers[:i], handlers[i+1:]...)
-			break
-		}
+	var version int
+	err := s.db.QueryRow(query, aggregateID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last event version: %w", err)
 	}
 
-	log.Info().
-		Str("event_type", string(eventType)).
-		Msg("Handler unsubscribed")
-
-	return nil
+	return version, nil
 }
 
+// ErrSnapshotNotFound is returned when an aggregate has no snapshot
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
 // PostgresSnapshotStore implements SnapshotStore using PostgreSQL
 type PostgresSnapshotStore struct {
 	db *sql.DB
@@ -340,7 +391,7 @@ func (s *PostgresSnapshotStore) GetSnapshot(aggregateID uuid.UUID) (*Snapshot, e
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("snapshot not found")
+			return nil, ErrSnapshotNotFound
 		}
 		return nil, fmt.Errorf("failed to get snapshot: %w", err)
 	}
@@ -363,7 +414,7 @@ func (s *PostgresSnapshotStore) DeleteSnapshot(aggregateID uuid.UUID) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("snapshot not found")
+		return ErrSnapshotNotFound
 	}
 
 	log.Debug().
@@ -406,54 +457,66 @@ func (s *EventService) PublishAndStore(event *Event) error {
 	return nil
 }
 
-// ReplayEvents replays events for rebuilding projections
+// ReplayEvents replays events in creation order for rebuilding projections.
+// Events are read in pages of replay.BatchSize from replay.FromTime, or from
+// the first event when it is nil, up to replay.ToTime.
 func (s *EventService) ReplayEvents(ctx context.Context, replay EventReplay, handler func(*Event) error) error {
-	var events []*Event
-	var err error
+	batchSize := replay.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReplayBatchSize
+	}
 
-	// Get events based on replay criteria
+	var from time.Time
 	if replay.FromTime != nil {
-		events, err = s.store.GetEventsAfter(*replay.FromTime, replay.BatchSize)
-	} else {
-		// For simplicity, get all events (in a real implementation, you'd want pagination)
-		return fmt.Errorf("replay without time criteria not implemented")
+		from = *replay.FromTime
 	}
+	cursor := newEventCursor(s.store, from, uuid.Nil)
 
-	if err != nil {
-		return fmt.Errorf("failed to get events for replay: %w", err)
-	}
+	processed := 0
+	for {
+		events, more, err := cursor.next(batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get events for replay: %w", err)
+		}
 
-	// Process events in batches
-	for _, event := range events {
-		// Filter by event types if specified
-		if len(replay.EventTypes) > 0 {
-			found := false
-			for _, eventType := range replay.EventTypes {
-				if event.Type == eventType {
-					found = true
-					break
+		for _, event := range events {
+			if replay.ToTime != nil && event.CreatedAt.After(*replay.ToTime) {
+				more = false
+				break
+			}
+			cursor.advance(event)
+
+			// Filter by event types if specified
+			if len(replay.EventTypes) > 0 {
+				found := false
+				for _, eventType := range replay.EventTypes {
+					if event.Type == eventType {
+						found = true
+						break
+					}
+				}
+				if !found {
+					continue
 				}
 			}
-			if !found {
+
+			// Filter by version if specified
+			if replay.FromVersion != nil && event.Version < *replay.FromVersion {
+				continue
+			}
+			if replay.ToVersion != nil && event.Version > *replay.ToVersion {
 				continue
 			}
-		}
-
-		// Filter by version if specified
-		if replay.FromVersion != nil && event.Version < *replay.FromVersion {
-			continue
-		}
-		if replay.ToVersion != nil && event.Version > *replay.ToVersion {
-			continue
-		}
 
-		// Handle the event
-		if err := handler(event); err != nil {
-			log.Error().
-				Err(err).
-				Str("event_id", event.ID.String()).
-				Msg("Failed to handle event during replay")
-			return fmt.Errorf("failed to handle event during replay: %w", err)
+			// Handle the event
+			if err := handler(event); err != nil {
+				log.Error().
+					Err(err).
+					Str("event_id", event.ID.String()).
+					Msg("Failed to handle event during replay")
+				return fmt.Errorf("failed to handle event during replay: %w", err)
+			}
+			processed++
 		}
 
 		// Check if context is cancelled
@@ -462,10 +525,14 @@ func (s *EventService) ReplayEvents(ctx context.Context, replay EventReplay, han
 			return ctx.Err()
 		default:
 		}
+
+		if !more {
+			break
+		}
 	}
 
 	log.Info().
-		Int("events_processed", len(events)).
+		Int("events_processed", processed).
 		Msg("Event replay completed")
 
 	return nil
@@ -0,0 +1,116 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"insider-backend/internal/domain"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// StaticRateProvider serves exchange rates from a fixed table, typically loaded
+// from a JSON file. It is meant for tests and environments without a live feed.
+type StaticRateProvider struct {
+	rates map[string]domain.ExchangeRate
+}
+
+// RatesFile is the on-disk format read by LoadStaticRateProvider
+type RatesFile struct {
+	Source    string            `json:"source"`
+	Timestamp time.Time         `json:"timestamp"`
+	Spread    string            `json:"spread,omitempty"`
+	Rates     map[string]string `json:"rates"` // keyed by "FROM/TO", e.g. "USD/EUR"
+}
+
+// LoadStaticRateProvider reads a rates file from disk
+func LoadStaticRateProvider(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var file RatesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	return NewStaticRateProvider(file)
+}
+
+// NewStaticRateProvider builds a provider from an in-memory rate table
+func NewStaticRateProvider(file RatesFile) (*StaticRateProvider, error) {
+	if file.Timestamp.IsZero() {
+		return nil, fmt.Errorf("rates file timestamp is required")
+	}
+
+	source := file.Source
+	if source == "" {
+		source = "static"
+	}
+
+	p := &StaticRateProvider{rates: make(map[string]domain.ExchangeRate, len(file.Rates))}
+
+	for pair, rate := range file.Rates {
+		fromCode, toCode, ok := strings.Cut(pair, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid currency pair %q, expected FROM/TO", pair)
+		}
+
+		from, err := domain.ParseCurrency(fromCode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid currency pair %q: %w", pair, err)
+		}
+		to, err := domain.ParseCurrency(toCode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid currency pair %q: %w", pair, err)
+		}
+
+		// Normalize to the scale stored on transactions so the audited rate
+		// is exactly the one used for conversion
+		parsed, ok := new(big.Rat).SetString(rate)
+		if !ok {
+			return nil, fmt.Errorf("invalid rate %q for %s", rate, pair)
+		}
+
+		quote := domain.ExchangeRate{
+			From:      from,
+			To:        to,
+			Rate:      parsed.FloatString(domain.ExchangeRateScale),
+			Spread:    file.Spread,
+			Timestamp: file.Timestamp,
+			Source:    source,
+		}
+		if err := quote.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid rate for %s: %w", pair, err)
+		}
+
+		p.rates[pairKey(from, to)] = quote
+	}
+
+	return p, nil
+}
+
+// GetRate returns the quote for from -> to. If only the opposite direction is
+// configured, its inverse is returned.
+func (p *StaticRateProvider) GetRate(ctx context.Context, from, to domain.Currency) (*domain.ExchangeRate, error) {
+	if rate, ok := p.rates[pairKey(from, to)]; ok {
+		return &rate, nil
+	}
+
+	if rate, ok := p.rates[pairKey(to, from)]; ok {
+		inverse, err := rate.Inverse()
+		if err != nil {
+			return nil, err
+		}
+		return &inverse, nil
+	}
+
+	return nil, fmt.Errorf("no exchange rate available for %s/%s", from, to)
+}
+
+func pairKey(from, to domain.Currency) string {
+	return from.String() + "/" + to.String()
+}
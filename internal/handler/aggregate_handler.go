@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"insider-backend/internal/service"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+type AggregateHandler struct {
+	aggregateService *service.AggregateService
+}
+
+func NewAggregateHandler(aggregateService *service.AggregateService) *AggregateHandler {
+	return &AggregateHandler{
+		aggregateService: aggregateService,
+	}
+}
+
+// GetUser handles loading a user from its event stream (admin only)
+func (h *AggregateHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDVar(w, r, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	user, info, err := h.aggregateService.GetUser(userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to load user aggregate")
+		http.Error(w, "User aggregate not found", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"user": user,
+		"load": info,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBalance handles loading a wallet from its event stream (admin only)
+func (h *AggregateHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDVar(w, r, "user_id", "Invalid user ID")
+	if !ok {
+		return
+	}
+	currency := mux.Vars(r)["currency"]
+
+	balance, info, err := h.aggregateService.GetBalance(userID, currency)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Str("currency", currency).Msg("Failed to load balance aggregate")
+		http.Error(w, "Balance aggregate not found", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"balance": balance,
+		"load":    info,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
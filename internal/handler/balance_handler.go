@@ -2,10 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
 	"insider-backend/internal/middleware"
+	"insider-backend/internal/repository"
 	"insider-backend/internal/service"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -30,15 +35,21 @@ func (h *BalanceHandler) GetCurrentBalance(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	balance, err := h.balanceService.GetBalance(r.Context(), userID)
+	balances, err := h.balanceService.GetBalances(r.Context(), userID)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get current balance")
 		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
 		return
 	}
 
+	response := map[string]interface{}{
+		"user_id":  userID,
+		"balances": balances,
+		"count":    len(balances),
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(balance)
+	json.NewEncoder(w).Encode(response)
 }
 
 // GetUserBalance handles getting balance for a specific user (admin only)
@@ -61,15 +72,21 @@ func (h *BalanceHandler) GetUserBalance(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	balance, err := h.balanceService.GetBalance(r.Context(), userID)
+	balances, err := h.balanceService.GetBalances(r.Context(), userID)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userIDStr).Msg("Failed to get user balance")
 		http.Error(w, "Failed to get balance", http.StatusInternalServerError)
 		return
 	}
 
+	response := map[string]interface{}{
+		"user_id":  userID,
+		"balances": balances,
+		"count":    len(balances),
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(balance)
+	json.NewEncoder(w).Encode(response)
 }
 
 // GetBalanceHistory handles getting balance history
@@ -144,6 +161,12 @@ func (h *BalanceHandler) GetBalanceAtTime(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	currency, err := domain.ParseCurrency(r.URL.Query().Get("currency"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Check if requesting balance for a different user
 	if userIDParam := r.URL.Query().Get("user_id"); userIDParam != "" {
 		if requestedUserID, err := uuid.Parse(userIDParam); err == nil {
@@ -156,7 +179,19 @@ func (h *BalanceHandler) GetBalanceAtTime(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	balance, err := h.balanceService.GetBalanceAtTime(r.Context(), userID, timestamp)
+	if mode := r.URL.Query().Get("mode"); mode == "events" {
+		h.reconstructBalanceAtTime(w, r, userID, currency, timestamp)
+		return
+	} else if mode != "" && mode != "history" {
+		http.Error(w, "mode must be history or events", http.StatusBadRequest)
+		return
+	}
+
+	balance, err := h.balanceService.GetBalanceAtTime(r.Context(), userID, currency, timestamp)
+	if errors.Is(err, repository.ErrBalanceHistoryNotFound) {
+		http.Error(w, "No balance history at this time", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.String()).Str("timestamp", timestamp).Msg("Failed to get balance at time")
 		http.Error(w, "Failed to get balance at time", http.StatusInternalServerError)
@@ -165,6 +200,7 @@ func (h *BalanceHandler) GetBalanceAtTime(w http.ResponseWriter, r *http.Request
 
 	response := map[string]interface{}{
 		"user_id":   userID,
+		"currency":  currency,
 		"timestamp": timestamp,
 		"balance":   balance,
 	}
@@ -173,6 +209,41 @@ func (h *BalanceHandler) GetBalanceAtTime(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 }
 
+// reconstructBalanceAtTime answers GetBalanceAtTime by replaying the wallet's
+// balance events, listing the events applied and their transactions
+func (h *BalanceHandler) reconstructBalanceAtTime(w http.ResponseWriter, r *http.Request, userID uuid.UUID, currency domain.Currency, timestamp string) {
+	at, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		http.Error(w, "timestamp must be an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+
+	useSnapshot := r.URL.Query().Get("snapshot") != "false"
+
+	reconstruction, err := h.balanceService.ReconstructBalanceAtTime(r.Context(), userID, currency, at, useSnapshot)
+	if errors.Is(err, event.ErrBalanceNotRecorded) {
+		http.Error(w, "No balance event records the wallet at this time", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Str("timestamp", timestamp).Msg("Failed to reconstruct balance at time")
+		http.Error(w, "Failed to reconstruct balance at time", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"user_id":        userID,
+		"currency":       currency,
+		"timestamp":      timestamp,
+		"balance":        reconstruction.Balance.Amount,
+		"mode":           "events",
+		"reconstruction": reconstruction,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // GetBalanceSnapshot handles getting a balance snapshot
 func (h *BalanceHandler) GetBalanceSnapshot(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
@@ -193,7 +264,13 @@ func (h *BalanceHandler) GetBalanceSnapshot(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	snapshot, err := h.balanceService.GetBalanceSnapshot(r.Context(), userID)
+	currency, err := domain.ParseCurrency(r.URL.Query().Get("currency"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := h.balanceService.GetBalanceSnapshot(r.Context(), userID, currency)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get balance snapshot")
 		http.Error(w, "Failed to get balance snapshot", http.StatusInternalServerError)
@@ -224,13 +301,19 @@ func (h *BalanceHandler) RefreshBalance(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	balance, err := h.balanceService.RefreshBalance(r.Context(), userID)
+	balances, err := h.balanceService.RefreshBalance(r.Context(), userID)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to refresh balance")
 		http.Error(w, "Failed to refresh balance", http.StatusInternalServerError)
 		return
 	}
 
+	response := map[string]interface{}{
+		"user_id":  userID,
+		"balances": balances,
+		"count":    len(balances),
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(balance)
+	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"insider-backend/internal/circuit"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type CircuitBreakerHandler struct {
+	registry *circuit.Registry
+}
+
+func NewCircuitBreakerHandler(registry *circuit.Registry) *CircuitBreakerHandler {
+	return &CircuitBreakerHandler{
+		registry: registry,
+	}
+}
+
+// ListCircuitBreakers handles listing the state and counts of every circuit
+// breaker (admin only)
+func (h *CircuitBreakerHandler) ListCircuitBreakers(w http.ResponseWriter, r *http.Request) {
+	statuses := h.registry.Statuses()
+
+	response := map[string]interface{}{
+		"circuit_breakers": statuses,
+		"count":            len(statuses),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCircuitBreaker handles getting the state and counts of a circuit breaker
+// (admin only)
+func (h *CircuitBreakerHandler) GetCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+	cb, ok := h.registry.Lookup(mux.Vars(r)["name"])
+	if !ok {
+		http.Error(w, "Circuit breaker not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cb.Status())
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"insider-backend/internal/event"
+	"insider-backend/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Event stream settings
+const (
+	eventStreamPollInterval = time.Second
+	eventStreamHeartbeat    = 15 * time.Second
+)
+
+type EventHandler struct {
+	eventQueryService *service.EventQueryService
+}
+
+func NewEventHandler(eventQueryService *service.EventQueryService) *EventHandler {
+	return &EventHandler{
+		eventQueryService: eventQueryService,
+	}
+}
+
+// QueryEvents handles listing the events matching the query parameters
+// (admin only). Pages are continued with the next_cursor of the previous page.
+func (h *EventHandler) QueryEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Limit, _ = parsePagination(r)
+
+	query := event.EventQuery{
+		Filter: filter,
+		SortBy: r.URL.Query().Get("sort_by"),
+		Order:  r.URL.Query().Get("order"),
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+	if err := query.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.eventQueryService.QueryEvents(r.Context(), query)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query events")
+		if errors.Is(err, event.ErrInvalidCursor) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to query events", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"events":      page.Events,
+		"next_cursor": page.NextCursor,
+		"has_more":    page.HasMore,
+		"count":       len(page.Events),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// StreamEvents handles tailing new events as Server-Sent Events (admin only).
+// Each event's SSE id is its cursor, so a reconnecting client resumes after
+// the last event it received through Last-Event-ID.
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("cursor")
+	}
+	if cursor == "" {
+		cursor = h.eventQueryService.TailCursor()
+	}
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn().Err(err).Msg("Failed to clear write deadline of event stream")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	poll := time.NewTicker(eventStreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-poll.C:
+			for more := true; more && ctx.Err() == nil; {
+				page, err := h.eventQueryService.PollEvents(ctx, filter, cursor)
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to poll events for stream")
+					if errors.Is(err, event.ErrInvalidCursor) {
+						fmt.Fprint(w, "event: error\ndata: invalid cursor\n\n")
+						flusher.Flush()
+						return
+					}
+					break
+				}
+
+				for _, evt := range page.Events {
+					data, err := json.Marshal(evt)
+					if err != nil {
+						log.Error().Err(err).Str("event_id", evt.ID.String()).Msg("Failed to marshal streamed event")
+						continue
+					}
+					eventCursor := event.EncodeEventCursor(evt.CreatedAt, evt.ID)
+					if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", eventCursor, evt.Type, data); err != nil {
+						return
+					}
+				}
+				flusher.Flush()
+
+				if page.NextCursor != "" {
+					cursor = page.NextCursor
+				}
+				more = page.HasMore
+			}
+		}
+	}
+}
+
+// parseEventFilter reads the aggregate_id, type (repeated or comma-separated),
+// from_time and to_time (RFC 3339) query parameters
+func parseEventFilter(r *http.Request) (event.EventFilter, error) {
+	var filter event.EventFilter
+	query := r.URL.Query()
+
+	if aggregateIDStr := query.Get("aggregate_id"); aggregateIDStr != "" {
+		aggregateID, err := uuid.Parse(aggregateIDStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid aggregate ID")
+		}
+		filter.AggregateID = &aggregateID
+	}
+
+	for _, value := range query["type"] {
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if eventType == "" {
+				continue
+			}
+			if !event.IsKnownEventType(eventType) {
+				return filter, fmt.Errorf("unknown event type: %s", eventType)
+			}
+			filter.EventTypes = append(filter.EventTypes, event.EventType(eventType))
+		}
+	}
+
+	if fromStr := query.Get("from_time"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from_time, use RFC 3339")
+		}
+		filter.FromTime = &from
+	}
+
+	if toStr := query.Get("to_time"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to_time, use RFC 3339")
+		}
+		filter.ToTime = &to
+	}
+
+	return filter, nil
+}
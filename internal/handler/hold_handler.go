@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"encoding/json"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/middleware"
+	"insider-backend/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+type HoldHandler struct {
+	holdService *service.HoldService
+}
+
+func NewHoldHandler(holdService *service.HoldService) *HoldHandler {
+	return &HoldHandler{
+		holdService: holdService,
+	}
+}
+
+// CreateHold handles placing a hold on a user's wallet
+func (h *HoldHandler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	if !canManageHolds(r, req.UserID) {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	ipAddress := getClientIP(r)
+	userAgent := r.UserAgent()
+
+	hold, err := h.holdService.PlaceHold(r.Context(), req, &userID, ipAddress, userAgent)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to place hold")
+		if idempotencyConflict(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// GetHold handles getting a hold by ID
+func (h *HoldHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	hold, ok := h.loadHold(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// CaptureHold handles turning a hold into a debit or transfer
+func (h *HoldHandler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	hold, ok := h.loadHold(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.CaptureHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	ipAddress := getClientIP(r)
+	userAgent := r.UserAgent()
+
+	transaction, err := h.holdService.CaptureHold(r.Context(), hold.ID, req, &userID, ipAddress, userAgent)
+	if err != nil {
+		log.Error().Err(err).Str("hold_id", hold.ID.String()).Msg("Failed to capture hold")
+		if idempotencyConflict(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// VoidHold handles releasing a hold
+func (h *HoldHandler) VoidHold(w http.ResponseWriter, r *http.Request) {
+	hold, ok := h.loadHold(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	ipAddress := getClientIP(r)
+	userAgent := r.UserAgent()
+
+	voided, err := h.holdService.VoidHold(r.Context(), hold.ID, &userID, ipAddress, userAgent)
+	if err != nil {
+		log.Error().Err(err).Str("hold_id", hold.ID.String()).Msg("Failed to void hold")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voided)
+}
+
+// GetUserHolds handles listing the holds of a user
+func (h *HoldHandler) GetUserHolds(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userIDStr := vars["user_id"]
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if !canManageHolds(r, userID) {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	limit := 20 // default
+	offset := 0 // default
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	holds, err := h.holdService.GetUserHolds(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userIDStr).Msg("Failed to get user holds")
+		http.Error(w, "Failed to get user holds", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"holds":   holds,
+		"user_id": userID,
+		"limit":   limit,
+		"offset":  offset,
+		"count":   len(holds),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadHold reads the hold named in the URL and checks the caller may manage it
+func (h *HoldHandler) loadHold(w http.ResponseWriter, r *http.Request) (*domain.Hold, bool) {
+	vars := mux.Vars(r)
+	holdIDStr := vars["id"]
+
+	holdID, err := uuid.Parse(holdIDStr)
+	if err != nil {
+		http.Error(w, "Invalid hold ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	hold, err := h.holdService.GetHold(r.Context(), holdID)
+	if err != nil {
+		log.Error().Err(err).Str("hold_id", holdIDStr).Msg("Failed to get hold")
+		http.Error(w, "Hold not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	if !canManageHolds(r, hold.UserID) {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return nil, false
+	}
+
+	return hold, true
+}
+
+// canManageHolds checks if the caller may manage holds on the user's wallets
+func canManageHolds(r *http.Request, userID uuid.UUID) bool {
+	currentUserID, _ := middleware.GetUserIDFromContext(r.Context())
+	currentUserRole, _ := middleware.GetUserRoleFromContext(r.Context())
+
+	return currentUserRole == "admin" || currentUserID == userID
+}
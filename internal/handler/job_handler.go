@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"insider-backend/internal/service"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+type JobHandler struct {
+	jobService *service.JobService
+}
+
+func NewJobHandler(jobService *service.JobService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// ListDeadLetters handles listing dead-lettered jobs awaiting a requeue or a
+// discard, optionally of one type (admin only)
+func (h *JobHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+	jobType := r.URL.Query().Get("type")
+
+	deadLetters, err := h.jobService.ListDeadLetters(r.Context(), jobType, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list job dead letters")
+		http.Error(w, "Failed to list dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"dead_letters": deadLetters,
+		"limit":        limit,
+		"offset":       offset,
+		"count":        len(deadLetters),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetJob handles getting a job with the error history of its attempts (admin
+// only)
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid job ID")
+	if !ok {
+		return
+	}
+
+	job, attempts, err := h.jobService.GetJob(r.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("job_id", id.String()).Msg("Failed to get job")
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"job":      job,
+		"attempts": attempts,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RequeueJob handles queueing a dead job again (admin only)
+func (h *JobHandler) RequeueJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid job ID")
+	if !ok {
+		return
+	}
+
+	job, err := h.jobService.RequeueJob(r.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("job_id", id.String()).Msg("Failed to requeue job")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// DiscardJob handles giving up on a dead job (admin only)
+func (h *JobHandler) DiscardJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid job ID")
+	if !ok {
+		return
+	}
+
+	job, err := h.jobService.DiscardJob(r.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("job_id", id.String()).Msg("Failed to discard job")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/service"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+type LedgerHandler struct {
+	ledgerService *service.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+	}
+}
+
+// GetTransactionEntries handles getting the journal entries of a transaction (admin only)
+func (h *LedgerHandler) GetTransactionEntries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionIDStr := vars["id"]
+
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.ledgerService.GetTransactionEntries(r.Context(), transactionID)
+	if err != nil {
+		log.Error().Err(err).Str("transaction_id", transactionIDStr).Msg("Failed to get journal entries")
+		http.Error(w, "Failed to get journal entries", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"transaction_id": transactionID,
+		"entries":        entries,
+		"count":          len(entries),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetUserBalances handles getting a user's balances computed from the journal (admin only)
+func (h *LedgerHandler) GetUserBalances(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userIDStr := vars["user_id"]
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	balances, err := h.ledgerService.GetUserBalances(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userIDStr).Msg("Failed to get ledger balances")
+		http.Error(w, "Failed to get ledger balances", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"user_id":  userID,
+		"balances": balances,
+		"count":    len(balances),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RebuildBalances handles rebuilding the balances projection from the journal (admin only).
+// An optional user_id query parameter limits the rebuild to one user.
+func (h *LedgerHandler) RebuildBalances(w http.ResponseWriter, r *http.Request) {
+	var userID *uuid.UUID
+	if userIDStr := r.URL.Query().Get("user_id"); userIDStr != "" {
+		parsed, err := uuid.Parse(userIDStr)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		userID = &parsed
+	}
+
+	changed, err := h.ledgerService.RebuildBalances(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrHeldExceedsBalance) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Error().Err(err).Msg("Failed to rebuild balances")
+		http.Error(w, "Failed to rebuild balances", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"user_id": userID,
+		"changed": changed,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
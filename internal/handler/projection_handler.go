@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"insider-backend/internal/event"
+	"insider-backend/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+type ProjectionHandler struct {
+	projectionService *service.ProjectionService
+}
+
+func NewProjectionHandler(projectionService *service.ProjectionService) *ProjectionHandler {
+	return &ProjectionHandler{
+		projectionService: projectionService,
+	}
+}
+
+// GetProjections handles listing the projections and their checkpoints (admin only)
+func (h *ProjectionHandler) GetProjections(w http.ResponseWriter, r *http.Request) {
+	checkpoints, err := h.projectionService.GetCheckpoints()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get projection checkpoints")
+		http.Error(w, "Failed to get projections", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"projections": checkpoints,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RebuildProjection handles clearing a projection and replaying every event
+// into it (admin only)
+func (h *ProjectionHandler) RebuildProjection(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	handled, err := h.projectionService.Rebuild(r.Context(), name)
+	if err != nil {
+		log.Error().Err(err).Str("projection", name).Msg("Failed to rebuild projection")
+		if errors.Is(err, event.ErrProjectionNotFound) {
+			http.Error(w, "Projection not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to rebuild projection", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"projection":       name,
+		"events_processed": handled,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetUserDailyTotals handles getting a user's daily totals (admin only).
+// from and to default to the last 30 days.
+func (h *ProjectionHandler) GetUserDailyTotals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDVar(w, r, "user_id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -29).Format("2006-01-02")
+	to := now.Format("2006-01-02")
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		from = fromStr
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to = toStr
+	}
+
+	totals, err := h.projectionService.GetUserDailyTotals(r.Context(), userID, from, to)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get daily totals")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"daily_totals": totals,
+		"user_id":      userID,
+		"from":         from,
+		"to":           to,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetUserBalances handles getting a user's balances from the balance read
+// model (admin only)
+func (h *ProjectionHandler) GetUserBalances(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDVar(w, r, "user_id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	balances, err := h.projectionService.GetUserBalances(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get balance views")
+		http.Error(w, "Failed to get balances", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"balances": balances,
+		"user_id":  userID,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
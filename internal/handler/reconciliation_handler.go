@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"insider-backend/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type ReconciliationHandler struct {
+	reconciliationService *service.ReconciliationService
+}
+
+func NewReconciliationHandler(reconciliationService *service.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciliationService: reconciliationService,
+	}
+}
+
+// Reconcile handles running a balance reconciliation (admin only).
+// Optional query parameters: user_id limits the run to one user and
+// repair=true resets drifted balances.
+func (h *ReconciliationHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	var userID *uuid.UUID
+	if userIDStr := r.URL.Query().Get("user_id"); userIDStr != "" {
+		parsed, err := uuid.Parse(userIDStr)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		userID = &parsed
+	}
+
+	repair := false
+	if repairStr := r.URL.Query().Get("repair"); repairStr != "" {
+		parsed, err := strconv.ParseBool(repairStr)
+		if err != nil {
+			http.Error(w, "Invalid repair flag", http.StatusBadRequest)
+			return
+		}
+		repair = parsed
+	}
+
+	report, err := h.reconciliationService.Reconcile(r.Context(), userID, repair)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reconcile balances")
+		http.Error(w, "Failed to reconcile balances", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/middleware"
+	"insider-backend/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+type ScheduleHandler struct {
+	scheduleService *service.ScheduleService
+}
+
+func NewScheduleHandler(scheduleService *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// CreateSchedule handles scheduling a one-off or recurring transaction
+func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	ipAddress := getClientIP(r)
+	userAgent := r.UserAgent()
+
+	schedule, err := h.scheduleService.CreateSchedule(r.Context(), req, userID, ipAddress, userAgent)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create schedule")
+		if idempotencyConflict(w, err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// GetSchedule handles getting a schedule by ID
+func (h *ScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, ok := h.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// CancelSchedule handles stopping a schedule
+func (h *ScheduleHandler) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, ok := h.loadSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	ipAddress := getClientIP(r)
+	userAgent := r.UserAgent()
+
+	cancelled, err := h.scheduleService.CancelSchedule(r.Context(), schedule.ID, &userID, ipAddress, userAgent)
+	if err != nil {
+		log.Error().Err(err).Str("schedule_id", schedule.ID.String()).Msg("Failed to cancel schedule")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cancelled)
+}
+
+// GetUserSchedules handles listing the schedules created by a user
+func (h *ScheduleHandler) GetUserSchedules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userIDStr := vars["user_id"]
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check permissions
+	currentUserID, _ := middleware.GetUserIDFromContext(r.Context())
+	currentUserRole, _ := middleware.GetUserRoleFromContext(r.Context())
+
+	if currentUserRole != "admin" && currentUserID != userID {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	limit := 20 // default
+	offset := 0 // default
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	schedules, err := h.scheduleService.GetUserSchedules(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userIDStr).Msg("Failed to get user schedules")
+		http.Error(w, "Failed to get user schedules", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"schedules": schedules,
+		"user_id":   userID,
+		"limit":     limit,
+		"offset":    offset,
+		"count":     len(schedules),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadSchedule reads the schedule named in the URL. Only its creator and
+// admins may see or change it.
+func (h *ScheduleHandler) loadSchedule(w http.ResponseWriter, r *http.Request) (*domain.Schedule, bool) {
+	vars := mux.Vars(r)
+	scheduleIDStr := vars["id"]
+
+	scheduleID, err := uuid.Parse(scheduleIDStr)
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	schedule, err := h.scheduleService.GetSchedule(r.Context(), scheduleID)
+	if err != nil {
+		log.Error().Err(err).Str("schedule_id", scheduleIDStr).Msg("Failed to get schedule")
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	currentUserID, _ := middleware.GetUserIDFromContext(r.Context())
+	currentUserRole, _ := middleware.GetUserRoleFromContext(r.Context())
+
+	if currentUserRole != "admin" && currentUserID != schedule.UserID {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return nil, false
+	}
+
+	return schedule, true
+}
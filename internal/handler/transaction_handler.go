@@ -2,8 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"insider-backend/internal/domain"
 	"insider-backend/internal/middleware"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/repository/breaker"
 	"insider-backend/internal/service"
 	"net/http"
 	"strconv"
@@ -28,7 +31,7 @@ func NewTransactionHandler(transactionService *service.TransactionService) *Tran
 func (h *TransactionHandler) CreateCredit(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreateTransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
 		return
 	}
 
@@ -46,6 +49,9 @@ func (h *TransactionHandler) CreateCredit(w http.ResponseWriter, r *http.Request
 	transaction, err := h.transactionService.CreateCredit(r.Context(), req, &userID, ipAddress, userAgent)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create credit transaction")
+		if idempotencyConflict(w, err) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -59,7 +65,7 @@ func (h *TransactionHandler) CreateCredit(w http.ResponseWriter, r *http.Request
 func (h *TransactionHandler) CreateDebit(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreateTransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
 		return
 	}
 
@@ -77,6 +83,9 @@ func (h *TransactionHandler) CreateDebit(w http.ResponseWriter, r *http.Request)
 	transaction, err := h.transactionService.CreateDebit(r.Context(), req, &userID, ipAddress, userAgent)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create debit transaction")
+		if idempotencyConflict(w, err) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -90,7 +99,7 @@ func (h *TransactionHandler) CreateDebit(w http.ResponseWriter, r *http.Request)
 func (h *TransactionHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreateTransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
 		return
 	}
 
@@ -108,6 +117,9 @@ func (h *TransactionHandler) CreateTransfer(w http.ResponseWriter, r *http.Reque
 	transaction, err := h.transactionService.CreateTransfer(r.Context(), req, &userID, ipAddress, userAgent)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create transfer transaction")
+		if idempotencyConflict(w, err) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -328,3 +340,107 @@ func (h *TransactionHandler) CancelTransaction(w http.ResponseWriter, r *http.Re
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ReverseTransaction handles full or partial reversal of a settled transaction
+func (h *TransactionHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionIDStr := vars["id"]
+
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req domain.ReverseTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	original, err := h.transactionService.GetTransaction(r.Context(), transactionID)
+	if err != nil {
+		log.Error().Err(err).Str("transaction_id", transactionIDStr).Msg("Failed to get transaction")
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	if !canReverseTransaction(r, original) {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	ipAddress := getClientIP(r)
+	userAgent := r.UserAgent()
+
+	reversal, err := h.transactionService.ReverseTransaction(r.Context(), transactionID, req, &userID, ipAddress, userAgent)
+	if err != nil {
+		log.Error().Err(err).Str("transaction_id", transactionIDStr).Msg("Failed to reverse transaction")
+		writeReversalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reversal)
+}
+
+// writeReversalError answers a failed reversal. Only the client's mistakes
+// are explained; other failures are not exposed.
+func writeReversalError(w http.ResponseWriter, err error) {
+	switch {
+	case idempotencyConflict(w, err):
+	case errors.Is(err, repository.ErrTransactionNotFound):
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrReversalReasonRequired), errors.Is(err, domain.ErrInvalidAmount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrNotReversible):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrReversalExceedsAmount):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, repository.ErrVersionConflict):
+		http.Error(w, "Transaction was modified concurrently, please retry", http.StatusConflict)
+	case breaker.IsUnavailable(err):
+		http.Error(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
+	default:
+		http.Error(w, "Failed to reverse transaction", http.StatusInternalServerError)
+	}
+}
+
+// canReverseTransaction checks if the caller may reverse the transaction: an
+// admin, or the user it paid into, who gives the funds back
+func canReverseTransaction(r *http.Request, transaction *domain.Transaction) bool {
+	currentUserID, _ := middleware.GetUserIDFromContext(r.Context())
+	currentUserRole, _ := middleware.GetUserRoleFromContext(r.Context())
+
+	if currentUserRole == "admin" {
+		return true
+	}
+	return transaction.ToUserID != nil && *transaction.ToUserID == currentUserID
+}
+
+// idempotencyConflict answers 409 when err says another request with the same
+// idempotency key claimed it first
+func idempotencyConflict(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrIdempotencyKeyInProgress) {
+		return false
+	}
+	http.Error(w, err.Error(), http.StatusConflict)
+	return true
+}
+
+// invalidBodyMessage keeps amount validation errors visible to the client
+func invalidBodyMessage(err error) string {
+	if errors.Is(err, domain.ErrInvalidAmount) ||
+		errors.Is(err, domain.ErrSubCentAmount) ||
+		errors.Is(err, domain.ErrAmountOverflow) {
+		return err.Error()
+	}
+	return "Invalid request body"
+}
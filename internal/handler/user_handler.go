@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
 	"insider-backend/internal/middleware"
 	"insider-backend/internal/service"
 	"net"
@@ -127,6 +129,10 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	user, err := h.userService.UpdateUser(r.Context(), userID, req, ipAddress, userAgent)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userIDStr).Msg("Failed to update user")
+		if errors.Is(err, event.ErrConcurrencyConflict) {
+			http.Error(w, "User was modified concurrently, please retry", http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"encoding/json"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook handles creating a webhook subscription (admin only). The
+// signing secret is only returned in this response.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.webhookService.CreateSubscription(r.Context(), req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create webhook subscription")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"webhook": subscription,
+		"secret":  subscription.Secret,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetWebhook handles getting a webhook subscription by ID (admin only)
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(r.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("subscription_id", id.String()).Msg("Failed to get webhook subscription")
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// UpdateWebhook handles updating a webhook subscription (admin only)
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, invalidBodyMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.webhookService.UpdateSubscription(r.Context(), id, req)
+	if err != nil {
+		log.Error().Err(err).Str("subscription_id", id.String()).Msg("Failed to update webhook subscription")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// DeleteWebhook handles deleting a webhook subscription (admin only)
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), id); err != nil {
+		log.Error().Err(err).Str("subscription_id", id.String()).Msg("Failed to delete webhook subscription")
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhooks handles listing webhook subscriptions (admin only)
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+
+	subscriptions, err := h.webhookService.ListSubscriptions(r.Context(), limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list webhook subscriptions")
+		http.Error(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"webhooks": subscriptions,
+		"limit":    limit,
+		"offset":   offset,
+		"count":    len(subscriptions),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetWebhookDeliveries handles listing the deliveries of a subscription
+// (admin only). The optional status query parameter filters them.
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch domain.WebhookDeliveryStatus(status) {
+	case "", domain.WebhookDeliveryStatusPending, domain.WebhookDeliveryStatusDelivered, domain.WebhookDeliveryStatusDead:
+	default:
+		http.Error(w, "Invalid delivery status", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	deliveries, err := h.webhookService.GetSubscriptionDeliveries(r.Context(), id, status, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Str("subscription_id", id.String()).Msg("Failed to get webhook deliveries")
+		http.Error(w, "Failed to get webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"deliveries":      deliveries,
+		"subscription_id": id,
+		"limit":           limit,
+		"offset":          offset,
+		"count":           len(deliveries),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetDelivery handles getting a delivery with its attempts (admin only)
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, attempts, err := h.webhookService.GetDelivery(r.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("delivery_id", id.String()).Msg("Failed to get webhook delivery")
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"delivery": delivery,
+		"attempts": attempts,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReplayDelivery handles queueing a delivered or dead delivery to be sent
+// again (admin only)
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDVar(w, r, "id", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.ReplayDelivery(r.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("delivery_id", id.String()).Msg("Failed to replay webhook delivery")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// ListDeadLetters handles listing dead letters awaiting replay (admin only)
+func (h *WebhookHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+
+	deadLetters, err := h.webhookService.ListDeadLetters(r.Context(), limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list webhook dead letters")
+		http.Error(w, "Failed to list dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"dead_letters": deadLetters,
+		"limit":        limit,
+		"offset":       offset,
+		"count":        len(deadLetters),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseIDVar parses the UUID in the named URL variable, answering 400 with
+// message when it is invalid
+func parseIDVar(w http.ResponseWriter, r *http.Request, name, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)[name])
+	if err != nil {
+		http.Error(w, message, http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// parsePagination reads the limit (default 20, at most 100) and offset query
+// parameters
+func parsePagination(r *http.Request) (int, int) {
+	limit := 20 // default
+	offset := 0 // default
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	return limit, offset
+}
@@ -150,6 +150,15 @@ var (
 		},
 		[]string{"cache_type"},
 	)
+
+	// Circuit breaker metrics
+	circuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"name"},
+	)
 )
 
 // Init initializes the metrics
@@ -173,6 +182,7 @@ func Init() {
 		databaseQueryDuration,
 		cacheOperationsTotal,
 		cacheHitRatio,
+		circuitBreakerState,
 	)
 }
 
@@ -255,3 +265,8 @@ func RecordCacheOperation(operation, result string) {
 func SetCacheHitRatio(cacheType string, ratio float64) {
 	cacheHitRatio.WithLabelValues(cacheType).Set(ratio)
 }
+
+// Circuit Breaker Metrics
+func SetCircuitBreakerState(name string, state float64) {
+	circuitBreakerState.WithLabelValues(name).Set(state)
+}
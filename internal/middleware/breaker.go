@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"insider-backend/internal/circuit"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CircuitBreaker middleware fails requests fast with 503 while cb is open,
+// instead of letting them wait on a dependency that is down. Retry-After
+// tells clients when the breaker lets requests through again.
+func CircuitBreaker(cb *circuit.CircuitBreaker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			openUntil := cb.OpenUntil()
+			if openUntil.IsZero() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			retryAfter := int(math.Ceil(time.Until(openUntil).Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/service"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	referenceIDIdempotencyKey = "reference_id:"
+)
+
+// Idempotency makes POST endpoints safe to retry. The key is taken from the
+// Idempotency-Key header, falling back to the reference_id in the request body.
+// A retry with the same body replays the stored response, a retry with a
+// different body is rejected with 422 and a retry while the original request is
+// still running is rejected with 409. The key is claimed in the database
+// transaction of the change the request makes, so it cannot make the change
+// twice even while Redis is down. Only successful responses are stored, so a
+// failed request may be retried with the same key. Must run after AuthMiddleware.
+func Idempotency(idempotencyService *service.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "User not authenticated", http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				key = referenceIDKey(body)
+			}
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > domain.MaxIdempotencyKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			requestHash := domain.HashRequest(r.Method, r.URL.Path, compactJSON(body))
+
+			record, err := idempotencyService.Acquire(r.Context(), userID, key, requestHash)
+			if err != nil {
+				switch {
+				case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+					http.Error(w, err.Error(), http.StatusConflict)
+				case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+					http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				default:
+					log.Error().Err(err).Str("idempotency_key", key).Msg("Failed to check idempotency key")
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			if record != nil {
+				log.Info().
+					Str("idempotency_key", key).
+					Str("user_id", userID.String()).
+					Msg("Replaying idempotent response")
+
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(record.StatusCode)
+				w.Write(record.ResponseBody)
+				return
+			}
+
+			claim := domain.NewIdempotencyClaim(userID, key, r.Method, r.URL.Path, requestHash)
+			recorder := &recordingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(service.WithIdempotencyClaim(r.Context(), claim)))
+
+			// The response is already on its way to the client, so keep
+			// recording it even if the client has gone away
+			ctx := context.WithoutCancel(r.Context())
+
+			if recorder.statusCode < 200 || recorder.statusCode >= 300 {
+				idempotencyService.Release(ctx, userID, key)
+				return
+			}
+
+			completed := domain.NewIdempotencyRecord(
+				userID,
+				key,
+				r.Method,
+				r.URL.Path,
+				requestHash,
+				recorder.statusCode,
+				recorder.Header().Get("Content-Type"),
+				recorder.body.Bytes(),
+			)
+			if err := idempotencyService.Complete(ctx, completed); err != nil {
+				log.Error().Err(err).Str("idempotency_key", key).Msg("Failed to store idempotent response")
+			}
+		})
+	}
+}
+
+// referenceIDKey derives an idempotency key from the request's reference_id
+func referenceIDKey(body []byte) string {
+	var req struct {
+		ReferenceID string `json:"reference_id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.ReferenceID == "" {
+		return ""
+	}
+	return referenceIDIdempotencyKey + req.ReferenceID
+}
+
+// compactJSON strips insignificant whitespace so formatting changes do not
+// count as a different request
+func compactJSON(body []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, body); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+// recordingResponseWriter passes the response through while keeping a copy of it
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
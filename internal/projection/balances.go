@@ -0,0 +1,122 @@
+package projection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BalancesProjectionName names the current balance projection and its checkpoint
+const BalancesProjectionName = "balances"
+
+// BalanceView is the current balance of a wallet as seen by the event stream
+type BalanceView struct {
+	UserID            uuid.UUID       `json:"user_id"`
+	Currency          domain.Currency `json:"currency"`
+	Amount            domain.Money    `json:"amount"`
+	Version           int             `json:"version"`
+	LastTransactionID *uuid.UUID      `json:"last_transaction_id,omitempty"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+// BalancesProjection keeps the latest balance of every wallet from its
+// balance events
+type BalancesProjection struct {
+	db *sql.DB
+}
+
+// NewBalancesProjection creates the current balance projection
+func NewBalancesProjection(db *sql.DB) *BalancesProjection {
+	return &BalancesProjection{db: db}
+}
+
+// GetName returns the name of the projection
+func (p *BalancesProjection) GetName() string {
+	return BalancesProjectionName
+}
+
+// Handle records the new balance of a balance event. An event only replaces
+// the balance when its version in the wallet stream is newer, so events
+// handled again or out of order change nothing.
+func (p *BalancesProjection) Handle(evt *event.Event) error {
+	if evt.Type != event.BalanceCreditedEvent && evt.Type != event.BalanceDebitedEvent {
+		return nil
+	}
+
+	var data event.BalanceChangedEventData
+	if err := evt.GetData(&data); err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", evt.Type, err)
+	}
+
+	query := `
+		INSERT INTO projection_balances (user_id, currency, amount, version, last_transaction_id, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, currency)
+		DO UPDATE SET
+			amount = EXCLUDED.amount,
+			version = EXCLUDED.version,
+			last_transaction_id = EXCLUDED.last_transaction_id,
+			updated_at = EXCLUDED.updated_at
+		WHERE projection_balances.version < EXCLUDED.version`
+
+	_, err := p.db.Exec(query,
+		data.UserID,
+		data.Currency,
+		data.NewBalance,
+		evt.Version,
+		data.TransactionID,
+		evt.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update balance view: %w", err)
+	}
+
+	return nil
+}
+
+// Rebuild clears the balances so they can be replayed from the first event
+func (p *BalancesProjection) Rebuild() error {
+	if _, err := p.db.Exec(`TRUNCATE projection_balances`); err != nil {
+		return fmt.Errorf("failed to clear balance views: %w", err)
+	}
+	return nil
+}
+
+// GetUserBalances returns the current balances of a user's wallets
+func (p *BalancesProjection) GetUserBalances(ctx context.Context, userID uuid.UUID) ([]*BalanceView, error) {
+	query := `
+		SELECT user_id, currency, amount, version, last_transaction_id, updated_at
+		FROM projection_balances
+		WHERE user_id = $1
+		ORDER BY currency ASC`
+
+	rows, err := p.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance views: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*BalanceView
+	for rows.Next() {
+		balance := &BalanceView{}
+		err := rows.Scan(
+			&balance.UserID,
+			&balance.Currency,
+			&balance.Amount,
+			&balance.Version,
+			&balance.LastTransactionID,
+			&balance.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan balance view: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
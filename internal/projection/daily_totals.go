@@ -0,0 +1,167 @@
+package projection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DailyTotalsProjectionName names the daily totals projection and its checkpoint
+const DailyTotalsProjectionName = "daily_totals"
+
+// DailyTotal is the money a user's wallet received and sent on one UTC day
+type DailyTotal struct {
+	UserID      uuid.UUID       `json:"user_id"`
+	Currency    domain.Currency `json:"currency"`
+	Day         string          `json:"day"`
+	Credited    domain.Money    `json:"credited"`
+	Debited     domain.Money    `json:"debited"`
+	CreditCount int             `json:"credit_count"`
+	DebitCount  int             `json:"debit_count"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// DailyTotalsProjection sums balance events per user, currency and UTC day.
+// Transfers count as a debit of the sender and a credit of the recipient.
+type DailyTotalsProjection struct {
+	db *sql.DB
+}
+
+// NewDailyTotalsProjection creates the daily totals projection
+func NewDailyTotalsProjection(db *sql.DB) *DailyTotalsProjection {
+	return &DailyTotalsProjection{db: db}
+}
+
+// GetName returns the name of the projection
+func (p *DailyTotalsProjection) GetName() string {
+	return DailyTotalsProjectionName
+}
+
+// Handle adds a balance event to the totals of its day. Each event is
+// recorded when it is added, so handling it again changes nothing.
+func (p *DailyTotalsProjection) Handle(evt *event.Event) error {
+	var credited, debited domain.Money
+	var creditCount, debitCount int
+
+	switch evt.Type {
+	case event.BalanceCreditedEvent:
+		creditCount = 1
+	case event.BalanceDebitedEvent:
+		debitCount = 1
+	default:
+		return nil
+	}
+
+	var data event.BalanceChangedEventData
+	if err := evt.GetData(&data); err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", evt.Type, err)
+	}
+	if creditCount > 0 {
+		credited = data.Amount
+	} else {
+		debited = data.Amount
+	}
+
+	ctx := context.Background()
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO projection_daily_totals_events (event_id) VALUES ($1) ON CONFLICT (event_id) DO NOTHING`,
+		evt.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO projection_daily_totals (user_id, currency, day, credited, debited, credit_count, debit_count, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (user_id, currency, day)
+		DO UPDATE SET
+			credited = projection_daily_totals.credited + EXCLUDED.credited,
+			debited = projection_daily_totals.debited + EXCLUDED.debited,
+			credit_count = projection_daily_totals.credit_count + EXCLUDED.credit_count,
+			debit_count = projection_daily_totals.debit_count + EXCLUDED.debit_count,
+			updated_at = NOW()`
+
+	_, err = tx.ExecContext(ctx, query,
+		data.UserID,
+		data.Currency,
+		evt.CreatedAt.UTC().Format("2006-01-02"),
+		credited,
+		debited,
+		creditCount,
+		debitCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update daily totals: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Rebuild clears the totals so they can be replayed from the first event
+func (p *DailyTotalsProjection) Rebuild() error {
+	if _, err := p.db.Exec(`TRUNCATE projection_daily_totals, projection_daily_totals_events`); err != nil {
+		return fmt.Errorf("failed to clear daily totals: %w", err)
+	}
+	return nil
+}
+
+// GetUserTotals returns a user's daily totals from one UTC day to another,
+// both inclusive and formatted as YYYY-MM-DD, newest first
+func (p *DailyTotalsProjection) GetUserTotals(ctx context.Context, userID uuid.UUID, from, to string) ([]*DailyTotal, error) {
+	query := `
+		SELECT user_id, currency, to_char(day, 'YYYY-MM-DD'), credited, debited, credit_count, debit_count, updated_at
+		FROM projection_daily_totals
+		WHERE user_id = $1 AND day BETWEEN $2 AND $3
+		ORDER BY day DESC, currency ASC`
+
+	rows, err := p.db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []*DailyTotal
+	for rows.Next() {
+		total := &DailyTotal{}
+		err := rows.Scan(
+			&total.UserID,
+			&total.Currency,
+			&total.Day,
+			&total.Credited,
+			&total.Debited,
+			&total.CreditCount,
+			&total.DebitCount,
+			&total.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan daily total: %w", err)
+		}
+		totals = append(totals, total)
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,43 @@
+package breaker
+
+import (
+	"context"
+	"insider-backend/internal/circuit"
+	"insider-backend/internal/repository"
+)
+
+// CacheRepository runs cache calls through a circuit breaker, which should
+// count only IsCacheFailure errors. While it is open calls fail at once, so
+// reads fall back to the database without waiting for Redis to time out.
+type CacheRepository struct {
+	next  repository.CacheRepository
+	guard guard
+}
+
+func NewCacheRepository(next repository.CacheRepository, cb *circuit.CircuitBreaker) *CacheRepository {
+	return &CacheRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *CacheRepository) Set(ctx context.Context, key string, value interface{}, expiration int) error {
+	return r.guard.call(func() error { return r.next.Set(ctx, key, value, expiration) })
+}
+
+func (r *CacheRepository) Get(ctx context.Context, key string, dest interface{}) error {
+	return r.guard.call(func() error { return r.next.Get(ctx, key, dest) })
+}
+
+func (r *CacheRepository) Delete(ctx context.Context, key string) error {
+	return r.guard.call(func() error { return r.next.Delete(ctx, key) })
+}
+
+func (r *CacheRepository) DeletePattern(ctx context.Context, pattern string) error {
+	return r.guard.call(func() error { return r.next.DeletePattern(ctx, pattern) })
+}
+
+func (r *CacheRepository) Exists(ctx context.Context, key string) (bool, error) {
+	return do(r.guard, func() (bool, error) { return r.next.Exists(ctx, key) })
+}
+
+func (r *CacheRepository) SetNX(ctx context.Context, key string, value interface{}, expiration int) (bool, error) {
+	return do(r.guard, func() (bool, error) { return r.next.SetNX(ctx, key, value, expiration) })
+}
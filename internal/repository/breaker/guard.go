@@ -0,0 +1,78 @@
+package breaker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"insider-backend/internal/circuit"
+	"insider-backend/internal/repository"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// guard runs calls through a circuit breaker. Which errors count against the
+// breaker is up to its IsSuccessful setting, see IsPostgresFailure and
+// IsCacheFailure.
+type guard struct {
+	cb *circuit.CircuitBreaker
+}
+
+func (g guard) call(fn func() error) error {
+	return g.cb.Call(fn)
+}
+
+// do is call for functions returning a value
+func do[T any](g guard, fn func() (T, error)) (T, error) {
+	var result T
+	err := g.call(func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+// IsUnavailable reports whether err comes from an open circuit breaker
+func IsUnavailable(err error) bool {
+	return errors.Is(err, circuit.ErrCircuitOpen) || errors.Is(err, circuit.ErrTooManyRequests)
+}
+
+// IsPostgresFailure reports whether err means Postgres could not be reached
+// or could not serve the request, as opposed to rejecting it. A caller's
+// cancelled or expired context is not a failure, even when the driver reports
+// it as a network error; timeouts count only when the connection timed out.
+func IsPostgresFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// Dial and I/O errors, including read and write timeouts of the connection
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// Connection exceptions, insufficient resources, operator intervention
+	// and system errors
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "53") ||
+			strings.HasPrefix(code, "57P") || strings.HasPrefix(code, "58")
+	}
+
+	return false
+}
+
+// IsCacheFailure reports whether err means Redis could not serve the request.
+// Misses and cancelled requests are not failures.
+func IsCacheFailure(err error) bool {
+	return err != nil && !errors.Is(err, repository.ErrCacheMiss) && !errors.Is(err, context.Canceled)
+}
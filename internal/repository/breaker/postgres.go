@@ -0,0 +1,542 @@
+package breaker
+
+import (
+	"context"
+	"insider-backend/internal/circuit"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+	"insider-backend/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The Postgres repositories share one circuit breaker, since they share one
+// connection pool. It should count only IsPostgresFailure errors, so that a
+// missing row or a constraint violation does not trip it.
+
+// Wrap returns repos with every Postgres repository and the unit of work
+// running through postgres, and the cache through redis. A nil breaker leaves
+// the matching repositories unwrapped.
+func Wrap(repos *repository.Repositories, postgres, redis *circuit.CircuitBreaker) *repository.Repositories {
+	wrapped := *repos
+
+	if postgres != nil {
+		wrapped.User = NewUserRepository(repos.User, postgres)
+		wrapped.Transaction = NewTransactionRepository(repos.Transaction, postgres)
+		wrapped.Balance = NewBalanceRepository(repos.Balance, postgres)
+		wrapped.AuditLog = NewAuditLogRepository(repos.AuditLog, postgres)
+		wrapped.Ledger = NewLedgerRepository(repos.Ledger, postgres)
+		wrapped.Reconciliation = NewReconciliationRepository(repos.Reconciliation, postgres)
+		wrapped.Idempotency = NewIdempotencyRepository(repos.Idempotency, postgres)
+		wrapped.Hold = NewHoldRepository(repos.Hold, postgres)
+		wrapped.Schedule = NewScheduleRepository(repos.Schedule, postgres)
+		wrapped.Outbox = NewOutboxRepository(repos.Outbox, postgres)
+		wrapped.Webhook = NewWebhookRepository(repos.Webhook, postgres)
+		wrapped.WebhookDelivery = NewWebhookDeliveryRepository(repos.WebhookDelivery, postgres)
+		wrapped.Job = NewJobRepository(repos.Job, postgres)
+		if repos.UnitOfWork != nil {
+			wrapped.UnitOfWork = NewUnitOfWork(repos.UnitOfWork, postgres)
+		}
+	}
+	if redis != nil && repos.Cache != nil {
+		wrapped.Cache = NewCacheRepository(repos.Cache, redis)
+	}
+
+	return &wrapped
+}
+
+// UnitOfWork runs whole transactions through a circuit breaker. The
+// repositories inside a transaction are not wrapped again, so a transaction
+// counts once.
+type UnitOfWork struct {
+	next  repository.UnitOfWork
+	guard guard
+}
+
+func NewUnitOfWork(next repository.UnitOfWork, cb *circuit.CircuitBreaker) *UnitOfWork {
+	return &UnitOfWork{next: next, guard: guard{cb: cb}}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(repos *repository.Repositories) error) error {
+	return u.guard.call(func() error { return u.next.Do(ctx, fn) })
+}
+
+type UserRepository struct {
+	next  repository.UserRepository
+	guard guard
+}
+
+func NewUserRepository(next repository.UserRepository, cb *circuit.CircuitBreaker) *UserRepository {
+	return &UserRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, user) })
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return do(r.guard, func() (*domain.User, error) { return r.next.GetByID(ctx, id) })
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	return do(r.guard, func() (*domain.User, error) { return r.next.GetByUsername(ctx, username) })
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return do(r.guard, func() (*domain.User, error) { return r.next.GetByEmail(ctx, email) })
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, user) })
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.guard.call(func() error { return r.next.Delete(ctx, id) })
+}
+
+func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*domain.User, error) {
+	return do(r.guard, func() ([]*domain.User, error) { return r.next.List(ctx, limit, offset) })
+}
+
+func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	return do(r.guard, func() (bool, error) { return r.next.ExistsByUsername(ctx, username) })
+}
+
+func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	return do(r.guard, func() (bool, error) { return r.next.ExistsByEmail(ctx, email) })
+}
+
+type TransactionRepository struct {
+	next  repository.TransactionRepository
+	guard guard
+}
+
+func NewTransactionRepository(next repository.TransactionRepository, cb *circuit.CircuitBreaker) *TransactionRepository {
+	return &TransactionRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *TransactionRepository) Create(ctx context.Context, transaction *domain.Transaction) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, transaction) })
+}
+
+func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	return do(r.guard, func() (*domain.Transaction, error) { return r.next.GetByID(ctx, id) })
+}
+
+func (r *TransactionRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	return do(r.guard, func() (*domain.Transaction, error) { return r.next.GetByIDForUpdate(ctx, id) })
+}
+
+func (r *TransactionRepository) Update(ctx context.Context, transaction *domain.Transaction) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, transaction) })
+}
+
+func (r *TransactionRepository) List(ctx context.Context, filter domain.TransactionFilter) ([]*domain.Transaction, error) {
+	return do(r.guard, func() ([]*domain.Transaction, error) { return r.next.List(ctx, filter) })
+}
+
+func (r *TransactionRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, error) {
+	return do(r.guard, func() ([]*domain.Transaction, error) { return r.next.GetByUserID(ctx, userID, limit, offset) })
+}
+
+func (r *TransactionRepository) GetByReferenceID(ctx context.Context, referenceID string) (*domain.Transaction, error) {
+	return do(r.guard, func() (*domain.Transaction, error) { return r.next.GetByReferenceID(ctx, referenceID) })
+}
+
+func (r *TransactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.TransactionStatus) error {
+	return r.guard.call(func() error { return r.next.UpdateStatus(ctx, id, status) })
+}
+
+func (r *TransactionRepository) GetPendingReversalAmount(ctx context.Context, id uuid.UUID) (domain.Money, error) {
+	return do(r.guard, func() (domain.Money, error) { return r.next.GetPendingReversalAmount(ctx, id) })
+}
+
+func (r *TransactionRepository) ListPending(ctx context.Context, limit int) ([]*domain.Transaction, error) {
+	return do(r.guard, func() ([]*domain.Transaction, error) { return r.next.ListPending(ctx, limit) })
+}
+
+type BalanceRepository struct {
+	next  repository.BalanceRepository
+	guard guard
+}
+
+func NewBalanceRepository(next repository.BalanceRepository, cb *circuit.CircuitBreaker) *BalanceRepository {
+	return &BalanceRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *BalanceRepository) Create(ctx context.Context, balance *domain.Balance) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, balance) })
+}
+
+func (r *BalanceRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.Balance, error) {
+	return do(r.guard, func() ([]*domain.Balance, error) { return r.next.GetByUserID(ctx, userID) })
+}
+
+func (r *BalanceRepository) GetByUserIDAndCurrency(ctx context.Context, userID uuid.UUID, currency domain.Currency) (*domain.Balance, error) {
+	return do(r.guard, func() (*domain.Balance, error) { return r.next.GetByUserIDAndCurrency(ctx, userID, currency) })
+}
+
+func (r *BalanceRepository) Update(ctx context.Context, balance *domain.Balance) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, balance) })
+}
+
+func (r *BalanceRepository) UpdateWithLock(ctx context.Context, balance *domain.Balance) error {
+	return r.guard.call(func() error { return r.next.UpdateWithLock(ctx, balance) })
+}
+
+func (r *BalanceRepository) BatchUpdate(ctx context.Context, balances []*domain.Balance) error {
+	return r.guard.call(func() error { return r.next.BatchUpdate(ctx, balances) })
+}
+
+func (r *BalanceRepository) CreateHistory(ctx context.Context, history *domain.BalanceHistory) error {
+	return r.guard.call(func() error { return r.next.CreateHistory(ctx, history) })
+}
+
+func (r *BalanceRepository) GetHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.BalanceHistory, error) {
+	return do(r.guard, func() ([]*domain.BalanceHistory, error) { return r.next.GetHistory(ctx, userID, limit, offset) })
+}
+
+func (r *BalanceRepository) GetBalanceAtTime(ctx context.Context, userID uuid.UUID, currency domain.Currency, timestamp string) (domain.Money, error) {
+	return do(r.guard, func() (domain.Money, error) { return r.next.GetBalanceAtTime(ctx, userID, currency, timestamp) })
+}
+
+type AuditLogRepository struct {
+	next  repository.AuditLogRepository
+	guard guard
+}
+
+func NewAuditLogRepository(next repository.AuditLogRepository, cb *circuit.CircuitBreaker) *AuditLogRepository {
+	return &AuditLogRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *AuditLogRepository) Create(ctx context.Context, auditLog *domain.AuditLog) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, auditLog) })
+}
+
+func (r *AuditLogRepository) List(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, error) {
+	return do(r.guard, func() ([]*domain.AuditLog, error) { return r.next.List(ctx, filter) })
+}
+
+func (r *AuditLogRepository) GetByEntityID(ctx context.Context, entityType string, entityID uuid.UUID, limit, offset int) ([]*domain.AuditLog, error) {
+	return do(r.guard, func() ([]*domain.AuditLog, error) {
+		return r.next.GetByEntityID(ctx, entityType, entityID, limit, offset)
+	})
+}
+
+func (r *AuditLogRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.AuditLog, error) {
+	return do(r.guard, func() ([]*domain.AuditLog, error) { return r.next.GetByUserID(ctx, userID, limit, offset) })
+}
+
+func (r *AuditLogRepository) DeleteOlderThan(ctx context.Context, days int) error {
+	return r.guard.call(func() error { return r.next.DeleteOlderThan(ctx, days) })
+}
+
+type LedgerRepository struct {
+	next  repository.LedgerRepository
+	guard guard
+}
+
+func NewLedgerRepository(next repository.LedgerRepository, cb *circuit.CircuitBreaker) *LedgerRepository {
+	return &LedgerRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *LedgerRepository) CreateEntry(ctx context.Context, entry *domain.JournalEntry) error {
+	return r.guard.call(func() error { return r.next.CreateEntry(ctx, entry) })
+}
+
+func (r *LedgerRepository) GetEntriesByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]*domain.JournalEntry, error) {
+	return do(r.guard, func() ([]*domain.JournalEntry, error) { return r.next.GetEntriesByTransactionID(ctx, transactionID) })
+}
+
+func (r *LedgerRepository) GetUserBalances(ctx context.Context, userID *uuid.UUID) ([]*domain.LedgerBalance, error) {
+	return do(r.guard, func() ([]*domain.LedgerBalance, error) { return r.next.GetUserBalances(ctx, userID) })
+}
+
+func (r *LedgerRepository) RebuildBalances(ctx context.Context, userID *uuid.UUID) (int64, error) {
+	return do(r.guard, func() (int64, error) { return r.next.RebuildBalances(ctx, userID) })
+}
+
+type ReconciliationRepository struct {
+	next  repository.ReconciliationRepository
+	guard guard
+}
+
+func NewReconciliationRepository(next repository.ReconciliationRepository, cb *circuit.CircuitBreaker) *ReconciliationRepository {
+	return &ReconciliationRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *ReconciliationRepository) GetWalletStates(ctx context.Context, userID *uuid.UUID) ([]*domain.WalletState, error) {
+	return do(r.guard, func() ([]*domain.WalletState, error) { return r.next.GetWalletStates(ctx, userID) })
+}
+
+func (r *ReconciliationRepository) GetSuspectTransactions(ctx context.Context, userID uuid.UUID, currency domain.Currency) ([]uuid.UUID, error) {
+	return do(r.guard, func() ([]uuid.UUID, error) { return r.next.GetSuspectTransactions(ctx, userID, currency) })
+}
+
+type IdempotencyRepository struct {
+	next  repository.IdempotencyRepository
+	guard guard
+}
+
+func NewIdempotencyRepository(next repository.IdempotencyRepository, cb *circuit.CircuitBreaker) *IdempotencyRepository {
+	return &IdempotencyRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *IdempotencyRepository) Create(ctx context.Context, record *domain.IdempotencyRecord) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, record) })
+}
+
+func (r *IdempotencyRepository) Claim(ctx context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	return do(r.guard, func() (bool, error) { return r.next.Claim(ctx, record) })
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*domain.IdempotencyRecord, error) {
+	return do(r.guard, func() (*domain.IdempotencyRecord, error) { return r.next.Get(ctx, userID, key) })
+}
+
+type HoldRepository struct {
+	next  repository.HoldRepository
+	guard guard
+}
+
+func NewHoldRepository(next repository.HoldRepository, cb *circuit.CircuitBreaker) *HoldRepository {
+	return &HoldRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *HoldRepository) Create(ctx context.Context, hold *domain.Hold) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, hold) })
+}
+
+func (r *HoldRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Hold, error) {
+	return do(r.guard, func() (*domain.Hold, error) { return r.next.GetByID(ctx, id) })
+}
+
+func (r *HoldRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Hold, error) {
+	return do(r.guard, func() (*domain.Hold, error) { return r.next.GetByIDForUpdate(ctx, id) })
+}
+
+func (r *HoldRepository) Update(ctx context.Context, hold *domain.Hold) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, hold) })
+}
+
+func (r *HoldRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Hold, error) {
+	return do(r.guard, func() ([]*domain.Hold, error) { return r.next.GetByUserID(ctx, userID, limit, offset) })
+}
+
+func (r *HoldRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]*domain.Hold, error) {
+	return do(r.guard, func() ([]*domain.Hold, error) { return r.next.ListExpired(ctx, before, limit) })
+}
+
+type ScheduleRepository struct {
+	next  repository.ScheduleRepository
+	guard guard
+}
+
+func NewScheduleRepository(next repository.ScheduleRepository, cb *circuit.CircuitBreaker) *ScheduleRepository {
+	return &ScheduleRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *ScheduleRepository) Create(ctx context.Context, schedule *domain.Schedule) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, schedule) })
+}
+
+func (r *ScheduleRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Schedule, error) {
+	return do(r.guard, func() (*domain.Schedule, error) { return r.next.GetByID(ctx, id) })
+}
+
+func (r *ScheduleRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Schedule, error) {
+	return do(r.guard, func() (*domain.Schedule, error) { return r.next.GetByIDForUpdate(ctx, id) })
+}
+
+func (r *ScheduleRepository) Update(ctx context.Context, schedule *domain.Schedule) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, schedule) })
+}
+
+func (r *ScheduleRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Schedule, error) {
+	return do(r.guard, func() ([]*domain.Schedule, error) { return r.next.GetByUserID(ctx, userID, limit, offset) })
+}
+
+func (r *ScheduleRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]*domain.Schedule, error) {
+	return do(r.guard, func() ([]*domain.Schedule, error) { return r.next.ClaimDue(ctx, now, limit) })
+}
+
+type OutboxRepository struct {
+	next  repository.OutboxRepository
+	guard guard
+}
+
+func NewOutboxRepository(next repository.OutboxRepository, cb *circuit.CircuitBreaker) *OutboxRepository {
+	return &OutboxRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *OutboxRepository) Append(ctx context.Context, evt *event.Event) error {
+	return r.guard.call(func() error { return r.next.Append(ctx, evt) })
+}
+
+func (r *OutboxRepository) AppendEvents(ctx context.Context, aggregateID uuid.UUID, expectedVersion int, events ...*event.Event) error {
+	return r.guard.call(func() error { return r.next.AppendEvents(ctx, aggregateID, expectedVersion, events...) })
+}
+
+func (r *OutboxRepository) LastVersion(ctx context.Context, aggregateID uuid.UUID) (int, error) {
+	return do(r.guard, func() (int, error) { return r.next.LastVersion(ctx, aggregateID) })
+}
+
+func (r *OutboxRepository) ClaimPending(ctx context.Context, now time.Time, limit int) ([]*event.OutboxMessage, error) {
+	return do(r.guard, func() ([]*event.OutboxMessage, error) { return r.next.ClaimPending(ctx, now, limit) })
+}
+
+func (r *OutboxRepository) Update(ctx context.Context, message *event.OutboxMessage) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, message) })
+}
+
+func (r *OutboxRepository) DeleteDelivered(ctx context.Context, before time.Time) (int64, error) {
+	return do(r.guard, func() (int64, error) { return r.next.DeleteDelivered(ctx, before) })
+}
+
+type WebhookRepository struct {
+	next  repository.WebhookRepository
+	guard guard
+}
+
+func NewWebhookRepository(next repository.WebhookRepository, cb *circuit.CircuitBreaker) *WebhookRepository {
+	return &WebhookRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	return r.guard.call(func() error { return r.next.Create(ctx, subscription) })
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	return do(r.guard, func() (*domain.WebhookSubscription, error) { return r.next.GetByID(ctx, id) })
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, subscription) })
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.guard.call(func() error { return r.next.Delete(ctx, id) })
+}
+
+func (r *WebhookRepository) List(ctx context.Context, limit, offset int) ([]*domain.WebhookSubscription, error) {
+	return do(r.guard, func() ([]*domain.WebhookSubscription, error) { return r.next.List(ctx, limit, offset) })
+}
+
+func (r *WebhookRepository) GetActiveByEventType(ctx context.Context, eventType string) ([]*domain.WebhookSubscription, error) {
+	return do(r.guard, func() ([]*domain.WebhookSubscription, error) { return r.next.GetActiveByEventType(ctx, eventType) })
+}
+
+type WebhookDeliveryRepository struct {
+	next  repository.WebhookDeliveryRepository
+	guard guard
+}
+
+func NewWebhookDeliveryRepository(next repository.WebhookDeliveryRepository, cb *circuit.CircuitBreaker) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error) {
+	return do(r.guard, func() (bool, error) { return r.next.Create(ctx, delivery) })
+}
+
+func (r *WebhookDeliveryRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error) {
+	return do(r.guard, func() (*domain.WebhookDelivery, error) { return r.next.GetByID(ctx, id) })
+}
+
+func (r *WebhookDeliveryRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error) {
+	return do(r.guard, func() (*domain.WebhookDelivery, error) { return r.next.GetByIDForUpdate(ctx, id) })
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, delivery) })
+}
+
+func (r *WebhookDeliveryRepository) GetBySubscriptionID(ctx context.Context, subscriptionID uuid.UUID, status string, limit, offset int) ([]*domain.WebhookDelivery, error) {
+	return do(r.guard, func() ([]*domain.WebhookDelivery, error) {
+		return r.next.GetBySubscriptionID(ctx, subscriptionID, status, limit, offset)
+	})
+}
+
+func (r *WebhookDeliveryRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	return do(r.guard, func() ([]*domain.WebhookDelivery, error) { return r.next.ClaimDue(ctx, now, leaseUntil, limit) })
+}
+
+func (r *WebhookDeliveryRepository) CreateAttempt(ctx context.Context, attempt *domain.WebhookAttempt) error {
+	return r.guard.call(func() error { return r.next.CreateAttempt(ctx, attempt) })
+}
+
+func (r *WebhookDeliveryRepository) GetAttempts(ctx context.Context, deliveryID uuid.UUID) ([]*domain.WebhookAttempt, error) {
+	return do(r.guard, func() ([]*domain.WebhookAttempt, error) { return r.next.GetAttempts(ctx, deliveryID) })
+}
+
+func (r *WebhookDeliveryRepository) CreateDeadLetter(ctx context.Context, deadLetter *domain.WebhookDeadLetter) error {
+	return r.guard.call(func() error { return r.next.CreateDeadLetter(ctx, deadLetter) })
+}
+
+func (r *WebhookDeliveryRepository) ListDeadLetters(ctx context.Context, limit, offset int) ([]*domain.WebhookDeadLetter, error) {
+	return do(r.guard, func() ([]*domain.WebhookDeadLetter, error) { return r.next.ListDeadLetters(ctx, limit, offset) })
+}
+
+func (r *WebhookDeliveryRepository) MarkDeadLettersReplayed(ctx context.Context, deliveryID uuid.UUID, replayedAt time.Time) error {
+	return r.guard.call(func() error { return r.next.MarkDeadLettersReplayed(ctx, deliveryID, replayedAt) })
+}
+
+type JobRepository struct {
+	next  repository.JobRepository
+	guard guard
+}
+
+func NewJobRepository(next repository.JobRepository, cb *circuit.CircuitBreaker) *JobRepository {
+	return &JobRepository{next: next, guard: guard{cb: cb}}
+}
+
+func (r *JobRepository) Enqueue(ctx context.Context, job *domain.Job) (bool, error) {
+	return do(r.guard, func() (bool, error) { return r.next.Enqueue(ctx, job) })
+}
+
+func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Job, error) {
+	return do(r.guard, func() (*domain.Job, error) { return r.next.GetByID(ctx, id) })
+}
+
+func (r *JobRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Job, error) {
+	return do(r.guard, func() (*domain.Job, error) { return r.next.GetByIDForUpdate(ctx, id) })
+}
+
+func (r *JobRepository) Claim(ctx context.Context, worker string, now, lockedUntil time.Time, limit int) ([]*domain.Job, error) {
+	return do(r.guard, func() ([]*domain.Job, error) { return r.next.Claim(ctx, worker, now, lockedUntil, limit) })
+}
+
+func (r *JobRepository) Update(ctx context.Context, job *domain.Job) error {
+	return r.guard.call(func() error { return r.next.Update(ctx, job) })
+}
+
+func (r *JobRepository) Requeue(ctx context.Context, job *domain.Job) error {
+	return r.guard.call(func() error { return r.next.Requeue(ctx, job) })
+}
+
+func (r *JobRepository) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	return do(r.guard, func() (int64, error) { return r.next.DeleteFinished(ctx, before) })
+}
+
+func (r *JobRepository) CreateAttempt(ctx context.Context, attempt *domain.JobAttempt) error {
+	return r.guard.call(func() error { return r.next.CreateAttempt(ctx, attempt) })
+}
+
+func (r *JobRepository) GetAttempts(ctx context.Context, jobID uuid.UUID) ([]*domain.JobAttempt, error) {
+	return do(r.guard, func() ([]*domain.JobAttempt, error) { return r.next.GetAttempts(ctx, jobID) })
+}
+
+func (r *JobRepository) CreateDeadLetter(ctx context.Context, deadLetter *domain.JobDeadLetter) error {
+	return r.guard.call(func() error { return r.next.CreateDeadLetter(ctx, deadLetter) })
+}
+
+func (r *JobRepository) ListDeadLetters(ctx context.Context, jobType string, limit, offset int) ([]*domain.JobDeadLetter, error) {
+	return do(r.guard, func() ([]*domain.JobDeadLetter, error) {
+		return r.next.ListDeadLetters(ctx, jobType, limit, offset)
+	})
+}
+
+func (r *JobRepository) ResolveDeadLetters(ctx context.Context, jobID uuid.UUID, resolution domain.JobDeadLetterResolution, resolvedAt time.Time) error {
+	return r.guard.call(func() error { return r.next.ResolveDeadLetters(ctx, jobID, resolution, resolvedAt) })
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -22,23 +25,26 @@ type UserRepository interface {
 type TransactionRepository interface {
 	Create(ctx context.Context, transaction *domain.Transaction) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error)
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Transaction, error)
 	Update(ctx context.Context, transaction *domain.Transaction) error
 	List(ctx context.Context, filter domain.TransactionFilter) ([]*domain.Transaction, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, error)
 	GetByReferenceID(ctx context.Context, referenceID string) (*domain.Transaction, error)
 	UpdateStatus(ctx context.Context, id uuid.UUID, status domain.TransactionStatus) error
+	GetPendingReversalAmount(ctx context.Context, id uuid.UUID) (domain.Money, error)
 	ListPending(ctx context.Context, limit int) ([]*domain.Transaction, error)
 }
 
 type BalanceRepository interface {
 	Create(ctx context.Context, balance *domain.Balance) error
-	GetByUserID(ctx context.Context, userID uuid.UUID) (*domain.Balance, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.Balance, error)
+	GetByUserIDAndCurrency(ctx context.Context, userID uuid.UUID, currency domain.Currency) (*domain.Balance, error)
 	Update(ctx context.Context, balance *domain.Balance) error
 	UpdateWithLock(ctx context.Context, balance *domain.Balance) error
 	BatchUpdate(ctx context.Context, balances []*domain.Balance) error
 	CreateHistory(ctx context.Context, history *domain.BalanceHistory) error
 	GetHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.BalanceHistory, error)
-	GetBalanceAtTime(ctx context.Context, userID uuid.UUID, timestamp string) (float64, error)
+	GetBalanceAtTime(ctx context.Context, userID uuid.UUID, currency domain.Currency, timestamp string) (domain.Money, error)
 }
 
 type AuditLogRepository interface {
@@ -49,6 +55,119 @@ type AuditLogRepository interface {
 	DeleteOlderThan(ctx context.Context, days int) error
 }
 
+type LedgerRepository interface {
+	CreateEntry(ctx context.Context, entry *domain.JournalEntry) error
+	GetEntriesByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]*domain.JournalEntry, error)
+	GetUserBalances(ctx context.Context, userID *uuid.UUID) ([]*domain.LedgerBalance, error)
+	RebuildBalances(ctx context.Context, userID *uuid.UUID) (int64, error)
+}
+
+type ReconciliationRepository interface {
+	GetWalletStates(ctx context.Context, userID *uuid.UUID) ([]*domain.WalletState, error)
+	GetSuspectTransactions(ctx context.Context, userID uuid.UUID, currency domain.Currency) ([]uuid.UUID, error)
+}
+
+type IdempotencyRepository interface {
+	Create(ctx context.Context, record *domain.IdempotencyRecord) error
+	// Claim returns false when the key is already claimed or completed
+	Claim(ctx context.Context, record *domain.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, userID uuid.UUID, key string) (*domain.IdempotencyRecord, error)
+}
+
+type HoldRepository interface {
+	Create(ctx context.Context, hold *domain.Hold) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Hold, error)
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Hold, error)
+	Update(ctx context.Context, hold *domain.Hold) error
+	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Hold, error)
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]*domain.Hold, error)
+}
+
+type ScheduleRepository interface {
+	Create(ctx context.Context, schedule *domain.Schedule) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Schedule, error)
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Schedule, error)
+	Update(ctx context.Context, schedule *domain.Schedule) error
+	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Schedule, error)
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]*domain.Schedule, error)
+}
+
+// OutboxRepository appends domain events and queues them for delivery. Append
+// must run in the unit of work that saves the state change the event describes.
+type OutboxRepository interface {
+	Append(ctx context.Context, evt *event.Event) error
+	AppendEvents(ctx context.Context, aggregateID uuid.UUID, expectedVersion int, events ...*event.Event) error
+	LastVersion(ctx context.Context, aggregateID uuid.UUID) (int, error)
+	ClaimPending(ctx context.Context, now time.Time, limit int) ([]*event.OutboxMessage, error)
+	Update(ctx context.Context, message *event.OutboxMessage) error
+	DeleteDelivered(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, subscription *domain.WebhookSubscription) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error)
+	Update(ctx context.Context, subscription *domain.WebhookSubscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, limit, offset int) ([]*domain.WebhookSubscription, error)
+	GetActiveByEventType(ctx context.Context, eventType string) ([]*domain.WebhookSubscription, error)
+}
+
+type WebhookDeliveryRepository interface {
+	// Create returns false when the subscription already has a delivery of the event
+	Create(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error)
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error)
+	Update(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetBySubscriptionID(ctx context.Context, subscriptionID uuid.UUID, status string, limit, offset int) ([]*domain.WebhookDelivery, error)
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*domain.WebhookDelivery, error)
+	CreateAttempt(ctx context.Context, attempt *domain.WebhookAttempt) error
+	GetAttempts(ctx context.Context, deliveryID uuid.UUID) ([]*domain.WebhookAttempt, error)
+	CreateDeadLetter(ctx context.Context, deadLetter *domain.WebhookDeadLetter) error
+	ListDeadLetters(ctx context.Context, limit, offset int) ([]*domain.WebhookDeadLetter, error)
+	MarkDeadLettersReplayed(ctx context.Context, deliveryID uuid.UUID, replayedAt time.Time) error
+}
+
+// JobRepository is the persistent job queue. Jobs are claimed with row locks
+// that skip rows claimed elsewhere, so every worker of every instance can
+// consume it.
+type JobRepository interface {
+	// Enqueue returns false when an unfinished job with the same key exists
+	Enqueue(ctx context.Context, job *domain.Job) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Job, error)
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Job, error)
+	Claim(ctx context.Context, worker string, now, lockedUntil time.Time, limit int) ([]*domain.Job, error)
+	Update(ctx context.Context, job *domain.Job) error
+	Requeue(ctx context.Context, job *domain.Job) error
+	DeleteFinished(ctx context.Context, before time.Time) (int64, error)
+	CreateAttempt(ctx context.Context, attempt *domain.JobAttempt) error
+	GetAttempts(ctx context.Context, jobID uuid.UUID) ([]*domain.JobAttempt, error)
+	CreateDeadLetter(ctx context.Context, deadLetter *domain.JobDeadLetter) error
+	ListDeadLetters(ctx context.Context, jobType string, limit, offset int) ([]*domain.JobDeadLetter, error)
+	ResolveDeadLetters(ctx context.Context, jobID uuid.UUID, resolution domain.JobDeadLetterResolution, resolvedAt time.Time) error
+}
+
+// ErrJobLeaseLost is returned when the outcome of a job is saved after its
+// lease expired and another worker claimed it
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+// ErrBalanceHistoryNotFound is returned when no balance history row records
+// a wallet at the requested time
+var ErrBalanceHistoryNotFound = errors.New("no balance history at this time")
+
+// ErrTransactionNotFound is returned when a transaction does not exist
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// ErrVersionConflict is returned when a row being updated changed since it
+// was read. Reading it again and redoing the update may succeed.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrHeldExceedsBalance is returned when rebuilding balances from the journal
+// would leave less than the amount on hold in a wallet
+var ErrHeldExceedsBalance = errors.New("rebuilt balance is below the held amount")
+
+// ErrCacheMiss is returned by CacheRepository.Get when the key does not exist
+var ErrCacheMiss = errors.New("key not found")
+
 type CacheRepository interface {
 	Set(ctx context.Context, key string, value interface{}, expiration int) error
 	Get(ctx context.Context, key string, dest interface{}) error
@@ -58,10 +177,36 @@ type CacheRepository interface {
 	SetNX(ctx context.Context, key string, value interface{}, expiration int) (bool, error)
 }
 
+// UnitOfWork runs fn with repositories that share a single database
+// transaction. The transaction commits if fn returns nil and rolls back otherwise.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(repos *Repositories) error) error
+}
+
 type Repositories struct {
-	User        UserRepository
-	Transaction TransactionRepository
-	Balance     BalanceRepository
-	AuditLog    AuditLogRepository
-	Cache       CacheRepository
+	User            UserRepository
+	Transaction     TransactionRepository
+	Balance         BalanceRepository
+	AuditLog        AuditLogRepository
+	Ledger          LedgerRepository
+	Reconciliation  ReconciliationRepository
+	Idempotency     IdempotencyRepository
+	Hold            HoldRepository
+	Schedule        ScheduleRepository
+	Outbox          OutboxRepository
+	Webhook         WebhookRepository
+	WebhookDelivery WebhookDeliveryRepository
+	Job             JobRepository
+	Cache           CacheRepository
+	UnitOfWork      UnitOfWork
+}
+
+// WithinTransaction runs fn atomically through the unit of work. Repositories
+// passed to fn are already transactional, so nested calls join the outer
+// transaction. Without a unit of work fn runs against r directly.
+func (r *Repositories) WithinTransaction(ctx context.Context, fn func(repos *Repositories) error) error {
+	if r.UnitOfWork == nil {
+		return fn(r)
+	}
+	return r.UnitOfWork.Do(ctx, fn)
 }
@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"insider-backend/internal/domain"
 	"strings"
@@ -12,10 +11,10 @@ import (
 )
 
 type AuditLogRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewAuditLogRepository(db *sql.DB) *AuditLogRepository {
+func NewAuditLogRepository(db DBTX) *AuditLogRepository {
 	return &AuditLogRepository{db: db}
 }
 
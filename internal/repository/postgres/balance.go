@@ -5,26 +5,29 @@ import (
 	"database/sql"
 	"fmt"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
 
 	"github.com/google/uuid"
 )
 
 type BalanceRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewBalanceRepository(db *sql.DB) *BalanceRepository {
+func NewBalanceRepository(db DBTX) *BalanceRepository {
 	return &BalanceRepository{db: db}
 }
 
 func (r *BalanceRepository) Create(ctx context.Context, balance *domain.Balance) error {
 	query := `
-		INSERT INTO balances (user_id, amount, last_updated_at, version)
-		VALUES ($1, $2, $3, $4)`
+		INSERT INTO balances (user_id, currency, amount, held_amount, last_updated_at, version)
+		VALUES ($1, $2, $3, $4, $5, $6)`
 
 	_, err := r.db.ExecContext(ctx, query,
 		balance.UserID,
+		balance.Currency,
 		balance.Amount,
+		balance.HeldAmount,
 		balance.LastUpdatedAt,
 		balance.Version,
 	)
@@ -36,22 +39,56 @@ func (r *BalanceRepository) Create(ctx context.Context, balance *domain.Balance)
 	return nil
 }
 
-func (r *BalanceRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*domain.Balance, error) {
+func (r *BalanceRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.Balance, error) {
+	query := `
+		SELECT user_id, currency, amount, held_amount, last_updated_at, version
+		FROM balances WHERE user_id = $1
+		ORDER BY currency ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*domain.Balance
+	for rows.Next() {
+		balance := &domain.Balance{}
+		err := rows.Scan(
+			&balance.UserID,
+			&balance.Currency,
+			&balance.Amount,
+			&balance.HeldAmount,
+			&balance.LastUpdatedAt,
+			&balance.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+func (r *BalanceRepository) GetByUserIDAndCurrency(ctx context.Context, userID uuid.UUID, currency domain.Currency) (*domain.Balance, error) {
 	query := `
-		SELECT user_id, amount, last_updated_at, version
-		FROM balances WHERE user_id = $1`
+		SELECT user_id, currency, amount, held_amount, last_updated_at, version
+		FROM balances WHERE user_id = $1 AND currency = $2`
 
-	balance := domain.NewBalance(userID)
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+	balance := domain.NewBalance(userID, currency)
+	err := r.db.QueryRowContext(ctx, query, userID, currency).Scan(
 		&balance.UserID,
+		&balance.Currency,
 		&balance.Amount,
+		&balance.HeldAmount,
 		&balance.LastUpdatedAt,
 		&balance.Version,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// Create new balance if it doesn't exist
+			// Create new wallet if it doesn't exist
 			if createErr := r.Create(ctx, balance); createErr != nil {
 				return nil, fmt.Errorf("failed to create new balance: %w", createErr)
 			}
@@ -66,12 +103,14 @@ func (r *BalanceRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 func (r *BalanceRepository) Update(ctx context.Context, balance *domain.Balance) error {
 	query := `
 		UPDATE balances 
-		SET amount = $2, last_updated_at = $3, version = $4
-		WHERE user_id = $1`
+		SET amount = $3, held_amount = $4, last_updated_at = $5, version = $6
+		WHERE user_id = $1 AND currency = $2`
 
 	result, err := r.db.ExecContext(ctx, query,
 		balance.UserID,
+		balance.Currency,
 		balance.Amount,
+		balance.HeldAmount,
 		balance.LastUpdatedAt,
 		balance.Version,
 	)
@@ -93,95 +132,106 @@ func (r *BalanceRepository) Update(ctx context.Context, balance *domain.Balance)
 }
 
 func (r *BalanceRepository) UpdateWithLock(ctx context.Context, balance *domain.Balance) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Lock the row for update
-	query := `
-		SELECT user_id, amount, last_updated_at, version
-		FROM balances WHERE user_id = $1 FOR UPDATE`
-
-	currentBalance := &domain.Balance{}
-	err = tx.QueryRowContext(ctx, query, balance.UserID).Scan(
-		&currentBalance.UserID,
-		&currentBalance.Amount,
-		&currentBalance.LastUpdatedAt,
-		&currentBalance.Version,
-	)
+	return withTx(ctx, r.db, func(tx DBTX) error {
+		// Lock the row for update
+		query := `
+			SELECT user_id, currency, amount, held_amount, last_updated_at, version
+			FROM balances WHERE user_id = $1 AND currency = $2 FOR UPDATE`
+
+		currentBalance := &domain.Balance{}
+		err := tx.QueryRowContext(ctx, query, balance.UserID, balance.Currency).Scan(
+			&currentBalance.UserID,
+			&currentBalance.Currency,
+			&currentBalance.Amount,
+			&currentBalance.HeldAmount,
+			&currentBalance.LastUpdatedAt,
+			&currentBalance.Version,
+		)
 
-	if err != nil {
-		return fmt.Errorf("failed to lock balance: %w", err)
-	}
+		if err != nil {
+			return fmt.Errorf("failed to lock balance: %w", err)
+		}
 
-	// Check version for optimistic locking
-	if currentBalance.Version != balance.Version-1 {
-		return fmt.Errorf("balance version mismatch: expected %d, got %d", balance.Version-1, currentBalance.Version)
-	}
+		// Check version for optimistic locking
+		if currentBalance.Version != balance.Version-1 {
+			return fmt.Errorf("%w: balance expected at version %d, found %d", repository.ErrVersionConflict, balance.Version-1, currentBalance.Version)
+		}
 
-	// Update the balance
-	updateQuery := `
-		UPDATE balances 
-		SET amount = $2, last_updated_at = $3, version = $4
-		WHERE user_id = $1`
+		// Update the balance
+		updateQuery := `
+			UPDATE balances 
+			SET amount = $3, held_amount = $4, last_updated_at = $5, version = $6
+			WHERE user_id = $1 AND currency = $2`
 
-	_, err = tx.ExecContext(ctx, updateQuery,
-		balance.UserID,
-		balance.Amount,
-		balance.LastUpdatedAt,
-		balance.Version,
-	)
+		_, err = tx.ExecContext(ctx, updateQuery,
+			balance.UserID,
+			balance.Currency,
+			balance.Amount,
+			balance.HeldAmount,
+			balance.LastUpdatedAt,
+			balance.Version,
+		)
 
-	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
-	}
+		if err != nil {
+			return fmt.Errorf("failed to update balance: %w", err)
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
+// BatchUpdate updates several balances atomically. Each balance must still be
+// at the version it was read at, otherwise the whole batch is rejected.
 func (r *BalanceRepository) BatchUpdate(ctx context.Context, balances []*domain.Balance) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
+	return withTx(ctx, r.db, func(tx DBTX) error {
+		query := `
+			UPDATE balances 
+			SET amount = $3, held_amount = $4, last_updated_at = $5, version = $6
+			WHERE user_id = $1 AND currency = $2 AND version = $7`
 
-	query := `
-		UPDATE balances 
-		SET amount = $2, last_updated_at = $3, version = $4
-		WHERE user_id = $1`
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, balance := range balances {
+			result, err := stmt.ExecContext(ctx,
+				balance.UserID,
+				balance.Currency,
+				balance.Amount,
+				balance.HeldAmount,
+				balance.LastUpdatedAt,
+				balance.Version,
+				balance.Version-1,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update %s balance for user %s: %w", balance.Currency, balance.UserID, err)
+			}
 
-	stmt, err := tx.PrepareContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
-	}
-	defer stmt.Close()
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to get rows affected: %w", err)
+			}
 
-	for _, balance := range balances {
-		_, err = stmt.ExecContext(ctx,
-			balance.UserID,
-			balance.Amount,
-			balance.LastUpdatedAt,
-			balance.Version,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to update balance for user %s: %w", balance.UserID, err)
+			if rowsAffected == 0 {
+				return fmt.Errorf("%w: %s balance of user %s", repository.ErrVersionConflict, balance.Currency, balance.UserID)
+			}
 		}
-	}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 func (r *BalanceRepository) CreateHistory(ctx context.Context, history *domain.BalanceHistory) error {
 	query := `
-		INSERT INTO balance_history (id, user_id, amount, previous_amount, transaction_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+		INSERT INTO balance_history (id, user_id, currency, amount, previous_amount, transaction_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
 	_, err := r.db.ExecContext(ctx, query,
 		history.ID,
 		history.UserID,
+		history.Currency,
 		history.Amount,
 		history.PreviousAmount,
 		history.TransactionID,
@@ -197,7 +247,7 @@ func (r *BalanceRepository) CreateHistory(ctx context.Context, history *domain.B
 
 func (r *BalanceRepository) GetHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.BalanceHistory, error) {
 	query := `
-		SELECT id, user_id, amount, previous_amount, transaction_id, created_at
+		SELECT id, user_id, currency, amount, previous_amount, transaction_id, created_at
 		FROM balance_history 
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -215,6 +265,7 @@ func (r *BalanceRepository) GetHistory(ctx context.Context, userID uuid.UUID, li
 		err := rows.Scan(
 			&history.ID,
 			&history.UserID,
+			&history.Currency,
 			&history.Amount,
 			&history.PreviousAmount,
 			&history.TransactionID,
@@ -229,19 +280,21 @@ func (r *BalanceRepository) GetHistory(ctx context.Context, userID uuid.UUID, li
 	return histories, nil
 }
 
-func (r *BalanceRepository) GetBalanceAtTime(ctx context.Context, userID uuid.UUID, timestamp string) (float64, error) {
+// GetBalanceAtTime returns the balance recorded by the last history row at or
+// before timestamp, or repository.ErrBalanceHistoryNotFound if there is none
+func (r *BalanceRepository) GetBalanceAtTime(ctx context.Context, userID uuid.UUID, currency domain.Currency, timestamp string) (domain.Money, error) {
 	query := `
 		SELECT amount
 		FROM balance_history 
-		WHERE user_id = $1 AND created_at <= $2
+		WHERE user_id = $1 AND currency = $2 AND created_at <= $3
 		ORDER BY created_at DESC
 		LIMIT 1`
 
-	var amount float64
-	err := r.db.QueryRowContext(ctx, query, userID, timestamp).Scan(&amount)
+	var amount domain.Money
+	err := r.db.QueryRowContext(ctx, query, userID, currency, timestamp).Scan(&amount)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, nil // No history found, return 0 balance
+			return 0, repository.ErrBalanceHistoryNotFound
 		}
 		return 0, fmt.Errorf("failed to get balance at time: %w", err)
 	}
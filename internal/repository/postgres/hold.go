@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// holdColumns lists the columns read and written for a hold, in scan order
+const holdColumns = `id, user_id, currency, amount, captured_amount, status, description, reference_id,
+		transaction_id, expires_at, created_at, updated_at`
+
+func scanHold(row rowScanner) (*domain.Hold, error) {
+	hold := &domain.Hold{}
+	err := row.Scan(
+		&hold.ID,
+		&hold.UserID,
+		&hold.Currency,
+		&hold.Amount,
+		&hold.CapturedAmount,
+		&hold.Status,
+		&hold.Description,
+		&hold.ReferenceID,
+		&hold.TransactionID,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+type HoldRepository struct {
+	db DBTX
+}
+
+func NewHoldRepository(db DBTX) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+func (r *HoldRepository) Create(ctx context.Context, hold *domain.Hold) error {
+	query := `
+		INSERT INTO holds (` + holdColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		hold.ID,
+		hold.UserID,
+		hold.Currency,
+		hold.Amount,
+		hold.CapturedAmount,
+		hold.Status,
+		hold.Description,
+		hold.ReferenceID,
+		hold.TransactionID,
+		hold.ExpiresAt,
+		hold.CreatedAt,
+		hold.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	return nil
+}
+
+func (r *HoldRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds WHERE id = $1`
+
+	hold, err := scanHold(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("hold not found")
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+// GetByIDForUpdate reads a hold and locks its row until the enclosing
+// database transaction ends. Only meaningful inside a unit of work.
+func (r *HoldRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds WHERE id = $1 FOR UPDATE`
+
+	hold, err := scanHold(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("hold not found")
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (r *HoldRepository) Update(ctx context.Context, hold *domain.Hold) error {
+	query := `
+		UPDATE holds
+		SET captured_amount = $2, status = $3, transaction_id = $4, updated_at = $5
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		hold.ID,
+		hold.CapturedAmount,
+		hold.Status,
+		hold.TransactionID,
+		hold.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update hold: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("hold not found")
+	}
+
+	return nil
+}
+
+func (r *HoldRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.list(ctx, query, userID, limit, offset)
+}
+
+// ListExpired returns active holds whose expiry is at or before the given time
+func (r *HoldRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]*domain.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds
+		WHERE status = 'active' AND expires_at <= $1
+		ORDER BY expires_at ASC
+		LIMIT $2`
+
+	return r.list(ctx, query, before, limit)
+}
+
+func (r *HoldRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Hold, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list holds: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []*domain.Hold
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hold: %w", err)
+		}
+		holds = append(holds, hold)
+	}
+
+	return holds, nil
+}
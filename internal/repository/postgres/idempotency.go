@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+type IdempotencyRepository struct {
+	db DBTX
+}
+
+func NewIdempotencyRepository(db DBTX) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Create stores a completed request, completing the claim of its key. A
+// record already completed for the key is left untouched so the first
+// response always wins.
+func (r *IdempotencyRepository) Create(ctx context.Context, record *domain.IdempotencyRecord) error {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, method, path, request_hash, status, status_code, content_type, response_body, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET status = EXCLUDED.status, status_code = EXCLUDED.status_code,
+			content_type = EXCLUDED.content_type, response_body = EXCLUDED.response_body
+		WHERE idempotency_keys.status = 'in_progress' AND idempotency_keys.request_hash = EXCLUDED.request_hash`
+
+	_, err := r.db.ExecContext(ctx, query,
+		record.UserID,
+		record.Key,
+		record.Method,
+		record.Path,
+		record.RequestHash,
+		record.Status,
+		record.StatusCode,
+		record.ContentType,
+		record.ResponseBody,
+		record.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// Claim stores the in progress record of a key and reports whether the key
+// was free. The primary key makes concurrent claims of a key wait for each
+// other, so only one of them succeeds.
+func (r *IdempotencyRepository) Claim(ctx context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, method, path, request_hash, status, status_code, content_type, response_body, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query,
+		record.UserID,
+		record.Key,
+		record.Method,
+		record.Path,
+		record.RequestHash,
+		record.Status,
+		record.StatusCode,
+		record.ContentType,
+		record.ResponseBody,
+		record.CreatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// Get returns the record stored for a key, or nil if the key has not been used
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*domain.IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, idempotency_key, method, path, request_hash, status, status_code, content_type, response_body, created_at
+		FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`
+
+	record := &domain.IdempotencyRecord{}
+	err := r.db.QueryRowContext(ctx, query, userID, key).Scan(
+		&record.UserID,
+		&record.Key,
+		&record.Method,
+		&record.Path,
+		&record.RequestHash,
+		&record.Status,
+		&record.StatusCode,
+		&record.ContentType,
+		&record.ResponseBody,
+		&record.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	return record, nil
+}
@@ -0,0 +1,395 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// jobColumns lists the columns read and written for a job, in scan order
+const jobColumns = `id, key, type, payload, status, attempts, max_attempts, last_error,
+		scheduled_at, locked_by, locked_until, lease_id, created_at, updated_at, completed_at`
+
+func scanJob(row rowScanner) (*domain.Job, error) {
+	job := &domain.Job{}
+	var lastError, lockedBy sql.NullString
+	err := row.Scan(
+		&job.ID,
+		&job.Key,
+		&job.Type,
+		&job.Payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&lastError,
+		&job.ScheduledAt,
+		&lockedBy,
+		&job.LockedUntil,
+		&job.LeaseID,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.LastError = lastError.String
+	job.LockedBy = lockedBy.String
+	return job, nil
+}
+
+type JobRepository struct {
+	db DBTX
+}
+
+func NewJobRepository(db DBTX) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Enqueue saves a job unless an unfinished job with the same key exists, in
+// which case it returns false
+func (r *JobRepository) Enqueue(ctx context.Context, job *domain.Job) (bool, error) {
+	query := `
+		INSERT INTO jobs (` + jobColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (key) WHERE status IN ('pending', 'running') DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query,
+		job.ID,
+		job.Key,
+		job.Type,
+		job.Payload,
+		job.Status,
+		job.Attempts,
+		job.MaxAttempts,
+		sql.NullString{String: job.LastError, Valid: job.LastError != ""},
+		job.ScheduledAt,
+		sql.NullString{String: job.LockedBy, Valid: job.LockedBy != ""},
+		job.LockedUntil,
+		job.LeaseID,
+		job.CreatedAt,
+		job.UpdatedAt,
+		job.CompletedAt,
+	)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs WHERE id = $1`
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *JobRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs WHERE id = $1
+		FOR UPDATE`
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+// Claim leases up to limit jobs to worker until lockedUntil: pending jobs due
+// at or before now, and running jobs whose lease expired because their worker
+// died. Every claim counts as an attempt. Rows locked by another worker are
+// skipped, so any number of workers can claim concurrently.
+func (r *JobRepository) Claim(ctx context.Context, worker string, now, lockedUntil time.Time, limit int) ([]*domain.Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_by = $1, locked_until = $3,
+			lease_id = uuid_generate_v4(), updated_at = $2
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE (status = 'pending' AND scheduled_at <= $2)
+				OR (status = 'running' AND locked_until <= $2)
+			ORDER BY scheduled_at ASC
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	rows, err := r.db.QueryContext(ctx, query, worker, now, lockedUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*domain.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// Update saves the outcome of a claimed job. It fails with
+// repository.ErrJobLeaseLost when the lease the job was claimed with expired
+// and the job was claimed again.
+func (r *JobRepository) Update(ctx context.Context, job *domain.Job) error {
+	query := `
+		UPDATE jobs
+		SET status = $3, attempts = $4, last_error = $5, scheduled_at = $6, locked_by = $7,
+			locked_until = $8, updated_at = $9, completed_at = $10
+		WHERE id = $1 AND lease_id = $2 AND status = 'running'`
+
+	result, err := r.db.ExecContext(ctx, query,
+		job.ID,
+		job.LeaseID,
+		job.Status,
+		job.Attempts,
+		sql.NullString{String: job.LastError, Valid: job.LastError != ""},
+		job.ScheduledAt,
+		sql.NullString{String: job.LockedBy, Valid: job.LockedBy != ""},
+		job.LockedUntil,
+		job.UpdatedAt,
+		job.CompletedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+// Requeue saves a dead job that was queued again. It fails when an unfinished
+// job with the same key was queued meanwhile.
+func (r *JobRepository) Requeue(ctx context.Context, job *domain.Job) error {
+	query := `
+		UPDATE jobs
+		SET status = $2, attempts = $3, last_error = $4, scheduled_at = $5, updated_at = $6, completed_at = $7
+		WHERE id = $1 AND status = 'dead'`
+
+	result, err := r.db.ExecContext(ctx, query,
+		job.ID,
+		job.Status,
+		job.Attempts,
+		sql.NullString{String: job.LastError, Valid: job.LastError != ""},
+		job.ScheduledAt,
+		job.UpdatedAt,
+		job.CompletedAt,
+	)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return fmt.Errorf("an unfinished job with key %s is already queued", job.Key)
+		}
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("job not found")
+	}
+
+	return nil
+}
+
+func (r *JobRepository) CreateAttempt(ctx context.Context, attempt *domain.JobAttempt) error {
+	query := `
+		INSERT INTO job_attempts (id, job_id, attempt, worker, error, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		attempt.ID,
+		attempt.JobID,
+		attempt.Attempt,
+		attempt.Worker,
+		sql.NullString{String: attempt.Error, Valid: attempt.Error != ""},
+		attempt.DurationMs,
+		attempt.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create job attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetAttempts lists the attempts of a job in the order they were made
+func (r *JobRepository) GetAttempts(ctx context.Context, jobID uuid.UUID) ([]*domain.JobAttempt, error) {
+	query := `
+		SELECT id, job_id, attempt, worker, COALESCE(error, ''), duration_ms, created_at
+		FROM job_attempts
+		WHERE job_id = $1
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*domain.JobAttempt
+	for rows.Next() {
+		attempt := &domain.JobAttempt{}
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.JobID,
+			&attempt.Attempt,
+			&attempt.Worker,
+			&attempt.Error,
+			&attempt.DurationMs,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+func (r *JobRepository) CreateDeadLetter(ctx context.Context, deadLetter *domain.JobDeadLetter) error {
+	query := `
+		INSERT INTO job_dead_letters (id, job_id, key, type, payload, attempts, last_error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		deadLetter.ID,
+		deadLetter.JobID,
+		deadLetter.Key,
+		deadLetter.Type,
+		deadLetter.Payload,
+		deadLetter.Attempts,
+		deadLetter.LastError,
+		deadLetter.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create job dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeadLetters lists the dead letters that have not been resolved, newest
+// first, optionally only those of a job type
+func (r *JobRepository) ListDeadLetters(ctx context.Context, jobType string, limit, offset int) ([]*domain.JobDeadLetter, error) {
+	query := `
+		SELECT id, job_id, key, type, payload, attempts, last_error, created_at, resolution, resolved_at
+		FROM job_dead_letters
+		WHERE resolved_at IS NULL AND ($1 = '' OR type = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, jobType, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []*domain.JobDeadLetter
+	for rows.Next() {
+		deadLetter := &domain.JobDeadLetter{}
+		err := rows.Scan(
+			&deadLetter.ID,
+			&deadLetter.JobID,
+			&deadLetter.Key,
+			&deadLetter.Type,
+			&deadLetter.Payload,
+			&deadLetter.Attempts,
+			&deadLetter.LastError,
+			&deadLetter.CreatedAt,
+			&deadLetter.Resolution,
+			&deadLetter.ResolvedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job dead letter: %w", err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	return deadLetters, nil
+}
+
+// ResolveDeadLetters records how the open dead letters of a job were resolved
+func (r *JobRepository) ResolveDeadLetters(ctx context.Context, jobID uuid.UUID, resolution domain.JobDeadLetterResolution, resolvedAt time.Time) error {
+	query := `UPDATE job_dead_letters SET resolution = $2, resolved_at = $3 WHERE job_id = $1 AND resolved_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, jobID, resolution, resolvedAt); err != nil {
+		return fmt.Errorf("failed to resolve job dead letters: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFinished prunes jobs that completed or failed before the given time,
+// and dead jobs whose dead letter was discarded. Other dead jobs are kept
+// until they are dealt with.
+func (r *JobRepository) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM jobs
+		WHERE completed_at < $1 AND (
+			status IN ('completed', 'failed')
+			OR (status = 'dead'
+				AND EXISTS (SELECT 1 FROM job_dead_letters d WHERE d.job_id = jobs.id AND d.resolution = 'discarded')
+				AND NOT EXISTS (SELECT 1 FROM job_dead_letters d WHERE d.job_id = jobs.id AND d.resolved_at IS NULL))
+		)`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished jobs: %w", err)
+	}
+
+	return result.RowsAffected()
+}
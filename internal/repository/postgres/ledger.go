@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type LedgerRepository struct {
+	db DBTX
+}
+
+func NewLedgerRepository(db DBTX) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// CreateEntry writes a journal entry and its postings, opening any ledger
+// accounts that do not exist yet
+func (r *LedgerRepository) CreateEntry(ctx context.Context, entry *domain.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	return withTx(ctx, r.db, func(tx DBTX) error {
+		entryQuery := `
+			INSERT INTO journal_entries (id, transaction_id, description, created_at)
+			VALUES ($1, $2, $3, $4)`
+
+		_, err := tx.ExecContext(ctx, entryQuery,
+			entry.ID,
+			entry.TransactionID,
+			entry.Description,
+			entry.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create journal entry: %w", err)
+		}
+
+		postingQuery := `
+			INSERT INTO postings (id, journal_entry_id, account_id, currency, amount, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+
+		for _, posting := range entry.Postings {
+			accountID, err := r.ensureAccount(ctx, tx, posting.Account())
+			if err != nil {
+				return err
+			}
+			posting.AccountID = accountID
+
+			_, err = tx.ExecContext(ctx, postingQuery,
+				posting.ID,
+				posting.JournalEntryID,
+				posting.AccountID,
+				posting.Currency,
+				posting.Amount,
+				posting.CreatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create posting: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// ensureAccount returns the ID of the account with the given code, creating it
+// if needed. An existing account row is only read, not locked, so postings to
+// the shared external and FX accounts do not serialize on it.
+func (r *LedgerRepository) ensureAccount(ctx context.Context, tx DBTX, account *domain.LedgerAccount) (uuid.UUID, error) {
+	query := `
+		INSERT INTO ledger_accounts (id, code, type, user_id, currency, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING id`
+
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, query,
+		account.ID,
+		account.Code,
+		account.Type,
+		account.UserID,
+		account.Currency,
+		account.CreatedAt,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx, `SELECT id FROM ledger_accounts WHERE code = $1`, account.Code).Scan(&id)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to open ledger account %s: %w", account.Code, err)
+	}
+
+	return id, nil
+}
+
+// GetEntriesByTransactionID returns the journal entries recorded for a transaction
+func (r *LedgerRepository) GetEntriesByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]*domain.JournalEntry, error) {
+	query := `
+		SELECT e.id, e.transaction_id, e.description, e.created_at,
+			p.id, p.account_id, a.type, a.user_id, p.currency, p.amount, p.created_at
+		FROM journal_entries e
+		JOIN postings p ON p.journal_entry_id = e.id
+		JOIN ledger_accounts a ON a.id = p.account_id
+		WHERE e.transaction_id = $1
+		ORDER BY e.created_at ASC, e.id, p.amount ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*domain.JournalEntry
+	var current *domain.JournalEntry
+	for rows.Next() {
+		entry := &domain.JournalEntry{}
+		posting := &domain.Posting{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TransactionID,
+			&entry.Description,
+			&entry.CreatedAt,
+			&posting.ID,
+			&posting.AccountID,
+			&posting.AccountType,
+			&posting.UserID,
+			&posting.Currency,
+			&posting.Amount,
+			&posting.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+
+		if current == nil || current.ID != entry.ID {
+			current = entry
+			entries = append(entries, current)
+		}
+		posting.JournalEntryID = current.ID
+		current.Postings = append(current.Postings, posting)
+	}
+
+	return entries, nil
+}
+
+// GetUserBalances sums the postings of user accounts. A nil userID returns every user.
+func (r *LedgerRepository) GetUserBalances(ctx context.Context, userID *uuid.UUID) ([]*domain.LedgerBalance, error) {
+	query := `
+		SELECT a.user_id, a.currency, COALESCE(SUM(p.amount), 0)
+		FROM ledger_accounts a
+		LEFT JOIN postings p ON p.account_id = a.id
+		WHERE a.type = 'user' AND ($1::uuid IS NULL OR a.user_id = $1)
+		GROUP BY a.user_id, a.currency
+		ORDER BY a.user_id, a.currency`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*domain.LedgerBalance
+	for rows.Next() {
+		balance := &domain.LedgerBalance{}
+		if err := rows.Scan(&balance.UserID, &balance.Currency, &balance.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger balance: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+// RebuildBalances overwrites the balances projection with the sums of the
+// journal. A nil userID rebuilds every user. It returns the number of
+// balances that changed, or repository.ErrHeldExceedsBalance without changing
+// any when a rebuilt amount would fall below the amount held in its wallet.
+func (r *LedgerRepository) RebuildBalances(ctx context.Context, userID *uuid.UUID) (int64, error) {
+	var changed int64
+
+	err := withTx(ctx, r.db, func(tx DBTX) error {
+		// Block balance writers while the projection is recomputed
+		lockQuery := `SELECT 1 FROM balances WHERE ($1::uuid IS NULL OR user_id = $1) FOR UPDATE`
+		if _, err := tx.ExecContext(ctx, lockQuery, userID); err != nil {
+			return fmt.Errorf("failed to lock balances: %w", err)
+		}
+
+		// Funds on hold must stay covered, so a journal that no longer covers
+		// them needs a look before the projection follows it
+		heldQuery := `
+			SELECT b.user_id, b.currency, b.held_amount, COALESCE(SUM(p.amount), 0)
+			FROM balances b
+			LEFT JOIN ledger_accounts a ON a.type = 'user' AND a.user_id = b.user_id AND a.currency = b.currency
+			LEFT JOIN postings p ON p.account_id = a.id
+			WHERE b.held_amount > 0 AND ($1::uuid IS NULL OR b.user_id = $1)
+			GROUP BY b.user_id, b.currency, b.held_amount
+			HAVING COALESCE(SUM(p.amount), 0) < b.held_amount`
+
+		rows, err := tx.QueryContext(ctx, heldQuery, userID)
+		if err != nil {
+			return fmt.Errorf("failed to check held amounts: %w", err)
+		}
+		defer rows.Close()
+
+		var uncovered []string
+		for rows.Next() {
+			var (
+				walletUserID uuid.UUID
+				currency     domain.Currency
+				held, amount domain.Money
+			)
+			if err := rows.Scan(&walletUserID, &currency, &held, &amount); err != nil {
+				return fmt.Errorf("failed to scan held amount: %w", err)
+			}
+			uncovered = append(uncovered, fmt.Sprintf("%s %s (%s held, %s rebuilt)", walletUserID, currency, held, amount))
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to check held amounts: %w", err)
+		}
+		if len(uncovered) > 0 {
+			return fmt.Errorf("%w: %s", repository.ErrHeldExceedsBalance, strings.Join(uncovered, ", "))
+		}
+
+		// New wallets start the version lineage of domain.NewBalance; changed
+		// ones move it on, so in-flight optimistic updates see the rebuild
+		upsertQuery := `
+			INSERT INTO balances (user_id, currency, amount, held_amount, last_updated_at, version)
+			SELECT a.user_id, a.currency, COALESCE(SUM(p.amount), 0), 0, NOW(), 1
+			FROM ledger_accounts a
+			LEFT JOIN postings p ON p.account_id = a.id
+			WHERE a.type = 'user' AND ($1::uuid IS NULL OR a.user_id = $1)
+			GROUP BY a.user_id, a.currency
+			ON CONFLICT (user_id, currency) DO UPDATE
+			SET amount = EXCLUDED.amount, last_updated_at = NOW(), version = balances.version + 1
+			WHERE balances.amount <> EXCLUDED.amount`
+
+		result, err := tx.ExecContext(ctx, upsertQuery, userID)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild balances: %w", err)
+		}
+		upserted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		// Wallets without any ledger account have never been posted to
+		resetQuery := `
+			UPDATE balances b
+			SET amount = 0, last_updated_at = NOW(), version = b.version + 1
+			WHERE b.amount <> 0 AND ($1::uuid IS NULL OR b.user_id = $1)
+			AND NOT EXISTS (
+				SELECT 1 FROM ledger_accounts a
+				WHERE a.type = 'user' AND a.user_id = b.user_id AND a.currency = b.currency
+			)`
+
+		result, err = tx.ExecContext(ctx, resetQuery, userID)
+		if err != nil {
+			return fmt.Errorf("failed to reset balances: %w", err)
+		}
+		reset, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		changed = upserted + reset
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,219 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"insider-backend/internal/event"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxRepository struct {
+	db DBTX
+}
+
+func NewOutboxRepository(db DBTX) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Append assigns evt the next version of its aggregate's stream, saves it to
+// the events table and queues it in the outbox. Must run inside a unit of work.
+func (r *OutboxRepository) Append(ctx context.Context, evt *event.Event) error {
+	return r.AppendEvents(ctx, evt.AggregateID, event.AnyVersion, evt)
+}
+
+// AppendEvents saves events to an aggregate's stream, numbered from
+// expectedVersion+1, and queues them in the outbox. It returns an
+// event.ConcurrencyConflictError if the stream is no longer at
+// expectedVersion. An advisory lock on the aggregate serializes concurrent
+// appends until the enclosing database transaction ends, so it must run
+// inside a unit of work.
+func (r *OutboxRepository) AppendEvents(ctx context.Context, aggregateID uuid.UUID, expectedVersion int, events ...*event.Event) error {
+	if _, err := r.db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, aggregateID.String()); err != nil {
+		return fmt.Errorf("failed to lock event stream: %w", err)
+	}
+
+	version, err := r.LastVersion(ctx, aggregateID)
+	if err != nil {
+		return err
+	}
+	if expectedVersion != event.AnyVersion && version != expectedVersion {
+		return &event.ConcurrencyConflictError{AggregateID: aggregateID, ExpectedVersion: expectedVersion, ActualVersion: version}
+	}
+
+	for i, evt := range events {
+		if evt.AggregateID != aggregateID {
+			return fmt.Errorf("event %s belongs to aggregate %s, not %s", evt.ID, evt.AggregateID, aggregateID)
+		}
+		evt.Version = version + i + 1
+
+		if err := r.insert(ctx, evt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LastVersion returns the version of the last event of an aggregate, or zero
+// if it has none
+func (r *OutboxRepository) LastVersion(ctx context.Context, aggregateID uuid.UUID) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM events WHERE aggregate_id = $1`, aggregateID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last event version: %w", err)
+	}
+	return version, nil
+}
+
+func (r *OutboxRepository) insert(ctx context.Context, evt *event.Event) error {
+	metadataJSON, err := json.Marshal(evt.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	query := `
+		INSERT INTO events (id, type, aggregate_id, data, metadata, version, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		evt.ID,
+		evt.Type,
+		evt.AggregateID,
+		evt.Data,
+		metadataJSON,
+		evt.Version,
+		evt.CreatedAt,
+	)
+	if err != nil {
+		if event.IsVersionConflict(err) {
+			return &event.ConcurrencyConflictError{AggregateID: evt.AggregateID, ExpectedVersion: evt.Version - 1, ActualVersion: event.AnyVersion}
+		}
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+
+	query = `
+		INSERT INTO outbox (event_id, aggregate_id, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $4)`
+
+	_, err = r.db.ExecContext(ctx, query, evt.ID, evt.AggregateID, event.OutboxStatusPending, evt.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to queue event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPending locks up to limit pending messages due at or before now, in
+// the order they were queued. Only the oldest pending message of an aggregate
+// is claimable, so its events are delivered in version order and a message
+// waiting for a retry holds back the ones after it. Rows locked by another
+// relay are skipped. Must run inside a unit of work.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, now time.Time, limit int) ([]*event.OutboxMessage, error) {
+	query := `
+		SELECT o.id, o.status, o.attempts, COALESCE(o.last_error, ''), o.next_attempt_at, o.created_at, o.delivered_at,
+			e.id, e.type, e.aggregate_id, e.data, e.metadata, e.version, e.created_at
+		FROM outbox o
+		JOIN events e ON e.id = o.event_id
+		WHERE o.status = 'pending' AND o.next_attempt_at <= $1
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox earlier
+				WHERE earlier.aggregate_id = o.aggregate_id AND earlier.status = 'pending' AND earlier.id < o.id
+			)
+		ORDER BY o.id ASC
+		LIMIT $2
+		FOR UPDATE OF o SKIP LOCKED`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*event.OutboxMessage
+	for rows.Next() {
+		message := &event.OutboxMessage{Event: &event.Event{}}
+		var metadataJSON []byte
+
+		err := rows.Scan(
+			&message.ID,
+			&message.Status,
+			&message.Attempts,
+			&message.LastError,
+			&message.NextAttemptAt,
+			&message.CreatedAt,
+			&message.DeliveredAt,
+			&message.Event.ID,
+			&message.Event.Type,
+			&message.Event.AggregateID,
+			&message.Event.Data,
+			&metadataJSON,
+			&message.Event.Version,
+			&message.Event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &message.Event.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *OutboxRepository) Update(ctx context.Context, message *event.OutboxMessage) error {
+	query := `
+		UPDATE outbox
+		SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5, delivered_at = $6
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		message.ID,
+		message.Status,
+		message.Attempts,
+		sql.NullString{String: message.LastError, Valid: message.LastError != ""},
+		message.NextAttemptAt,
+		message.DeliveredAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("outbox message not found")
+	}
+
+	return nil
+}
+
+// DeleteDelivered prunes messages delivered before the given time. The
+// events themselves are kept.
+func (r *OutboxRepository) DeleteDelivered(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox WHERE status = 'delivered' AND delivered_at < $1`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered outbox messages: %w", err)
+	}
+
+	return result.RowsAffected()
+}
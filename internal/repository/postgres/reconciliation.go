@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+type ReconciliationRepository struct {
+	db DBTX
+}
+
+func NewReconciliationRepository(db DBTX) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db}
+}
+
+// GetWalletStates compares every wallet's stored balance with the amount
+// recomputed from completed transactions and with its latest balance history.
+// A nil userID returns every user.
+func (r *ReconciliationRepository) GetWalletStates(ctx context.Context, userID *uuid.UUID) ([]*domain.WalletState, error) {
+	query := `
+		WITH movements AS (
+			SELECT to_user_id AS user_id, currency, amount
+			FROM transactions WHERE status IN ('completed', 'partially_reversed', 'reversed') AND type = 'credit'
+			UNION ALL
+			SELECT from_user_id, currency, -amount
+			FROM transactions WHERE status IN ('completed', 'partially_reversed', 'reversed') AND type IN ('debit', 'transfer')
+			UNION ALL
+			SELECT to_user_id, COALESCE(to_currency, currency), COALESCE(to_amount, amount)
+			FROM transactions WHERE status IN ('completed', 'partially_reversed', 'reversed') AND type = 'transfer'
+		), transaction_totals AS (
+			SELECT user_id, currency, SUM(amount) AS amount
+			FROM movements
+			WHERE $1::uuid IS NULL OR user_id = $1
+			GROUP BY user_id, currency
+		), latest_history AS (
+			SELECT DISTINCT ON (user_id, currency) user_id, currency, amount
+			FROM balance_history
+			WHERE $1::uuid IS NULL OR user_id = $1
+			ORDER BY user_id, currency, created_at DESC
+		), wallets AS (
+			SELECT user_id, currency FROM balances WHERE $1::uuid IS NULL OR user_id = $1
+			UNION
+			SELECT user_id, currency FROM transaction_totals
+		)
+		SELECT w.user_id, w.currency, COALESCE(b.amount, 0), COALESCE(t.amount, 0), h.amount
+		FROM wallets w
+		LEFT JOIN balances b ON b.user_id = w.user_id AND b.currency = w.currency
+		LEFT JOIN transaction_totals t ON t.user_id = w.user_id AND t.currency = w.currency
+		LEFT JOIN latest_history h ON h.user_id = w.user_id AND h.currency = w.currency
+		ORDER BY w.user_id, w.currency`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet states: %w", err)
+	}
+	defer rows.Close()
+
+	var states []*domain.WalletState
+	for rows.Next() {
+		state := &domain.WalletState{}
+		err := rows.Scan(
+			&state.UserID,
+			&state.Currency,
+			&state.BalanceAmount,
+			&state.TransactionAmount,
+			&state.HistoryAmount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet state: %w", err)
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// GetSuspectTransactions returns the transactions of a wallet whose balance
+// updates look incomplete: completed without a history row, recorded in
+// history without completing, or breaking the history chain.
+func (r *ReconciliationRepository) GetSuspectTransactions(ctx context.Context, userID uuid.UUID, currency domain.Currency) ([]uuid.UUID, error) {
+	query := `
+		SELECT t.id FROM transactions t
+		WHERE t.id IN (
+			SELECT t.id FROM transactions t
+			WHERE t.status IN ('completed', 'partially_reversed', 'reversed')
+			AND (
+				(t.to_user_id = $1 AND COALESCE(t.to_currency, t.currency) = $2 AND t.type IN ('credit', 'transfer'))
+				OR (t.from_user_id = $1 AND t.currency = $2 AND t.type IN ('debit', 'transfer'))
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM balance_history h
+				WHERE h.transaction_id = t.id AND h.user_id = $1 AND h.currency = $2
+			)
+			UNION
+			SELECT h.transaction_id FROM balance_history h
+			JOIN transactions t ON t.id = h.transaction_id
+			WHERE h.user_id = $1 AND h.currency = $2 AND t.status NOT IN ('completed', 'partially_reversed', 'reversed')
+			UNION
+			SELECT h.transaction_id FROM (
+				SELECT transaction_id, previous_amount, LAG(amount) OVER (ORDER BY created_at) AS prior_amount
+				FROM balance_history
+				WHERE user_id = $1 AND currency = $2
+			) h
+			WHERE h.prior_amount IS NOT NULL AND h.prior_amount <> h.previous_amount
+		)
+		ORDER BY t.created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suspect transactions: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// scheduleColumns lists the columns read and written for a schedule, in scan order
+const scheduleColumns = `id, user_id, from_user_id, to_user_id, amount, currency, type, description,
+		recurrence, cron_expr, start_at, end_at, max_runs, next_run_at, run_count, status,
+		last_run_at, last_transaction_id, created_at, updated_at`
+
+func scanSchedule(row rowScanner) (*domain.Schedule, error) {
+	schedule := &domain.Schedule{}
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.UserID,
+		&schedule.FromUserID,
+		&schedule.ToUserID,
+		&schedule.Amount,
+		&schedule.Currency,
+		&schedule.Type,
+		&schedule.Description,
+		&schedule.Recurrence,
+		&schedule.CronExpr,
+		&schedule.StartAt,
+		&schedule.EndAt,
+		&schedule.MaxRuns,
+		&schedule.NextRunAt,
+		&schedule.RunCount,
+		&schedule.Status,
+		&schedule.LastRunAt,
+		&schedule.LastTransactionID,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+type ScheduleRepository struct {
+	db DBTX
+}
+
+func NewScheduleRepository(db DBTX) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+func (r *ScheduleRepository) Create(ctx context.Context, schedule *domain.Schedule) error {
+	query := `
+		INSERT INTO scheduled_transactions (` + scheduleColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		schedule.ID,
+		schedule.UserID,
+		schedule.FromUserID,
+		schedule.ToUserID,
+		schedule.Amount,
+		schedule.Currency,
+		schedule.Type,
+		schedule.Description,
+		schedule.Recurrence,
+		schedule.CronExpr,
+		schedule.StartAt,
+		schedule.EndAt,
+		schedule.MaxRuns,
+		schedule.NextRunAt,
+		schedule.RunCount,
+		schedule.Status,
+		schedule.LastRunAt,
+		schedule.LastTransactionID,
+		schedule.CreatedAt,
+		schedule.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ScheduleRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM scheduled_transactions WHERE id = $1`
+
+	schedule, err := scanSchedule(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("schedule not found")
+		}
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// GetByIDForUpdate reads a schedule and locks its row until the enclosing
+// database transaction ends. Only meaningful inside a unit of work.
+func (r *ScheduleRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM scheduled_transactions WHERE id = $1 FOR UPDATE`
+
+	schedule, err := scanSchedule(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("schedule not found")
+		}
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+func (r *ScheduleRepository) Update(ctx context.Context, schedule *domain.Schedule) error {
+	query := `
+		UPDATE scheduled_transactions
+		SET next_run_at = $2, run_count = $3, status = $4, last_run_at = $5, last_transaction_id = $6, updated_at = $7
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		schedule.ID,
+		schedule.NextRunAt,
+		schedule.RunCount,
+		schedule.Status,
+		schedule.LastRunAt,
+		schedule.LastTransactionID,
+		schedule.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("schedule not found")
+	}
+
+	return nil
+}
+
+func (r *ScheduleRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM scheduled_transactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*domain.Schedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+// ClaimDue locks up to limit active schedules due at or before now. Rows
+// locked by another instance are skipped, so concurrent schedulers never claim
+// the same schedule. Must run inside a unit of work, the locks are held until
+// it ends.
+func (r *ScheduleRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]*domain.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM scheduled_transactions
+		WHERE status = 'active' AND next_run_at <= $1
+		ORDER BY next_run_at ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*domain.Schedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim due schedules: %w", err)
+	}
+
+	return schedules, nil
+}
@@ -5,34 +5,85 @@ import (
 	"database/sql"
 	"fmt"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
 	"strings"
 
 	"github.com/google/uuid"
 )
 
+// transactionColumns lists the columns read and written for a transaction, in scan order
+const transactionColumns = `id, from_user_id, to_user_id, amount, currency, type, status, description, reference_id, created_at,
+		to_currency, to_amount, fx_rate, fx_spread, fx_rate_at, fx_rate_source, reverses_transaction_id, reversed_amount, hold_id, schedule_id`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (*domain.Transaction, error) {
+	transaction := &domain.Transaction{}
+	err := row.Scan(
+		&transaction.ID,
+		&transaction.FromUserID,
+		&transaction.ToUserID,
+		&transaction.Amount,
+		&transaction.Currency,
+		&transaction.Type,
+		&transaction.Status,
+		&transaction.Description,
+		&transaction.ReferenceID,
+		&transaction.CreatedAt,
+		&transaction.ToCurrency,
+		&transaction.ToAmount,
+		&transaction.FXRate,
+		&transaction.FXSpread,
+		&transaction.FXRateAt,
+		&transaction.FXRateSource,
+		&transaction.ReversesTransactionID,
+		&transaction.ReversedAmount,
+		&transaction.HoldID,
+		&transaction.ScheduleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
 type TransactionRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewTransactionRepository(db *sql.DB) *TransactionRepository {
+func NewTransactionRepository(db DBTX) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
 func (r *TransactionRepository) Create(ctx context.Context, transaction *domain.Transaction) error {
 	query := `
-		INSERT INTO transactions (id, from_user_id, to_user_id, amount, type, status, description, reference_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+		INSERT INTO transactions (` + transactionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
 
 	_, err := r.db.ExecContext(ctx, query,
 		transaction.ID,
 		transaction.FromUserID,
 		transaction.ToUserID,
 		transaction.Amount,
+		transaction.Currency,
 		transaction.Type,
 		transaction.Status,
 		transaction.Description,
 		transaction.ReferenceID,
 		transaction.CreatedAt,
+		transaction.ToCurrency,
+		transaction.ToAmount,
+		transaction.FXRate,
+		transaction.FXSpread,
+		transaction.FXRateAt,
+		transaction.FXRateSource,
+		transaction.ReversesTransactionID,
+		transaction.ReversedAmount,
+		transaction.HoldID,
+		transaction.ScheduleID,
 	)
 
 	if err != nil {
@@ -44,25 +95,33 @@ func (r *TransactionRepository) Create(ctx context.Context, transaction *domain.
 
 func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
 	query := `
-		SELECT id, from_user_id, to_user_id, amount, type, status, description, reference_id, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions WHERE id = $1`
 
-	transaction := &domain.Transaction{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&transaction.ID,
-		&transaction.FromUserID,
-		&transaction.ToUserID,
-		&transaction.Amount,
-		&transaction.Type,
-		&transaction.Status,
-		&transaction.Description,
-		&transaction.ReferenceID,
-		&transaction.CreatedAt,
-	)
+	transaction, err := scanTransaction(r.db.QueryRowContext(ctx, query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found")
+			return nil, repository.ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// GetByIDForUpdate reads a transaction and locks its row until the enclosing
+// database transaction ends. Only meaningful inside a unit of work.
+func (r *TransactionRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions WHERE id = $1 FOR UPDATE`
+
+	transaction, err := scanTransaction(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrTransactionNotFound
 		}
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
@@ -73,13 +132,14 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*dom
 func (r *TransactionRepository) Update(ctx context.Context, transaction *domain.Transaction) error {
 	query := `
 		UPDATE transactions 
-		SET status = $2, description = $3
+		SET status = $2, description = $3, reversed_amount = $4
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query,
 		transaction.ID,
 		transaction.Status,
 		transaction.Description,
+		transaction.ReversedAmount,
 	)
 
 	if err != nil {
@@ -92,14 +152,14 @@ func (r *TransactionRepository) Update(ctx context.Context, transaction *domain.
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("transaction not found")
+		return repository.ErrTransactionNotFound
 	}
 
 	return nil
 }
 
 func (r *TransactionRepository) List(ctx context.Context, filter domain.TransactionFilter) ([]*domain.Transaction, error) {
-	query := `SELECT id, from_user_id, to_user_id, amount, type, status, description, reference_id, created_at FROM transactions`
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
 
 	var conditions []string
 	var args []interface{}
@@ -160,18 +220,7 @@ func (r *TransactionRepository) List(ctx context.Context, filter domain.Transact
 
 	var transactions []*domain.Transaction
 	for rows.Next() {
-		transaction := &domain.Transaction{}
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.FromUserID,
-			&transaction.ToUserID,
-			&transaction.Amount,
-			&transaction.Type,
-			&transaction.Status,
-			&transaction.Description,
-			&transaction.ReferenceID,
-			&transaction.CreatedAt,
-		)
+		transaction, err := scanTransaction(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -183,7 +232,7 @@ func (r *TransactionRepository) List(ctx context.Context, filter domain.Transact
 
 func (r *TransactionRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Transaction, error) {
 	query := `
-		SELECT id, from_user_id, to_user_id, amount, type, status, description, reference_id, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE from_user_id = $1 OR to_user_id = $1
 		ORDER BY created_at DESC
@@ -197,18 +246,7 @@ func (r *TransactionRepository) GetByUserID(ctx context.Context, userID uuid.UUI
 
 	var transactions []*domain.Transaction
 	for rows.Next() {
-		transaction := &domain.Transaction{}
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.FromUserID,
-			&transaction.ToUserID,
-			&transaction.Amount,
-			&transaction.Type,
-			&transaction.Status,
-			&transaction.Description,
-			&transaction.ReferenceID,
-			&transaction.CreatedAt,
-		)
+		transaction, err := scanTransaction(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -220,25 +258,14 @@ func (r *TransactionRepository) GetByUserID(ctx context.Context, userID uuid.UUI
 
 func (r *TransactionRepository) GetByReferenceID(ctx context.Context, referenceID string) (*domain.Transaction, error) {
 	query := `
-		SELECT id, from_user_id, to_user_id, amount, type, status, description, reference_id, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions WHERE reference_id = $1`
 
-	transaction := &domain.Transaction{}
-	err := r.db.QueryRowContext(ctx, query, referenceID).Scan(
-		&transaction.ID,
-		&transaction.FromUserID,
-		&transaction.ToUserID,
-		&transaction.Amount,
-		&transaction.Type,
-		&transaction.Status,
-		&transaction.Description,
-		&transaction.ReferenceID,
-		&transaction.CreatedAt,
-	)
+	transaction, err := scanTransaction(r.db.QueryRowContext(ctx, query, referenceID))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found")
+			return nil, repository.ErrTransactionNotFound
 		}
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
@@ -260,15 +287,30 @@ func (r *TransactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID,
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("transaction not found")
+		return repository.ErrTransactionNotFound
 	}
 
 	return nil
 }
 
+// GetPendingReversalAmount sums the reversals of a transaction that have not been processed yet
+func (r *TransactionRepository) GetPendingReversalAmount(ctx context.Context, id uuid.UUID) (domain.Money, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE reverses_transaction_id = $1 AND status = 'pending'`
+
+	var amount domain.Money
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&amount); err != nil {
+		return 0, fmt.Errorf("failed to get pending reversal amount: %w", err)
+	}
+
+	return amount, nil
+}
+
 func (r *TransactionRepository) ListPending(ctx context.Context, limit int) ([]*domain.Transaction, error) {
 	query := `
-		SELECT id, from_user_id, to_user_id, amount, type, status, description, reference_id, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE status = 'pending'
 		ORDER BY created_at ASC
@@ -282,18 +324,7 @@ func (r *TransactionRepository) ListPending(ctx context.Context, limit int) ([]*
 
 	var transactions []*domain.Transaction
 	for rows.Next() {
-		transaction := &domain.Transaction{}
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.FromUserID,
-			&transaction.ToUserID,
-			&transaction.Amount,
-			&transaction.Type,
-			&transaction.Status,
-			&transaction.Description,
-			&transaction.ReferenceID,
-			&transaction.CreatedAt,
-		)
+		transaction, err := scanTransaction(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/repository"
+)
+
+// UnitOfWork runs a set of repository calls in a single Postgres transaction
+type UnitOfWork struct {
+	db    *sql.DB
+	cache repository.CacheRepository
+}
+
+func NewUnitOfWork(db *sql.DB, cache repository.CacheRepository) *UnitOfWork {
+	return &UnitOfWork{db: db, cache: cache}
+}
+
+// Do begins a transaction and hands fn a set of repositories bound to it.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+// The cache is not transactional and is shared with the caller.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(repos *repository.Repositories) error) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	repos := &repository.Repositories{
+		User:            NewUserRepository(tx),
+		Transaction:     NewTransactionRepository(tx),
+		Balance:         NewBalanceRepository(tx),
+		AuditLog:        NewAuditLogRepository(tx),
+		Ledger:          NewLedgerRepository(tx),
+		Reconciliation:  NewReconciliationRepository(tx),
+		Idempotency:     NewIdempotencyRepository(tx),
+		Hold:            NewHoldRepository(tx),
+		Schedule:        NewScheduleRepository(tx),
+		Outbox:          NewOutboxRepository(tx),
+		Webhook:         NewWebhookRepository(tx),
+		WebhookDelivery: NewWebhookDeliveryRepository(tx),
+		Job:             NewJobRepository(tx),
+		Cache:           u.cache,
+	}
+
+	if err := fn(repos); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,493 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-backend/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// webhookColumns lists the columns read and written for a subscription, in scan order
+const webhookColumns = `id, url, secret, event_types, description, active, created_at, updated_at`
+
+func scanWebhook(row rowScanner) (*domain.WebhookSubscription, error) {
+	subscription := &domain.WebhookSubscription{}
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		&subscription.Secret,
+		pq.Array(&subscription.EventTypes),
+		&subscription.Description,
+		&subscription.Active,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+type WebhookRepository struct {
+	db DBTX
+}
+
+func NewWebhookRepository(db DBTX) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (` + webhookColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		subscription.ID,
+		subscription.URL,
+		subscription.Secret,
+		pq.Array(subscription.EventTypes),
+		subscription.Description,
+		subscription.Active,
+		subscription.CreatedAt,
+		subscription.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions WHERE id = $1`
+
+	subscription, err := scanWebhook(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook subscription not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, description = $4, active = $5, updated_at = $6
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		subscription.ID,
+		subscription.URL,
+		pq.Array(subscription.EventTypes),
+		subscription.Description,
+		subscription.Active,
+		subscription.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook subscription not found")
+	}
+
+	return nil
+}
+
+// Delete removes a subscription together with its deliveries
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook subscription not found")
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) List(ctx context.Context, limit, offset int) ([]*domain.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	return r.query(ctx, query, limit, offset)
+}
+
+// GetActiveByEventType returns the active subscriptions to an event type
+func (r *WebhookRepository) GetActiveByEventType(ctx context.Context, eventType string) ([]*domain.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE active AND event_types @> ARRAY[$1]::TEXT[]`
+
+	return r.query(ctx, query, eventType)
+}
+
+func (r *WebhookRepository) query(ctx context.Context, query string, args ...interface{}) ([]*domain.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []*domain.WebhookSubscription
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+// webhookDeliveryColumns lists the columns read and written for a delivery, in scan order
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+		last_error, last_response_status, next_attempt_at, created_at, updated_at, delivered_at`
+
+func scanWebhookDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	delivery := &domain.WebhookDelivery{}
+	var lastError sql.NullString
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&lastError,
+		&delivery.LastResponseStatus,
+		&delivery.NextAttemptAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+		&delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.LastError = lastError.String
+	return delivery, nil
+}
+
+type WebhookDeliveryRepository struct {
+	db DBTX
+}
+
+func NewWebhookDeliveryRepository(db DBTX) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+// Create saves a delivery unless the subscription already has one for the
+// event, in which case it returns false
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error) {
+	query := `
+		INSERT INTO webhook_deliveries (` + webhookDeliveryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query,
+		delivery.ID,
+		delivery.SubscriptionID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+		delivery.Attempts,
+		sql.NullString{String: delivery.LastError, Valid: delivery.LastError != ""},
+		delivery.LastResponseStatus,
+		delivery.NextAttemptAt,
+		delivery.CreatedAt,
+		delivery.UpdatedAt,
+		delivery.DeliveredAt,
+	)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *WebhookDeliveryRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries WHERE id = $1`
+
+	return r.get(ctx, query, id)
+}
+
+// GetByIDForUpdate reads a delivery and locks its row until the enclosing
+// database transaction ends. Only meaningful inside a unit of work.
+func (r *WebhookDeliveryRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries WHERE id = $1 FOR UPDATE`
+
+	return r.get(ctx, query, id)
+}
+
+func (r *WebhookDeliveryRepository) get(ctx context.Context, query string, id uuid.UUID) (*domain.WebhookDelivery, error) {
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_error = $4, last_response_status = $5,
+			next_attempt_at = $6, updated_at = $7, delivered_at = $8
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		sql.NullString{String: delivery.LastError, Valid: delivery.LastError != ""},
+		delivery.LastResponseStatus,
+		delivery.NextAttemptAt,
+		delivery.UpdatedAt,
+		delivery.DeliveredAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook delivery not found")
+	}
+
+	return nil
+}
+
+// GetBySubscriptionID lists the deliveries of a subscription, newest first.
+// An empty status lists all of them.
+func (r *WebhookDeliveryRepository) GetBySubscriptionID(ctx context.Context, subscriptionID uuid.UUID, status string, limit, offset int) ([]*domain.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	return r.query(ctx, query, subscriptionID, status, limit, offset)
+}
+
+// ClaimDue leases up to limit pending deliveries due at or before now by
+// moving their next attempt to leaseUntil. A dispatcher that crashes while
+// sending leaves them to be retried once the lease ends. Rows locked by
+// another dispatcher are skipped.
+func (r *WebhookDeliveryRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	return r.query(ctx, query, now, leaseUntil, limit)
+}
+
+func (r *WebhookDeliveryRepository) query(ctx context.Context, query string, args ...interface{}) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*domain.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookDeliveryRepository) CreateAttempt(ctx context.Context, attempt *domain.WebhookAttempt) error {
+	query := `
+		INSERT INTO webhook_attempts (id, delivery_id, response_status, error, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		attempt.ID,
+		attempt.DeliveryID,
+		attempt.ResponseStatus,
+		sql.NullString{String: attempt.Error, Valid: attempt.Error != ""},
+		attempt.DurationMs,
+		attempt.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create webhook attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetAttempts lists the attempts of a delivery in the order they were made
+func (r *WebhookDeliveryRepository) GetAttempts(ctx context.Context, deliveryID uuid.UUID) ([]*domain.WebhookAttempt, error) {
+	query := `
+		SELECT id, delivery_id, response_status, COALESCE(error, ''), duration_ms, created_at
+		FROM webhook_attempts
+		WHERE delivery_id = $1
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*domain.WebhookAttempt
+	for rows.Next() {
+		attempt := &domain.WebhookAttempt{}
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.DeliveryID,
+			&attempt.ResponseStatus,
+			&attempt.Error,
+			&attempt.DurationMs,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+func (r *WebhookDeliveryRepository) CreateDeadLetter(ctx context.Context, deadLetter *domain.WebhookDeadLetter) error {
+	query := `
+		INSERT INTO webhook_dead_letters (id, delivery_id, subscription_id, event_id, event_type, payload, attempts, last_error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		deadLetter.ID,
+		deadLetter.DeliveryID,
+		deadLetter.SubscriptionID,
+		deadLetter.EventID,
+		deadLetter.EventType,
+		deadLetter.Payload,
+		deadLetter.Attempts,
+		deadLetter.LastError,
+		deadLetter.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create webhook dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeadLetters lists the dead letters that have not been replayed, newest first
+func (r *WebhookDeliveryRepository) ListDeadLetters(ctx context.Context, limit, offset int) ([]*domain.WebhookDeadLetter, error) {
+	query := `
+		SELECT id, delivery_id, subscription_id, event_id, event_type, payload, attempts, last_error, created_at, replayed_at
+		FROM webhook_dead_letters
+		WHERE replayed_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []*domain.WebhookDeadLetter
+	for rows.Next() {
+		deadLetter := &domain.WebhookDeadLetter{}
+		err := rows.Scan(
+			&deadLetter.ID,
+			&deadLetter.DeliveryID,
+			&deadLetter.SubscriptionID,
+			&deadLetter.EventID,
+			&deadLetter.EventType,
+			&deadLetter.Payload,
+			&deadLetter.Attempts,
+			&deadLetter.LastError,
+			&deadLetter.CreatedAt,
+			&deadLetter.ReplayedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook dead letter: %w", err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	return deadLetters, nil
+}
+
+// MarkDeadLettersReplayed records that the dead letters of a delivery were replayed
+func (r *WebhookDeliveryRepository) MarkDeadLettersReplayed(ctx context.Context, deliveryID uuid.UUID, replayedAt time.Time) error {
+	query := `UPDATE webhook_dead_letters SET replayed_at = $2 WHERE delivery_id = $1 AND replayed_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, deliveryID, replayedAt); err != nil {
+		return fmt.Errorf("failed to mark webhook dead letters replayed: %w", err)
+	}
+
+	return nil
+}
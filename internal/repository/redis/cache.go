@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"insider-backend/internal/repository"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -36,7 +37,7 @@ func (r *CacheRepository) Get(ctx context.Context, key string, dest interface{})
 	data, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return fmt.Errorf("key not found")
+			return repository.ErrCacheMiss
 		}
 		return fmt.Errorf("failed to get cache: %w", err)
 	}
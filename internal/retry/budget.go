@@ -0,0 +1,54 @@
+package retry
+
+import "sync"
+
+// Budget limits the retries of the operations sharing it to a share of their
+// calls, so that retries do not multiply the load on a dependency that is
+// already failing. Every call deposits ratio tokens and every retry withdraws
+// one; the balance never exceeds the capacity the budget starts with.
+type Budget struct {
+	ratio    float64
+	capacity float64
+
+	mutex  sync.Mutex
+	tokens float64
+}
+
+// NewBudget creates a budget allowing ratio retries per call on average, and
+// bursts of up to capacity retries
+func NewBudget(ratio float64, capacity int) *Budget {
+	return &Budget{
+		ratio:    ratio,
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+	}
+}
+
+// Available returns the number of retries the budget allows right now
+func (b *Budget) Available() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return int(b.tokens)
+}
+
+func (b *Budget) deposit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+func (b *Budget) withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,176 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy defaults
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 100 * time.Millisecond
+	defaultMaxDelay    = 5 * time.Second
+)
+
+// Policy retries an operation with exponential backoff and full jitter: the
+// wait before retry n is drawn uniformly from zero to BaseDelay doubled n-1
+// times, capped at MaxDelay.
+type Policy struct {
+	// MaxAttempts is the number of attempts including the first one
+	MaxAttempts int
+	// BaseDelay bounds the wait before the first retry
+	BaseDelay time.Duration
+	// MaxDelay bounds the wait before any retry
+	MaxDelay time.Duration
+	// Retryable reports whether an attempt that failed with err may be
+	// retried. Defaults to every error not marked Permanent.
+	Retryable func(err error) bool
+	// Budget, when set, is shared with other callers and limits their
+	// retries to a share of their calls
+	Budget *Budget
+	// OnAttempt is called after every attempt
+	OnAttempt func(attempt Attempt)
+}
+
+// Attempt describes one attempt of an operation
+type Attempt struct {
+	Number    int           `json:"number"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	// Delay before the next attempt, zero if there is none
+	Delay time.Duration `json:"delay,omitempty"`
+	// GaveUp says why a failed attempt was not retried
+	GaveUp string `json:"gave_up,omitempty"`
+}
+
+// Reasons for giving up on an operation
+const (
+	GaveUpNotRetryable     = "not_retryable"
+	GaveUpMaxAttempts      = "max_attempts"
+	GaveUpBudget           = "budget_exhausted"
+	GaveUpDeadline         = "deadline"
+	GaveUpContextCancelled = "context_cancelled"
+)
+
+// permanentError marks an error that is not worth retrying
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not worth retrying, whatever the policy
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Do runs op until it succeeds, fails with an error that may not be retried
+// or runs out of attempts, budget or time, and returns its last error. It
+// does not wait past the deadline of ctx: a retry that could not start before
+// it is not attempted.
+func (p Policy) Do(ctx context.Context, op func(ctx context.Context) error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	if p.Budget != nil {
+		p.Budget.deposit()
+	}
+
+	for number := 1; ; number++ {
+		attempt := Attempt{Number: number, StartedAt: time.Now()}
+		err := op(ctx)
+		attempt.Duration = time.Since(attempt.StartedAt)
+
+		if err == nil {
+			p.notify(attempt)
+			return nil
+		}
+		attempt.Error = err.Error()
+
+		switch {
+		case !p.IsRetryable(err):
+			attempt.GaveUp = GaveUpNotRetryable
+		case ctx.Err() != nil:
+			attempt.GaveUp = GaveUpContextCancelled
+		case number >= maxAttempts:
+			attempt.GaveUp = GaveUpMaxAttempts
+		default:
+			attempt.Delay = p.Delay(number)
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(attempt.Delay).After(deadline) {
+				attempt.Delay = 0
+				attempt.GaveUp = GaveUpDeadline
+			} else if p.Budget != nil && !p.Budget.withdraw() {
+				attempt.Delay = 0
+				attempt.GaveUp = GaveUpBudget
+			}
+		}
+		p.notify(attempt)
+
+		if attempt.GaveUp != "" {
+			return err
+		}
+
+		timer := time.NewTimer(attempt.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRetryable reports whether an attempt that failed with err may be retried
+func (p Policy) IsRetryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+	if p.Retryable == nil {
+		return true
+	}
+	return p.Retryable(err)
+}
+
+// Delay returns the jittered wait after the given attempt
+func (p Policy) Delay(attempt int) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	ceiling := base << uint(attempt-1)
+	if ceiling <= 0 || ceiling > maxDelay {
+		ceiling = maxDelay
+	}
+	return rand.N(ceiling + 1)
+}
+
+func (p Policy) notify(attempt Attempt) {
+	if p.OnAttempt != nil {
+		p.OnAttempt(attempt)
+	}
+}
@@ -0,0 +1,249 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func TestPolicyDelayBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		attempt int
+		ceiling time.Duration
+	}{
+		{"first retry", Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}, 1, 10 * time.Millisecond},
+		{"doubles", Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}, 3, 40 * time.Millisecond},
+		{"capped", Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}, 10, time.Second},
+		{"overflow capped", Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}, 80, time.Second},
+		{"defaults", Policy{}, 1, defaultBaseDelay},
+		{"default cap", Policy{}, 20, defaultMaxDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var largest time.Duration
+			for i := 0; i < 1000; i++ {
+				delay := tt.policy.Delay(tt.attempt)
+				if delay < 0 || delay > tt.ceiling {
+					t.Fatalf("Delay(%d) = %v, want within [0, %v]", tt.attempt, delay, tt.ceiling)
+				}
+				largest = max(largest, delay)
+			}
+			// Full jitter spreads the delays over the whole range
+			if largest < tt.ceiling/2 {
+				t.Errorf("largest of 1000 delays = %v, want above %v", largest, tt.ceiling/2)
+			}
+		})
+	}
+}
+
+func TestPolicyDo(t *testing.T) {
+	permanent := Permanent(errTransient)
+
+	tests := []struct {
+		name         string
+		policy       Policy
+		errs         []error
+		wantAttempts int
+		wantErr      error
+		wantGaveUp   string
+	}{
+		{
+			name:         "succeeds first time",
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name:         "succeeds after retries",
+			policy:       Policy{MaxAttempts: 3},
+			errs:         []error{errTransient, errTransient, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "max attempts",
+			policy:       Policy{MaxAttempts: 4},
+			errs:         []error{errTransient},
+			wantAttempts: 4,
+			wantErr:      errTransient,
+			wantGaveUp:   GaveUpMaxAttempts,
+		},
+		{
+			name:         "default max attempts",
+			errs:         []error{errTransient},
+			wantAttempts: defaultMaxAttempts,
+			wantErr:      errTransient,
+			wantGaveUp:   GaveUpMaxAttempts,
+		},
+		{
+			name:         "permanent",
+			policy:       Policy{MaxAttempts: 5},
+			errs:         []error{permanent},
+			wantAttempts: 1,
+			wantErr:      permanent,
+			wantGaveUp:   GaveUpNotRetryable,
+		},
+		{
+			name:         "wrapped permanent",
+			policy:       Policy{MaxAttempts: 5, Retryable: func(error) bool { return true }},
+			errs:         []error{fmt.Errorf("op: %w", permanent)},
+			wantAttempts: 1,
+			wantErr:      errTransient,
+			wantGaveUp:   GaveUpNotRetryable,
+		},
+		{
+			name:         "not retryable",
+			policy:       Policy{MaxAttempts: 5, Retryable: func(err error) bool { return !errors.Is(err, errTransient) }},
+			errs:         []error{errTransient},
+			wantAttempts: 1,
+			wantErr:      errTransient,
+			wantGaveUp:   GaveUpNotRetryable,
+		},
+		{
+			name:         "budget exhausted",
+			policy:       Policy{MaxAttempts: 5, Budget: NewBudget(0, 2)},
+			errs:         []error{errTransient},
+			wantAttempts: 3,
+			wantErr:      errTransient,
+			wantGaveUp:   GaveUpBudget,
+		},
+		{
+			name:         "empty budget",
+			policy:       Policy{MaxAttempts: 5, Budget: NewBudget(0, 0)},
+			errs:         []error{errTransient},
+			wantAttempts: 1,
+			wantErr:      errTransient,
+			wantGaveUp:   GaveUpBudget,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			policy.BaseDelay = time.Microsecond
+			policy.MaxDelay = time.Microsecond
+
+			var attempts []Attempt
+			policy.OnAttempt = func(attempt Attempt) { attempts = append(attempts, attempt) }
+
+			calls := 0
+			err := policy.Do(context.Background(), func(ctx context.Context) error {
+				err := tt.errs[min(calls, len(tt.errs)-1)]
+				calls++
+				return err
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantAttempts || len(attempts) != tt.wantAttempts {
+				t.Fatalf("Do() made %d calls and %d attempts, want %d", calls, len(attempts), tt.wantAttempts)
+			}
+			for i, attempt := range attempts {
+				if attempt.Number != i+1 {
+					t.Errorf("attempt %d number = %d", i+1, attempt.Number)
+				}
+			}
+			if got := attempts[len(attempts)-1].GaveUp; got != tt.wantGaveUp {
+				t.Errorf("last attempt gave up = %q, want %q", got, tt.wantGaveUp)
+			}
+		})
+	}
+}
+
+func TestPolicyDoContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     Policy
+		ctx        func() (context.Context, context.CancelFunc)
+		wantGaveUp string
+	}{
+		{
+			name:   "cancelled",
+			policy: Policy{MaxAttempts: 5},
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantGaveUp: GaveUpContextCancelled,
+		},
+		{
+			name:   "retry past deadline",
+			policy: Policy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Millisecond)
+			},
+			wantGaveUp: GaveUpDeadline,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			policy := tt.policy
+			var last Attempt
+			policy.OnAttempt = func(attempt Attempt) { last = attempt }
+
+			// A delay within the deadline is drawn from an hour with negligible odds
+			err := policy.Do(ctx, func(ctx context.Context) error { return errTransient })
+			if !errors.Is(err, errTransient) {
+				t.Errorf("Do() error = %v, want %v", err, errTransient)
+			}
+			if last.Number != 1 || last.GaveUp != tt.wantGaveUp {
+				t.Errorf("last attempt = %+v, want attempt 1 giving up with %q", last, tt.wantGaveUp)
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratio       float64
+		capacity    int
+		drain       int
+		calls       int
+		wantAllowed int
+	}{
+		{"starts full", 0.1, 5, 0, 0, 5},
+		{"exhausts", 0.1, 5, 5, 0, 0},
+		{"empty", 0.5, 0, 0, 0, 0},
+		{"refills per call", 0.5, 2, 2, 4, 2},
+		{"partial refill", 0.5, 2, 2, 3, 1},
+		{"refill capped", 1, 3, 3, 10, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewBudget(tt.ratio, tt.capacity)
+			for i := 0; i < tt.drain; i++ {
+				if !budget.withdraw() {
+					t.Fatalf("withdrawal %d of %d refused", i+1, tt.drain)
+				}
+			}
+			for i := 0; i < tt.calls; i++ {
+				budget.deposit()
+			}
+
+			if got := budget.Available(); got != tt.wantAllowed {
+				t.Errorf("Available() = %d, want %d", got, tt.wantAllowed)
+			}
+
+			allowed := 0
+			for budget.withdraw() {
+				allowed++
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d withdrawals, want %d", allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -5,10 +5,16 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"insider-backend/internal/circuit"
 	"insider-backend/internal/config"
+	"insider-backend/internal/event"
+	"insider-backend/internal/fx"
 	"insider-backend/internal/handler"
+	"insider-backend/internal/metrics"
 	"insider-backend/internal/middleware"
+	"insider-backend/internal/projection"
 	"insider-backend/internal/repository"
+	"insider-backend/internal/repository/breaker"
 	"insider-backend/internal/repository/postgres"
 	redisrepo "insider-backend/internal/repository/redis"
 	"insider-backend/internal/service"
@@ -25,12 +31,19 @@ import (
 )
 
 type Server struct {
-	config      *config.Config
-	httpServer  *http.Server
-	db          *sql.DB
-	redisClient *redis.Client
-	workerPool  *worker.WorkerPool
-	router      *mux.Router
+	config       *config.Config
+	httpServer   *http.Server
+	db           *sql.DB
+	redisClient  *redis.Client
+	workerPool   *worker.WorkerPool
+	eventBus     *event.AsyncEventBus
+	eventStream  *event.RedisStreamSubscriber
+	breakers     *circuit.Registry
+	dbBreaker    *circuit.CircuitBreaker
+	cacheBreaker *circuit.CircuitBreaker
+	fxProvider   service.FXRateProvider
+	router       *mux.Router
+	stopJobs     context.CancelFunc
 }
 
 func New(cfg *config.Config) *Server {
@@ -49,6 +62,9 @@ func (s *Server) Start() error {
 
 	log.Info().Msg("Starting server...")
 
+	// Initialize metrics
+	metrics.Init()
+
 	// Initialize database
 	if err := s.initDatabase(); err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
@@ -59,8 +75,10 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to initialize Redis: %w", err)
 	}
 
-	// Initialize worker pool
-	s.initWorkerPool()
+	// Initialize FX rate provider
+	if err := s.initFXProvider(); err != nil {
+		return fmt.Errorf("failed to initialize FX rate provider: %w", err)
+	}
 
 	// Setup routes
 	s.setupRoutes()
@@ -77,6 +95,10 @@ func (s *Server) Start() error {
 	// Setup graceful shutdown
 	shutdown.Init(30 * time.Second)
 	shutdown.Add(s.gracefulShutdown)
+	shutdown.Add(s.eventBus.Shutdown)
+	if s.eventStream != nil {
+		shutdown.Add(s.eventStream.Shutdown)
+	}
 
 	// Start server in goroutine
 	go func() {
@@ -147,10 +169,36 @@ func (s *Server) initRedis() error {
 	return nil
 }
 
-func (s *Server) initWorkerPool() {
+func (s *Server) initFXProvider() error {
+	if s.config.FX.RatesFile == "" {
+		log.Info().Msg("FX rates file not configured, currency conversion disabled")
+		return nil
+	}
+
+	provider, err := fx.LoadStaticRateProvider(s.config.FX.RatesFile)
+	if err != nil {
+		return err
+	}
+
+	s.fxProvider = provider
+	log.Info().Str("file", s.config.FX.RatesFile).Msg("FX rates loaded")
+
+	return nil
+}
+
+// initWorkerPool creates the worker pool consuming the job queue of repos
+func (s *Server) initWorkerPool(repos *repository.Repositories) {
 	log.Info().Msg("Initializing worker pool...")
 
-	s.workerPool = worker.NewWorkerPool(10, 1000) // 10 workers, 1000 queue size
+	s.workerPool = worker.NewWorkerPool(repos, worker.PoolConfig{
+		Workers:           s.config.Worker.Count,
+		Name:              s.config.Worker.Name,
+		PollInterval:      s.config.Worker.PollInterval,
+		VisibilityTimeout: s.config.Worker.VisibilityTimeout,
+		MaxAttempts:       s.config.Worker.MaxAttempts,
+		Retention:         s.config.Worker.Retention,
+	})
+	s.workerPool.Register(worker.TransactionJobType, worker.NewTransactionJobFactory(repos), worker.TransactionJobPolicy)
 	s.workerPool.Start()
 
 	log.Info().Msg("Worker pool initialized")
@@ -161,22 +209,74 @@ func (s *Server) setupRoutes() {
 
 	// Initialize repositories
 	repos := &repository.Repositories{
-		User:        postgres.NewUserRepository(s.db),
-		Transaction: postgres.NewTransactionRepository(s.db),
-		Balance:     postgres.NewBalanceRepository(s.db),
-		AuditLog:    postgres.NewAuditLogRepository(s.db),
-		Cache:       redisrepo.NewCacheRepository(s.redisClient),
+		User:            postgres.NewUserRepository(s.db),
+		Transaction:     postgres.NewTransactionRepository(s.db),
+		Balance:         postgres.NewBalanceRepository(s.db),
+		AuditLog:        postgres.NewAuditLogRepository(s.db),
+		Ledger:          postgres.NewLedgerRepository(s.db),
+		Reconciliation:  postgres.NewReconciliationRepository(s.db),
+		Idempotency:     postgres.NewIdempotencyRepository(s.db),
+		Hold:            postgres.NewHoldRepository(s.db),
+		Schedule:        postgres.NewScheduleRepository(s.db),
+		Outbox:          postgres.NewOutboxRepository(s.db),
+		Webhook:         postgres.NewWebhookRepository(s.db),
+		WebhookDelivery: postgres.NewWebhookDeliveryRepository(s.db),
+		Job:             postgres.NewJobRepository(s.db),
+		Cache:           redisrepo.NewCacheRepository(s.redisClient),
 	}
+	repos.UnitOfWork = postgres.NewUnitOfWork(s.db, repos.Cache)
+
+	// Postgres and Redis calls fail fast while their dependency is down
+	s.breakers = circuit.NewRegistry(s.breakerSettings())
+	s.dbBreaker = s.newBreaker("postgres", breaker.IsPostgresFailure)
+	s.cacheBreaker = s.newBreaker("redis", breaker.IsCacheFailure)
+	repos = breaker.Wrap(repos, s.dbBreaker, s.cacheBreaker)
+
+	// Transactions are processed by workers consuming the persistent job queue
+	s.initWorkerPool(repos)
+
+	// Domain events queued in the outbox are relayed to the in-process bus
+	s.eventBus = event.NewAsyncEventBus(event.SubscriberConfig{
+		QueueSize:   s.config.EventBus.QueueSize,
+		Concurrency: s.config.EventBus.Concurrency,
+		MaxAttempts: s.config.EventBus.MaxAttempts,
+	})
+	eventStore := event.NewPostgresEventStore(s.db)
+	eventService := event.NewEventService(eventStore, s.eventBus)
+	aggregateLoader := event.NewAggregateLoader(eventStore, event.NewPostgresSnapshotStore(s.db), event.SnapshotPolicy{Every: s.config.Snapshot.Every})
+	projectionRunner := event.NewProjectionRunner(eventStore, eventService, event.NewPostgresCheckpointStore(s.db), s.config.Projection.BatchSize, s.config.Projection.Lag)
+	projectionRunner.Register(event.NewSnapshotter(aggregateLoader))
 
 	// Initialize services
 	userService := service.NewUserService(repos, s.config.JWT.SecretKey, s.config.JWT.AccessTokenTTL, s.config.JWT.RefreshTokenTTL)
-	transactionService := service.NewTransactionService(repos, s.workerPool)
-	balanceService := service.NewBalanceService(repos)
+	transactionService := service.NewTransactionService(repos, s.workerPool, s.fxProvider)
+	balanceService := service.NewBalanceService(repos, aggregateLoader)
+	idempotencyService := service.NewIdempotencyService(repos)
+	ledgerService := service.NewLedgerService(repos)
+	reconciliationService := service.NewReconciliationService(repos)
+	holdService := service.NewHoldService(repos, s.workerPool, s.config.Hold.DefaultTTL)
+	scheduleService := service.NewScheduleService(repos, s.workerPool)
+	webhookService := service.NewWebhookService(repos, s.config.Webhook.Timeout, s.config.Webhook.MaxAttempts)
+	projectionService := service.NewProjectionService(projectionRunner, projection.NewDailyTotalsProjection(s.db), projection.NewBalancesProjection(s.db))
+	aggregateService := service.NewAggregateService(aggregateLoader)
+	eventQueryService := service.NewEventQueryService(eventStore)
+	jobService := service.NewJobService(repos, s.workerPool)
+	outboxService := service.NewOutboxService(repos, event.NewMultiPublisher(s.eventPublisher(), webhookService), s.config.Outbox.MaxAttempts)
 
 	// Initialize handlers
 	userHandler := handler.NewUserHandler(userService)
 	transactionHandler := handler.NewTransactionHandler(transactionService)
 	balanceHandler := handler.NewBalanceHandler(balanceService)
+	ledgerHandler := handler.NewLedgerHandler(ledgerService)
+	reconciliationHandler := handler.NewReconciliationHandler(reconciliationService)
+	holdHandler := handler.NewHoldHandler(holdService)
+	scheduleHandler := handler.NewScheduleHandler(scheduleService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	projectionHandler := handler.NewProjectionHandler(projectionService)
+	aggregateHandler := handler.NewAggregateHandler(aggregateService)
+	eventHandler := handler.NewEventHandler(eventQueryService)
+	jobHandler := handler.NewJobHandler(jobService)
+	circuitBreakerHandler := handler.NewCircuitBreakerHandler(s.breakers)
 
 	// Global middleware
 	s.router.Use(middleware.Recovery())
@@ -187,20 +287,34 @@ func (s *Server) setupRoutes() {
 	s.router.Use(middleware.RateLimit(100)) // 100 requests per minute
 	s.router.Use(middleware.Timeout(30 * time.Second))
 
+	// Metrics
+	s.router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
 	// API routes
 	api := s.router.PathPrefix("/api/v1").Subrouter()
 	api.Use(middleware.JSONContentType())
 	api.Use(middleware.ValidateJSON())
 
+	// Routes that need Postgres fail fast while its circuit breaker is open
+	dbAvailable := middleware.CircuitBreaker(s.dbBreaker)
+
 	// Public routes (no authentication required)
-	api.HandleFunc("/auth/register", userHandler.Register).Methods("POST")
-	api.HandleFunc("/auth/login", userHandler.Login).Methods("POST")
+	api.Handle("/auth/register", dbAvailable(http.HandlerFunc(userHandler.Register))).Methods("POST")
+	api.Handle("/auth/login", dbAvailable(http.HandlerFunc(userHandler.Login))).Methods("POST")
 
 	// Health check
 	api.HandleFunc("/health", s.healthCheck).Methods("GET")
 
+	// Circuit breaker routes (admin only) stay available while Postgres is down
+	breakerAdmin := api.PathPrefix("/admin/circuit-breakers").Subrouter()
+	breakerAdmin.Use(middleware.AuthMiddleware(userService))
+	breakerAdmin.Use(middleware.RoleMiddleware("admin"))
+	breakerAdmin.HandleFunc("", circuitBreakerHandler.ListCircuitBreakers).Methods("GET")
+	breakerAdmin.HandleFunc("/{name}", circuitBreakerHandler.GetCircuitBreaker).Methods("GET")
+
 	// Protected routes (authentication required)
 	protected := api.PathPrefix("").Subrouter()
+	protected.Use(dbAvailable)
 	protected.Use(middleware.AuthMiddleware(userService))
 
 	// User routes
@@ -215,11 +329,13 @@ func (s *Server) setupRoutes() {
 	adminOnly.HandleFunc("/users/{id}", userHandler.DeleteUser).Methods("DELETE")
 
 	// Transaction routes
-	protected.HandleFunc("/transactions/credit", transactionHandler.CreateCredit).Methods("POST")
-	protected.HandleFunc("/transactions/debit", transactionHandler.CreateDebit).Methods("POST")
-	protected.HandleFunc("/transactions/transfer", transactionHandler.CreateTransfer).Methods("POST")
+	idempotent := middleware.Idempotency(idempotencyService)
+	protected.Handle("/transactions/credit", idempotent(http.HandlerFunc(transactionHandler.CreateCredit))).Methods("POST")
+	protected.Handle("/transactions/debit", idempotent(http.HandlerFunc(transactionHandler.CreateDebit))).Methods("POST")
+	protected.Handle("/transactions/transfer", idempotent(http.HandlerFunc(transactionHandler.CreateTransfer))).Methods("POST")
 	protected.HandleFunc("/transactions/{id}", transactionHandler.GetTransaction).Methods("GET")
 	protected.HandleFunc("/transactions/{id}/cancel", transactionHandler.CancelTransaction).Methods("POST")
+	protected.Handle("/transactions/{id}/reverse", idempotent(http.HandlerFunc(transactionHandler.ReverseTransaction))).Methods("POST")
 	protected.HandleFunc("/transactions/history", transactionHandler.GetTransactionHistory).Methods("GET")
 	protected.HandleFunc("/users/{user_id}/transactions", transactionHandler.GetUserTransactions).Methods("GET")
 
@@ -231,7 +347,330 @@ func (s *Server) setupRoutes() {
 	protected.HandleFunc("/balances/refresh", balanceHandler.RefreshBalance).Methods("POST")
 	protected.HandleFunc("/users/{user_id}/balance", balanceHandler.GetUserBalance).Methods("GET")
 
+	// Hold routes
+	protected.Handle("/holds", idempotent(http.HandlerFunc(holdHandler.CreateHold))).Methods("POST")
+	protected.HandleFunc("/holds/{id}", holdHandler.GetHold).Methods("GET")
+	protected.Handle("/holds/{id}/capture", idempotent(http.HandlerFunc(holdHandler.CaptureHold))).Methods("POST")
+	protected.HandleFunc("/holds/{id}/void", holdHandler.VoidHold).Methods("POST")
+	protected.HandleFunc("/users/{user_id}/holds", holdHandler.GetUserHolds).Methods("GET")
+
+	// Schedule routes
+	protected.Handle("/schedules", idempotent(http.HandlerFunc(scheduleHandler.CreateSchedule))).Methods("POST")
+	protected.HandleFunc("/schedules/{id}", scheduleHandler.GetSchedule).Methods("GET")
+	protected.HandleFunc("/schedules/{id}/cancel", scheduleHandler.CancelSchedule).Methods("POST")
+	protected.HandleFunc("/users/{user_id}/schedules", scheduleHandler.GetUserSchedules).Methods("GET")
+
+	// Ledger routes (admin only)
+	adminOnly.HandleFunc("/admin/ledger/transactions/{id}", ledgerHandler.GetTransactionEntries).Methods("GET")
+	adminOnly.HandleFunc("/admin/ledger/users/{user_id}/balances", ledgerHandler.GetUserBalances).Methods("GET")
+	adminOnly.HandleFunc("/admin/ledger/rebuild", ledgerHandler.RebuildBalances).Methods("POST")
+	adminOnly.HandleFunc("/admin/reconciliation", reconciliationHandler.Reconcile).Methods("POST")
+
+	// Webhook routes (admin only)
+	adminOnly.Handle("/admin/webhooks", idempotent(http.HandlerFunc(webhookHandler.CreateWebhook))).Methods("POST")
+	adminOnly.HandleFunc("/admin/webhooks", webhookHandler.ListWebhooks).Methods("GET")
+	adminOnly.HandleFunc("/admin/webhooks/dead-letters", webhookHandler.ListDeadLetters).Methods("GET")
+	adminOnly.HandleFunc("/admin/webhooks/deliveries/{id}", webhookHandler.GetDelivery).Methods("GET")
+	adminOnly.HandleFunc("/admin/webhooks/deliveries/{id}/replay", webhookHandler.ReplayDelivery).Methods("POST")
+	adminOnly.HandleFunc("/admin/webhooks/{id}", webhookHandler.GetWebhook).Methods("GET")
+	adminOnly.HandleFunc("/admin/webhooks/{id}", webhookHandler.UpdateWebhook).Methods("PUT")
+	adminOnly.HandleFunc("/admin/webhooks/{id}", webhookHandler.DeleteWebhook).Methods("DELETE")
+	adminOnly.HandleFunc("/admin/webhooks/{id}/deliveries", webhookHandler.GetWebhookDeliveries).Methods("GET")
+
+	// Projection routes (admin only)
+	adminOnly.HandleFunc("/admin/projections", projectionHandler.GetProjections).Methods("GET")
+	adminOnly.HandleFunc("/admin/projections/users/{user_id}/daily-totals", projectionHandler.GetUserDailyTotals).Methods("GET")
+	adminOnly.HandleFunc("/admin/projections/users/{user_id}/balances", projectionHandler.GetUserBalances).Methods("GET")
+	adminOnly.HandleFunc("/admin/projections/{name}/rebuild", projectionHandler.RebuildProjection).Methods("POST")
+
+	// Aggregate routes (admin only)
+	adminOnly.HandleFunc("/admin/aggregates/users/{id}", aggregateHandler.GetUser).Methods("GET")
+	adminOnly.HandleFunc("/admin/aggregates/users/{user_id}/balances/{currency}", aggregateHandler.GetBalance).Methods("GET")
+
+	// Event routes (admin only)
+	adminOnly.HandleFunc("/admin/events", eventHandler.QueryEvents).Methods("GET")
+	adminOnly.HandleFunc("/admin/events/stream", eventHandler.StreamEvents).Methods("GET")
+
+	// Job routes (admin only)
+	adminOnly.HandleFunc("/admin/jobs/dead-letters", jobHandler.ListDeadLetters).Methods("GET")
+	adminOnly.HandleFunc("/admin/jobs/{id}", jobHandler.GetJob).Methods("GET")
+	adminOnly.HandleFunc("/admin/jobs/{id}/requeue", jobHandler.RequeueJob).Methods("POST")
+	adminOnly.HandleFunc("/admin/jobs/{id}/discard", jobHandler.DiscardJob).Methods("POST")
+
 	log.Info().Msg("Routes configured")
+
+	// Background jobs run until the server shuts down
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopJobs = cancel
+
+	s.startReconciliation(ctx, reconciliationService, repos.Cache)
+	s.startHoldExpiry(ctx, holdService)
+	s.startScheduler(ctx, scheduleService)
+	s.startOutboxRelay(ctx, outboxService)
+	s.startWebhookDelivery(ctx, webhookService)
+	s.startProjections(ctx, projectionService)
+	s.startEventStream()
+}
+
+// breakerSettings returns the circuit breaker settings of the configuration.
+// Breaker state changes are exported as the circuit_breaker_state gauge.
+func (s *Server) breakerSettings() circuit.Settings {
+	window, err := circuit.ParseWindowType(s.config.Breaker.Window)
+	if err != nil {
+		log.Warn().Err(err).Msg("Using fixed circuit breaker window")
+	}
+
+	return circuit.Settings{
+		MaxRequests:  uint32(s.config.Breaker.MaxRequests),
+		Interval:     s.config.Breaker.Interval,
+		Timeout:      s.config.Breaker.Timeout,
+		FailureRatio: s.config.Breaker.FailureRatio,
+		Window:       window,
+		WindowSize:   s.config.Breaker.WindowSize,
+		OnStateChange: func(name string, from, to circuit.State) {
+			metrics.SetCircuitBreakerState(name, float64(to))
+		},
+	}
+}
+
+// newBreaker registers the circuit breaker name, which counts only the errors
+// isFailure accepts against the dependency
+func (s *Server) newBreaker(name string, isFailure func(error) bool) *circuit.CircuitBreaker {
+	st := s.breakerSettings()
+	st.Name = name
+	st.IsSuccessful = func(err error) bool { return !isFailure(err) }
+
+	cb := s.breakers.GetWithSettings(st)
+	metrics.SetCircuitBreakerState(name, float64(cb.State()))
+	return cb
+}
+
+// streamConfig returns the Redis Streams configuration of the event backend
+func (s *Server) streamConfig() event.RedisStreamConfig {
+	return event.RedisStreamConfig{
+		Prefix:        s.config.Stream.Prefix,
+		MaxLen:        int64(s.config.Stream.MaxLen),
+		Group:         s.config.Stream.Group,
+		Consumer:      s.config.Stream.Consumer,
+		ClaimIdle:     s.config.Stream.ClaimIdle,
+		MaxDeliveries: int64(s.config.Stream.MaxDeliveries),
+	}
+}
+
+// eventPublisher returns where the outbox relay delivers events: Redis
+// Streams shared by every replica when enabled, or the in-process bus
+func (s *Server) eventPublisher() event.EventPublisher {
+	if !s.config.Stream.Enabled {
+		return event.NewBusPublisher(s.eventBus)
+	}
+	return event.NewRedisStreamPublisher(s.redisClient, s.streamConfig())
+}
+
+// startEventStream feeds the events of the Redis Streams into the in-process
+// bus. Replicas share a consumer group, so each event reaches one of them.
+func (s *Server) startEventStream() {
+	if !s.config.Stream.Enabled {
+		log.Info().Msg("Event streams disabled")
+		return
+	}
+
+	subscriber := event.NewRedisStreamSubscriber(s.redisClient, s.streamConfig())
+	if err := subscriber.Subscribe(event.KnownEventTypes, s.eventBus.Publish); err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to event streams")
+		return
+	}
+	if err := subscriber.Start(); err != nil {
+		log.Error().Err(err).Msg("Failed to start event stream subscriber")
+		return
+	}
+
+	s.eventStream = subscriber
+}
+
+// startReconciliation runs a report-only balance reconciliation on an interval.
+// A Redis lock keeps multiple instances from running it at the same time.
+func (s *Server) startReconciliation(ctx context.Context, reconciliationService *service.ReconciliationService, cache repository.CacheRepository) {
+	interval := s.config.Reconcile.Interval
+	if interval <= 0 {
+		log.Info().Msg("Background reconciliation disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				acquired, err := cache.SetNX(ctx, "reconcile:lock", s.config.Server.Port, int(interval.Seconds()))
+				if err != nil {
+					log.Warn().Err(err).Msg("Failed to acquire reconciliation lock")
+					continue
+				}
+				if !acquired {
+					continue
+				}
+
+				if _, err := reconciliationService.Reconcile(ctx, nil, false); err != nil {
+					log.Error().Err(err).Msg("Background reconciliation failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Msg("Background reconciliation started")
+}
+
+// startHoldExpiry releases expired holds on an interval. Each hold is locked
+// while it is released, so every instance can run it.
+func (s *Server) startHoldExpiry(ctx context.Context, holdService *service.HoldService) {
+	interval := s.config.Hold.ExpiryInterval
+	if interval <= 0 {
+		log.Info().Msg("Hold expiry disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := holdService.ExpireHolds(ctx); err != nil {
+					log.Error().Err(err).Msg("Hold expiry failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Msg("Hold expiry started")
+}
+
+// startScheduler creates the transactions of due schedules on an interval.
+// Schedules are claimed with row locks, so every instance can run it.
+func (s *Server) startScheduler(ctx context.Context, scheduleService *service.ScheduleService) {
+	interval := s.config.Scheduler.Interval
+	if interval <= 0 {
+		log.Info().Msg("Transaction scheduler disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := scheduleService.RunDue(ctx); err != nil {
+					log.Error().Err(err).Msg("Scheduled transaction run failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Msg("Transaction scheduler started")
+}
+
+// startOutboxRelay delivers queued events on an interval and prunes delivered
+// messages hourly. Messages are claimed with row locks, so every instance can
+// run it.
+func (s *Server) startOutboxRelay(ctx context.Context, outboxService *service.OutboxService) {
+	interval := s.config.Outbox.RelayInterval
+	if interval <= 0 {
+		log.Info().Msg("Outbox relay disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		pruneTicker := time.NewTicker(time.Hour)
+		defer pruneTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := outboxService.Relay(ctx); err != nil {
+					log.Error().Err(err).Msg("Outbox relay failed")
+				}
+			case <-pruneTicker.C:
+				if _, err := outboxService.Prune(ctx, s.config.Outbox.Retention); err != nil {
+					log.Error().Err(err).Msg("Outbox pruning failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Msg("Outbox relay started")
+}
+
+// startWebhookDelivery sends due webhook deliveries on an interval. Deliveries
+// are leased while they are sent, so every instance can run it.
+func (s *Server) startWebhookDelivery(ctx context.Context, webhookService *service.WebhookService) {
+	interval := s.config.Webhook.DeliveryInterval
+	if interval <= 0 {
+		log.Info().Msg("Webhook delivery disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := webhookService.DeliverDue(ctx); err != nil {
+					log.Error().Err(err).Msg("Webhook delivery failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Msg("Webhook delivery started")
+}
+
+// startProjections catches the read model projections up with the event
+// store on an interval. Projections tolerate handling an event twice, so
+// every instance can run it.
+func (s *Server) startProjections(ctx context.Context, projectionService *service.ProjectionService) {
+	interval := s.config.Projection.Interval
+	if interval <= 0 {
+		log.Info().Msg("Projections disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := projectionService.CatchUp(ctx); err != nil {
+					log.Error().Err(err).Msg("Projection catch-up failed")
+				}
+			}
+		}
+	}()
+
+	log.Info().Dur("interval", interval).Msg("Projections started")
 }
 
 func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
@@ -263,6 +702,9 @@ func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 		"jobs_failed":      metrics.JobsFailed,
 		"jobs_in_progress": metrics.JobsInProgress,
 	}
+	health["event_bus"] = s.eventBus.Stats()
+
+	health["circuit_breakers"] = s.breakers.Statuses()
 
 	w.Header().Set("Content-Type", "application/json")
 	if health["status"] == "healthy" {
@@ -275,6 +717,11 @@ func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 func (s *Server) gracefulShutdown(ctx context.Context) error {
 	log.Info().Msg("Starting graceful shutdown...")
 
+	// Stop background jobs
+	if s.stopJobs != nil {
+		s.stopJobs()
+	}
+
 	// Stop worker pool
 	if s.workerPool != nil {
 		log.Info().Msg("Stopping worker pool...")
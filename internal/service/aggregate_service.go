@@ -0,0 +1,47 @@
+package service
+
+import (
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+
+	"github.com/google/uuid"
+)
+
+type AggregateService struct {
+	loader *event.AggregateLoader
+}
+
+// NewAggregateService creates a service loading aggregates with loader
+func NewAggregateService(loader *event.AggregateLoader) *AggregateService {
+	return &AggregateService{loader: loader}
+}
+
+// GetUser loads a user from its event stream
+func (s *AggregateService) GetUser(userID uuid.UUID) (*event.UserAggregate, *event.LoadInfo, error) {
+	user, info, err := s.loader.LoadUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Version == 0 {
+		return nil, nil, fmt.Errorf("user has no events")
+	}
+	return user, info, nil
+}
+
+// GetBalance loads a user's wallet in currency from its event stream
+func (s *AggregateService) GetBalance(userID uuid.UUID, currencyCode string) (*event.BalanceAggregate, *event.LoadInfo, error) {
+	currency, err := domain.ParseCurrency(currencyCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	balance, info, err := s.loader.LoadBalance(userID, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	if balance.Version == 0 {
+		return nil, nil, fmt.Errorf("wallet has no events")
+	}
+	return balance, info, nil
+}
@@ -4,43 +4,66 @@ import (
 	"context"
 	"fmt"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
 	"insider-backend/internal/repository"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
 type BalanceService struct {
-	balanceRepo repository.BalanceRepository
-	userRepo    repository.UserRepository
-	cacheRepo   repository.CacheRepository
+	balanceRepo     repository.BalanceRepository
+	userRepo        repository.UserRepository
+	transactionRepo repository.TransactionRepository
+	cacheRepo       repository.CacheRepository
+	loader          *event.AggregateLoader
 }
 
-func NewBalanceService(repos *repository.Repositories) *BalanceService {
+func NewBalanceService(repos *repository.Repositories, loader *event.AggregateLoader) *BalanceService {
 	return &BalanceService{
-		balanceRepo: repos.Balance,
-		userRepo:    repos.User,
-		cacheRepo:   repos.Cache,
+		balanceRepo:     repos.Balance,
+		userRepo:        repos.User,
+		transactionRepo: repos.Transaction,
+		cacheRepo:       repos.Cache,
+		loader:          loader,
 	}
 }
 
-// GetBalance retrieves the current balance for a user
-func (s *BalanceService) GetBalance(ctx context.Context, userID uuid.UUID) (*domain.Balance, error) {
+// BalanceReconstruction is a wallet rebuilt from its events at an instant,
+// with the transactions behind the events that were applied
+type BalanceReconstruction struct {
+	*event.BalanceReconstruction
+	Transactions []*domain.Transaction `json:"transactions"`
+}
+
+// GetBalances retrieves every currency wallet of a user
+func (s *BalanceService) GetBalances(ctx context.Context, userID uuid.UUID) ([]*domain.Balance, error) {
 	// Try cache first
-	cacheKey := fmt.Sprintf("balance:%s", userID.String())
-	var cachedBalance domain.Balance
-	if err := s.cacheRepo.Get(ctx, cacheKey, &cachedBalance); err == nil {
-		return &cachedBalance, nil
+	cacheKey := fmt.Sprintf("balances:%s", userID.String())
+	var cachedBalances []*domain.Balance
+	if err := s.cacheRepo.Get(ctx, cacheKey, &cachedBalances); err == nil {
+		return cachedBalances, nil
 	}
 
 	// Get from database
-	balance, err := s.balanceRepo.GetByUserID(ctx, userID)
+	balances, err := s.balanceRepo.GetByUserID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get balance: %w", err)
+		return nil, fmt.Errorf("failed to get balances: %w", err)
 	}
 
 	// Cache for future requests
-	s.cacheRepo.Set(ctx, cacheKey, balance, 60) // 1 minute
+	s.cacheRepo.Set(ctx, cacheKey, balances, 60) // 1 minute
+
+	return balances, nil
+}
+
+// GetBalance retrieves the wallet of a user in a specific currency
+func (s *BalanceService) GetBalance(ctx context.Context, userID uuid.UUID, currency domain.Currency) (*domain.Balance, error) {
+	balance, err := s.balanceRepo.GetByUserIDAndCurrency(ctx, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
 
 	return balance, nil
 }
@@ -62,14 +85,14 @@ func (s *BalanceService) GetBalanceHistory(ctx context.Context, userID uuid.UUID
 }
 
 // GetBalanceAtTime retrieves the balance at a specific point in time
-func (s *BalanceService) GetBalanceAtTime(ctx context.Context, userID uuid.UUID, timestamp string) (float64, error) {
+func (s *BalanceService) GetBalanceAtTime(ctx context.Context, userID uuid.UUID, currency domain.Currency, timestamp string) (domain.Money, error) {
 	// Verify user exists
 	_, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return 0, fmt.Errorf("user not found: %w", err)
 	}
 
-	balance, err := s.balanceRepo.GetBalanceAtTime(ctx, userID, timestamp)
+	balance, err := s.balanceRepo.GetBalanceAtTime(ctx, userID, currency, timestamp)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get balance at time: %w", err)
 	}
@@ -77,9 +100,46 @@ func (s *BalanceService) GetBalanceAtTime(ctx context.Context, userID uuid.UUID,
 	return balance, nil
 }
 
-// GetBalanceSnapshot returns a snapshot of the current balance
-func (s *BalanceService) GetBalanceSnapshot(ctx context.Context, userID uuid.UUID) (domain.BalanceSnapshot, error) {
-	balance, err := s.GetBalance(ctx, userID)
+// ReconstructBalanceAtTime rebuilds a wallet as it was at an instant by
+// replaying its balance events, starting from its latest snapshot when
+// useSnapshot is set and the snapshot predates the instant
+func (s *BalanceService) ReconstructBalanceAtTime(ctx context.Context, userID uuid.UUID, currency domain.Currency, at time.Time, useSnapshot bool) (*BalanceReconstruction, error) {
+	// Verify user exists
+	_, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	reconstruction, err := s.loader.LoadBalanceAt(userID, currency, at, useSnapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct balance: %w", err)
+	}
+
+	result := &BalanceReconstruction{
+		BalanceReconstruction: reconstruction,
+		Transactions:          []*domain.Transaction{},
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, contribution := range reconstruction.Contributions {
+		if contribution.TransactionID == nil || seen[*contribution.TransactionID] {
+			continue
+		}
+		seen[*contribution.TransactionID] = true
+
+		transaction, err := s.transactionRepo.GetByID(ctx, *contribution.TransactionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s: %w", *contribution.TransactionID, err)
+		}
+		result.Transactions = append(result.Transactions, transaction)
+	}
+
+	return result, nil
+}
+
+// GetBalanceSnapshot returns a snapshot of the current balance in a currency
+func (s *BalanceService) GetBalanceSnapshot(ctx context.Context, userID uuid.UUID, currency domain.Currency) (domain.BalanceSnapshot, error) {
+	balance, err := s.GetBalance(ctx, userID, currency)
 	if err != nil {
 		return domain.BalanceSnapshot{}, err
 	}
@@ -89,34 +149,34 @@ func (s *BalanceService) GetBalanceSnapshot(ctx context.Context, userID uuid.UUI
 
 // InvalidateBalanceCache invalidates the balance cache for a user
 func (s *BalanceService) InvalidateBalanceCache(ctx context.Context, userID uuid.UUID) {
-	cacheKey := fmt.Sprintf("balance:%s", userID.String())
+	cacheKey := fmt.Sprintf("balances:%s", userID.String())
 	if err := s.cacheRepo.Delete(ctx, cacheKey); err != nil {
 		log.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to invalidate balance cache")
 	}
 }
 
-// RefreshBalance forces a refresh of the balance from the database
-func (s *BalanceService) RefreshBalance(ctx context.Context, userID uuid.UUID) (*domain.Balance, error) {
+// RefreshBalance forces a refresh of the balances from the database
+func (s *BalanceService) RefreshBalance(ctx context.Context, userID uuid.UUID) ([]*domain.Balance, error) {
 	// Invalidate cache first
 	s.InvalidateBalanceCache(ctx, userID)
 
-	// Get fresh balance from database
-	return s.GetBalance(ctx, userID)
+	// Get fresh balances from database
+	return s.GetBalances(ctx, userID)
 }
 
-// CreateInitialBalance creates an initial balance for a new user
-func (s *BalanceService) CreateInitialBalance(ctx context.Context, userID uuid.UUID) (*domain.Balance, error) {
+// CreateInitialBalance creates an initial wallet for a user in the given currency
+func (s *BalanceService) CreateInitialBalance(ctx context.Context, userID uuid.UUID, currency domain.Currency) (*domain.Balance, error) {
 	// Verify user exists
 	_, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	balance := domain.NewBalance(userID)
+	balance := domain.NewBalance(userID, currency)
 	if err := s.balanceRepo.Create(ctx, balance); err != nil {
 		return nil, fmt.Errorf("failed to create initial balance: %w", err)
 	}
 
-	log.Info().Str("user_id", userID.String()).Msg("Initial balance created")
+	log.Info().Str("user_id", userID.String()).Str("currency", currency.String()).Msg("Initial balance created")
 	return balance, nil
 }
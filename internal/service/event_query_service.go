@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"insider-backend/internal/event"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event tailing settings
+const (
+	// eventTailLag leaves time for slower transactions to commit events
+	// created before ones already committed, so a tail does not skip them
+	eventTailLag = 2 * time.Second
+	// eventTailBatchSize is the page size of a tail poll
+	eventTailBatchSize = 100
+)
+
+type EventQueryService struct {
+	store event.EventStore
+}
+
+// NewEventQueryService creates a service querying store
+func NewEventQueryService(store event.EventStore) *EventQueryService {
+	return &EventQueryService{store: store}
+}
+
+// QueryEvents returns a page of the events matching query
+func (s *EventQueryService) QueryEvents(ctx context.Context, query event.EventQuery) (*event.EventPage, error) {
+	return s.store.QueryEvents(query)
+}
+
+// TailCursor returns the cursor a tail starts from when the client has none,
+// so that it only receives events created from now on
+func (s *EventQueryService) TailCursor() string {
+	return event.EncodeEventCursor(time.Now().Add(-eventTailLag), uuid.Nil)
+}
+
+// PollEvents returns the next page of events matching filter after cursor,
+// oldest first. Events younger than eventTailLag are left for a later poll.
+func (s *EventQueryService) PollEvents(ctx context.Context, filter event.EventFilter, cursor string) (*event.EventPage, error) {
+	to := time.Now().Add(-eventTailLag)
+	filter.ToTime = &to
+	filter.Limit = eventTailBatchSize
+	filter.Offset = 0
+
+	return s.store.QueryEvents(event.EventQuery{
+		Filter: filter,
+		Order:  event.SortAscending,
+		Cursor: cursor,
+	})
+}
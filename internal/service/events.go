@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/retry"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sources recorded in the metadata of events appended by services
+const (
+	eventSourceAPI            = "api"
+	eventSourceScheduler      = "scheduler"
+	eventSourceReconciliation = "reconciliation"
+)
+
+// eventMetadata describes who caused an event appended by a service
+func eventMetadata(userID *uuid.UUID, ipAddress net.IP, userAgent, source string) event.Metadata {
+	metadata := event.Metadata{
+		UserID:    userID,
+		UserAgent: userAgent,
+		Source:    source,
+	}
+	if ipAddress != nil {
+		metadata.IPAddress = ipAddress.String()
+	}
+	return metadata
+}
+
+// conflictRetryPolicy redoes an operation that lost the race for its
+// aggregate's stream, after a short jittered wait so that the racing
+// operations do not collide again
+var conflictRetryPolicy = retry.Policy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    100 * time.Millisecond,
+	Retryable: func(err error) bool {
+		return errors.Is(err, event.ErrConcurrencyConflict)
+	},
+}
+
+// appendEvent appends an event to its aggregate's stream and queues it for
+// delivery. repos must belong to the unit of work saving the change, so the
+// event is recorded if and only if the change is.
+func appendEvent(ctx context.Context, repos *repository.Repositories, eventType event.EventType, aggregateID uuid.UUID, data interface{}, metadata event.Metadata) error {
+	return appendEventAt(ctx, repos, event.AnyVersion, eventType, aggregateID, data, metadata)
+}
+
+// appendEventAt is like appendEvent but fails with event.ErrConcurrencyConflict
+// unless the stream is still at expectedVersion
+func appendEventAt(ctx context.Context, repos *repository.Repositories, expectedVersion int, eventType event.EventType, aggregateID uuid.UUID, data interface{}, metadata event.Metadata) error {
+	evt, err := event.NewEvent(eventType, aggregateID, data, metadata, 0)
+	if err != nil {
+		return fmt.Errorf("failed to build %s event: %w", eventType, err)
+	}
+
+	if err := repos.Outbox.AppendEvents(ctx, aggregateID, expectedVersion, evt); err != nil {
+		return fmt.Errorf("failed to append %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// retryOnConflict runs op again while it fails with a concurrency conflict,
+// as conflictRetryPolicy allows. op must re-read the state it changes on
+// every run.
+func retryOnConflict(ctx context.Context, op func() error) error {
+	return conflictRetryPolicy.Do(ctx, func(ctx context.Context) error {
+		return op()
+	})
+}
+
+// appendTransactionCreated appends the created event of a transaction
+func appendTransactionCreated(ctx context.Context, repos *repository.Repositories, transaction *domain.Transaction, metadata event.Metadata) error {
+	return appendEvent(ctx, repos, event.TransactionCreatedEvent, transaction.ID, event.NewTransactionCreatedEventData(transaction), metadata)
+}
+
+// appendTransactionStatusChanged appends eventType for a transaction that
+// moved from oldStatus to its current status
+func appendTransactionStatusChanged(ctx context.Context, repos *repository.Repositories, eventType event.EventType, transaction *domain.Transaction, oldStatus domain.TransactionStatus, reason string, metadata event.Metadata) error {
+	data := event.TransactionStatusChangedEventData{
+		TransactionID: transaction.ID,
+		OldStatus:     string(oldStatus),
+		NewStatus:     string(transaction.Status),
+		Reason:        reason,
+	}
+	return appendEvent(ctx, repos, eventType, transaction.ID, data, metadata)
+}
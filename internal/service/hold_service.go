@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/worker"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// expireHoldsBatchSize bounds how many holds one expiry run releases
+const expireHoldsBatchSize = 100
+
+type HoldService struct {
+	repos      *repository.Repositories
+	holdRepo   repository.HoldRepository
+	userRepo   repository.UserRepository
+	cacheRepo  repository.CacheRepository
+	workerPool *worker.WorkerPool
+	defaultTTL time.Duration
+}
+
+// NewHoldService creates a hold service. Holds placed without an expiry
+// expire after defaultTTL.
+func NewHoldService(repos *repository.Repositories, workerPool *worker.WorkerPool, defaultTTL time.Duration) *HoldService {
+	if defaultTTL <= 0 {
+		defaultTTL = domain.DefaultHoldTTL
+	}
+
+	return &HoldService{
+		repos:      repos,
+		holdRepo:   repos.Hold,
+		userRepo:   repos.User,
+		cacheRepo:  repos.Cache,
+		workerPool: workerPool,
+		defaultTTL: defaultTTL,
+	}
+}
+
+// PlaceHold reserves funds on a user's wallet without moving them
+func (s *HoldService) PlaceHold(ctx context.Context, req domain.CreateHoldRequest, userID *uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Hold, error) {
+	if _, err := s.userRepo.GetByID(ctx, req.UserID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	currency, err := domain.ParseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.defaultTTL)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	hold, err := domain.NewHold(req.UserID, currency, req.Amount, expiresAt, req.Description, req.ReferenceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	err = s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := claimIdempotencyKey(ctx, repos); err != nil {
+			return err
+		}
+
+		balance, err := repos.Balance.GetByUserIDAndCurrency(ctx, hold.UserID, hold.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to get balance: %w", err)
+		}
+
+		if err := balance.PlaceHold(hold.Amount); err != nil {
+			return err
+		}
+
+		if err := repos.Balance.UpdateWithLock(ctx, balance); err != nil {
+			return fmt.Errorf("failed to update balance: %w", err)
+		}
+
+		if err := repos.Hold.Create(ctx, hold); err != nil {
+			return fmt.Errorf("failed to save hold: %w", err)
+		}
+
+		return s.audit(ctx, repos, hold, balance, domain.ActionHold, "", userID, ipAddress, userAgent)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateBalances(ctx, hold.UserID)
+
+	log.Info().
+		Str("hold_id", hold.ID.String()).
+		Str("user_id", hold.UserID.String()).
+		Str("amount", hold.Amount.String()).
+		Str("currency", hold.Currency.String()).
+		Time("expires_at", hold.ExpiresAt).
+		Msg("Hold placed")
+
+	return hold, nil
+}
+
+// GetHold retrieves a hold by ID
+func (s *HoldService) GetHold(ctx context.Context, holdID uuid.UUID) (*domain.Hold, error) {
+	return s.holdRepo.GetByID(ctx, holdID)
+}
+
+// GetUserHolds retrieves the holds placed on a user's wallets
+func (s *HoldService) GetUserHolds(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Hold, error) {
+	return s.holdRepo.GetByUserID(ctx, userID, limit, offset)
+}
+
+// CaptureHold settles a hold with a debit, or a transfer when req.ToUserID is
+// set. A zero amount captures the whole hold and any remainder of a partial
+// capture is released. The held funds stay reserved until the transaction is
+// processed; if it fails the hold becomes active again.
+func (s *HoldService) CaptureHold(ctx context.Context, holdID uuid.UUID, req domain.CaptureHoldRequest, userID *uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Transaction, error) {
+	if req.ToUserID != nil {
+		if _, err := s.userRepo.GetByID(ctx, *req.ToUserID); err != nil {
+			return nil, fmt.Errorf("target user not found: %w", err)
+		}
+	}
+
+	var transaction *domain.Transaction
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := claimIdempotencyKey(ctx, repos); err != nil {
+			return err
+		}
+
+		hold, err := repos.Hold.GetByIDForUpdate(ctx, holdID)
+		if err != nil {
+			return err
+		}
+
+		amount := req.Amount
+		if amount.IsZero() {
+			amount = hold.Amount
+		}
+
+		txType := domain.TransactionTypeDebit
+		if req.ToUserID != nil {
+			txType = domain.TransactionTypeTransfer
+		}
+
+		description := req.Description
+		if description == "" {
+			description = fmt.Sprintf("Capture of hold %s", hold.ID)
+		}
+
+		transaction, err = domain.NewTransaction(&hold.UserID, req.ToUserID, amount, hold.Currency, txType, description, "")
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		transaction.HoldID = &hold.ID
+
+		if err := hold.Capture(amount, transaction.ID); err != nil {
+			return err
+		}
+
+		if err := repos.Transaction.Create(ctx, transaction); err != nil {
+			return fmt.Errorf("failed to save transaction: %w", err)
+		}
+
+		if err := appendTransactionCreated(ctx, repos, transaction, eventMetadata(userID, ipAddress, userAgent, eventSourceAPI)); err != nil {
+			return err
+		}
+
+		if err := repos.Hold.Update(ctx, hold); err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+
+		if err := s.workerPool.EnqueueTransaction(ctx, repos, transaction.ID); err != nil {
+			return err
+		}
+
+		balance, err := repos.Balance.GetByUserIDAndCurrency(ctx, hold.UserID, hold.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to get balance: %w", err)
+		}
+
+		return s.audit(ctx, repos, hold, balance, domain.ActionCapture, domain.HoldStatusActive, userID, ipAddress, userAgent)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Wake the worker pool to process the queued transaction
+	s.workerPool.Notify()
+
+	log.Info().
+		Str("hold_id", holdID.String()).
+		Str("transaction_id", transaction.ID.String()).
+		Str("amount", transaction.Amount.String()).
+		Str("currency", transaction.Currency.String()).
+		Str("type", string(transaction.Type)).
+		Msg("Hold captured")
+
+	return transaction, nil
+}
+
+// VoidHold releases an active hold
+func (s *HoldService) VoidHold(ctx context.Context, holdID uuid.UUID, userID *uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Hold, error) {
+	var hold *domain.Hold
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		var err error
+		hold, err = repos.Hold.GetByIDForUpdate(ctx, holdID)
+		if err != nil {
+			return err
+		}
+
+		if err := hold.Void(); err != nil {
+			return err
+		}
+
+		return s.release(ctx, repos, hold, domain.ActionVoid, userID, ipAddress, userAgent)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateBalances(ctx, hold.UserID)
+
+	log.Info().
+		Str("hold_id", hold.ID.String()).
+		Str("user_id", hold.UserID.String()).
+		Str("amount", hold.Amount.String()).
+		Msg("Hold voided")
+
+	return hold, nil
+}
+
+// ExpireHolds releases active holds that have passed their expiry and
+// returns how many were released. It is safe to run on several instances.
+func (s *HoldService) ExpireHolds(ctx context.Context) (int, error) {
+	holds, err := s.holdRepo.ListExpired(ctx, time.Now(), expireHoldsBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+
+	expired := 0
+	for _, candidate := range holds {
+		released := false
+		err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+			// Re-read under lock, it may have been captured or expired meanwhile
+			hold, err := repos.Hold.GetByIDForUpdate(ctx, candidate.ID)
+			if err != nil {
+				return err
+			}
+			if !hold.IsExpired(time.Now()) {
+				return nil
+			}
+
+			if err := hold.Expire(); err != nil {
+				return err
+			}
+
+			released = true
+			return s.release(ctx, repos, hold, domain.ActionExpire, nil, nil, "")
+		})
+		if err != nil {
+			log.Error().Err(err).Str("hold_id", candidate.ID.String()).Msg("Failed to expire hold")
+			continue
+		}
+
+		if released {
+			expired++
+			s.invalidateBalances(ctx, candidate.UserID)
+		}
+	}
+
+	if expired > 0 {
+		log.Info().Int("count", expired).Msg("Expired holds released")
+	}
+
+	return expired, nil
+}
+
+// release returns the funds of a voided or expired hold to the available balance
+func (s *HoldService) release(ctx context.Context, repos *repository.Repositories, hold *domain.Hold, action string, userID *uuid.UUID, ipAddress net.IP, userAgent string) error {
+	balance, err := repos.Balance.GetByUserIDAndCurrency(ctx, hold.UserID, hold.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	if err := balance.ReleaseHold(hold.Amount); err != nil {
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	if err := repos.Balance.UpdateWithLock(ctx, balance); err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	if err := repos.Hold.Update(ctx, hold); err != nil {
+		return fmt.Errorf("failed to update hold: %w", err)
+	}
+
+	return s.audit(ctx, repos, hold, balance, action, domain.HoldStatusActive, userID, ipAddress, userAgent)
+}
+
+func (s *HoldService) audit(ctx context.Context, repos *repository.Repositories, hold *domain.Hold, balance *domain.Balance, action string, oldStatus domain.HoldStatus, userID *uuid.UUID, ipAddress net.IP, userAgent string) error {
+	auditDetails := domain.NewHoldAuditDetails(hold, balance)
+	auditDetails.OldStatus = string(oldStatus)
+
+	auditLog, err := domain.NewAuditLog(
+		domain.EntityTypeHold,
+		action,
+		hold.ID,
+		auditDetails,
+		userID,
+		ipAddress,
+		userAgent,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build audit log: %w", err)
+	}
+
+	if err := repos.AuditLog.Create(ctx, auditLog); err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (s *HoldService) invalidateBalances(ctx context.Context, userID uuid.UUID) {
+	if s.cacheRepo == nil {
+		return
+	}
+	if err := s.cacheRepo.Delete(ctx, fmt.Sprintf("balances:%s", userID.String())); err != nil {
+		log.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to invalidate balance cache")
+	}
+}
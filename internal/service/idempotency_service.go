@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is already in progress")
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key has already been used with a different request")
+)
+
+// idempotencyLockTTL bounds how long an in-flight request holds its key, in seconds.
+// It must outlive the request timeout so a slow request is not executed twice.
+const idempotencyLockTTL = 60
+
+type IdempotencyService struct {
+	idempotencyRepo repository.IdempotencyRepository
+	cacheRepo       repository.CacheRepository
+}
+
+func NewIdempotencyService(repos *repository.Repositories) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: repos.Idempotency,
+		cacheRepo:       repos.Cache,
+	}
+}
+
+// Acquire locks a key for an in-flight request. It returns the stored record
+// when the key was already completed with the same request, in which case the
+// lock is not held and the record should be replayed. The lock only turns
+// away concurrent retries early: the key is claimed for good by
+// claimIdempotencyKey in the unit of work of the change the request makes.
+func (s *IdempotencyService) Acquire(ctx context.Context, userID uuid.UUID, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	locked, err := s.cacheRepo.SetNX(ctx, s.lockKey(userID, key), requestHash, idempotencyLockTTL)
+	if err != nil {
+		// The claim in the database still turns away concurrent requests
+		log.Warn().Err(err).Str("idempotency_key", key).Msg("Failed to lock idempotency key, relying on the database claim")
+		locked = true
+	}
+	if !locked {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	record, err := s.idempotencyRepo.Get(ctx, userID, key)
+	if err != nil {
+		s.Release(ctx, userID, key)
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+
+	if record == nil {
+		return nil, nil
+	}
+
+	s.Release(ctx, userID, key)
+
+	if !record.Matches(requestHash) {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+
+	// The change was committed but its response was never stored, e.g.
+	// because the server stopped in between
+	if !record.IsCompleted() {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return record, nil
+}
+
+// Complete stores the response of a request and releases its key
+func (s *IdempotencyService) Complete(ctx context.Context, record *domain.IdempotencyRecord) error {
+	defer s.Release(ctx, record.UserID, record.Key)
+
+	if err := s.idempotencyRepo.Create(ctx, record); err != nil {
+		return fmt.Errorf("failed to save idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// Release frees a key without storing a response so the request can be retried
+func (s *IdempotencyService) Release(ctx context.Context, userID uuid.UUID, key string) {
+	if err := s.cacheRepo.Delete(ctx, s.lockKey(userID, key)); err != nil {
+		log.Warn().Err(err).Str("idempotency_key", key).Msg("Failed to release idempotency key")
+	}
+}
+
+type idempotencyClaimKey struct{}
+
+// WithIdempotencyClaim returns a context carrying the claim of the key of
+// the request ctx belongs to
+func WithIdempotencyClaim(ctx context.Context, claim *domain.IdempotencyRecord) context.Context {
+	return context.WithValue(ctx, idempotencyClaimKey{}, claim)
+}
+
+// claimIdempotencyKey claims the idempotency key of the request ctx belongs
+// to, if any, in the unit of work of repos. The claim commits with the change
+// the request makes, so a key cannot make the change twice, and rolls back
+// with it, so a failed request can be retried.
+func claimIdempotencyKey(ctx context.Context, repos *repository.Repositories) error {
+	claim, ok := ctx.Value(idempotencyClaimKey{}).(*domain.IdempotencyRecord)
+	if !ok {
+		return nil
+	}
+
+	claimed, err := repos.Idempotency.Claim(ctx, claim)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrIdempotencyKeyInProgress
+	}
+
+	return nil
+}
+
+func (s *IdempotencyService) lockKey(userID uuid.UUID, key string) string {
+	return fmt.Sprintf("idempotency:%s:%s", userID.String(), key)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/worker"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type JobService struct {
+	repos      *repository.Repositories
+	jobRepo    repository.JobRepository
+	workerPool *worker.WorkerPool
+}
+
+func NewJobService(repos *repository.Repositories, workerPool *worker.WorkerPool) *JobService {
+	return &JobService{
+		repos:      repos,
+		jobRepo:    repos.Job,
+		workerPool: workerPool,
+	}
+}
+
+// ListDeadLetters lists the dead letters that have not been requeued or
+// discarded, optionally only those of a job type
+func (s *JobService) ListDeadLetters(ctx context.Context, jobType string, limit, offset int) ([]*domain.JobDeadLetter, error) {
+	return s.jobRepo.ListDeadLetters(ctx, jobType, limit, offset)
+}
+
+// GetJob gets a job together with its attempts
+func (s *JobService) GetJob(ctx context.Context, id uuid.UUID) (*domain.Job, []*domain.JobAttempt, error) {
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts, err := s.jobRepo.GetAttempts(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return job, attempts, nil
+}
+
+// RequeueJob queues a dead job again with a fresh retry budget
+func (s *JobService) RequeueJob(ctx context.Context, id uuid.UUID) (*domain.Job, error) {
+	var job *domain.Job
+
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		var err error
+		job, err = repos.Job.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := job.Requeue(now); err != nil {
+			return err
+		}
+
+		if err := repos.Job.Requeue(ctx, job); err != nil {
+			return err
+		}
+
+		return repos.Job.ResolveDeadLetters(ctx, job.ID, domain.JobDeadLetterRequeued, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.workerPool.Notify()
+
+	log.Info().Str("job_id", job.ID.String()).Str("job_type", job.Type).Msg("Job requeued")
+
+	return job, nil
+}
+
+// DiscardJob gives up on a dead job and settles its work, e.g. fails its
+// transaction. It stays dead and is pruned with the finished jobs.
+func (s *JobService) DiscardJob(ctx context.Context, id uuid.UUID) (*domain.Job, error) {
+	var job *domain.Job
+
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		var err error
+		job, err = repos.Job.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if job.Status != domain.JobStatusDead {
+			return fmt.Errorf("job is %s, only dead jobs can be discarded", job.Status)
+		}
+
+		if err := repos.Job.ResolveDeadLetters(ctx, job.ID, domain.JobDeadLetterDiscarded, time.Now()); err != nil {
+			return err
+		}
+
+		// A no-op for jobs already settled when they were dead-lettered
+		return s.workerPool.SettleDead(ctx, repos, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("job_id", job.ID.String()).Str("job_type", job.Type).Msg("Job discarded")
+
+	return job, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type LedgerService struct {
+	ledgerRepo repository.LedgerRepository
+	cacheRepo  repository.CacheRepository
+}
+
+func NewLedgerService(repos *repository.Repositories) *LedgerService {
+	return &LedgerService{
+		ledgerRepo: repos.Ledger,
+		cacheRepo:  repos.Cache,
+	}
+}
+
+// GetTransactionEntries returns the journal entries recorded for a transaction
+func (s *LedgerService) GetTransactionEntries(ctx context.Context, transactionID uuid.UUID) ([]*domain.JournalEntry, error) {
+	entries, err := s.ledgerRepo.GetEntriesByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get journal entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetUserBalances returns a user's balances as computed from the journal
+func (s *LedgerService) GetUserBalances(ctx context.Context, userID uuid.UUID) ([]*domain.LedgerBalance, error) {
+	balances, err := s.ledgerRepo.GetUserBalances(ctx, &userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger balances: %w", err)
+	}
+
+	return balances, nil
+}
+
+// RebuildBalances recomputes the balances projection from the journal.
+// A nil userID rebuilds every user.
+func (s *LedgerService) RebuildBalances(ctx context.Context, userID *uuid.UUID) (int64, error) {
+	changed, err := s.ledgerRepo.RebuildBalances(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild balances: %w", err)
+	}
+
+	// Drop cached balances so readers see the rebuilt projection
+	if userID != nil {
+		if err := s.cacheRepo.Delete(ctx, fmt.Sprintf("balances:%s", userID.String())); err != nil {
+			log.Warn().Err(err).Msg("Failed to invalidate balance cache")
+		}
+	} else if err := s.cacheRepo.DeletePattern(ctx, "balances:*"); err != nil {
+		log.Warn().Err(err).Msg("Failed to invalidate balance cache")
+	}
+
+	event := log.Info().Int64("changed", changed)
+	if userID != nil {
+		event = event.Str("user_id", userID.String())
+	}
+	event.Msg("Balances rebuilt from ledger")
+
+	return changed, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/event"
+	"insider-backend/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// A relay batch holds at most one message per aggregate, so a run keeps
+// claiming batches of up to relayBatchSize messages until nothing is due or
+// relayMaxBatches is reached.
+const (
+	relayBatchSize  = 100
+	relayMaxBatches = 10
+)
+
+type OutboxService struct {
+	repos       *repository.Repositories
+	outboxRepo  repository.OutboxRepository
+	publisher   event.EventPublisher
+	maxAttempts int
+}
+
+// NewOutboxService creates the relay delivering queued events to publisher.
+// A message is parked as failed after maxAttempts failed deliveries.
+func NewOutboxService(repos *repository.Repositories, publisher event.EventPublisher, maxAttempts int) *OutboxService {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return &OutboxService{
+		repos:       repos,
+		outboxRepo:  repos.Outbox,
+		publisher:   publisher,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Relay delivers due outbox messages and returns how many were delivered.
+// Messages are claimed with row locks, so every instance can run it. Delivery
+// is at least once: a crash between publishing and committing the delivery
+// status publishes the message again, so consumers must deduplicate on the
+// event ID.
+func (s *OutboxService) Relay(ctx context.Context) (int, error) {
+	delivered := 0
+
+	for batch := 0; batch < relayMaxBatches; batch++ {
+		claimed, batchDelivered := 0, 0
+		err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+			messages, err := repos.Outbox.ClaimPending(ctx, time.Now(), relayBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(messages)
+
+			for _, message := range messages {
+				if err := s.publisher.Publish(message.Event); err != nil {
+					message.MarkAttemptFailed(err, time.Now(), s.maxAttempts)
+					s.logFailure(message)
+				} else {
+					message.MarkDelivered(time.Now())
+					batchDelivered++
+				}
+
+				if err := repos.Outbox.Update(ctx, message); err != nil {
+					return fmt.Errorf("failed to record outbox delivery: %w", err)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return delivered, err
+		}
+		delivered += batchDelivered
+
+		if claimed == 0 {
+			break
+		}
+	}
+
+	return delivered, nil
+}
+
+// Prune deletes messages delivered more than retention ago
+func (s *OutboxService) Prune(ctx context.Context, retention time.Duration) (int64, error) {
+	deleted, err := s.outboxRepo.DeleteDelivered(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Msg("Pruned delivered outbox messages")
+	}
+
+	return deleted, nil
+}
+
+func (s *OutboxService) logFailure(message *event.OutboxMessage) {
+	logEvent := log.Warn()
+	if message.Status == event.OutboxStatusFailed {
+		logEvent = log.Error()
+	}
+
+	logEvent.
+		Int64("outbox_id", message.ID).
+		Str("event_id", message.Event.ID.String()).
+		Str("event_type", string(message.Event.Type)).
+		Int("attempts", message.Attempts).
+		Str("status", string(message.Status)).
+		Str("error", message.LastError).
+		Msg("Failed to deliver event")
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/event"
+	"insider-backend/internal/projection"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// maxDailyTotalsRange bounds how many days one daily totals query spans
+const maxDailyTotalsRange = 366 * 24 * time.Hour
+
+type ProjectionService struct {
+	runner      *event.ProjectionRunner
+	dailyTotals *projection.DailyTotalsProjection
+	balances    *projection.BalancesProjection
+}
+
+// NewProjectionService registers the read model projections with runner
+func NewProjectionService(runner *event.ProjectionRunner, dailyTotals *projection.DailyTotalsProjection, balances *projection.BalancesProjection) *ProjectionService {
+	runner.Register(dailyTotals)
+	runner.Register(balances)
+
+	return &ProjectionService{
+		runner:      runner,
+		dailyTotals: dailyTotals,
+		balances:    balances,
+	}
+}
+
+// CatchUp feeds the projections the events appended since their checkpoints
+func (s *ProjectionService) CatchUp(ctx context.Context) (int, error) {
+	handled, err := s.runner.CatchUp(ctx)
+	if handled > 0 {
+		log.Debug().Int("events", handled).Msg("Projections caught up")
+	}
+	return handled, err
+}
+
+// Rebuild clears a projection and replays every event into it
+func (s *ProjectionService) Rebuild(ctx context.Context, name string) (int, error) {
+	return s.runner.Rebuild(ctx, name)
+}
+
+// GetCheckpoints returns the checkpoint of every projection
+func (s *ProjectionService) GetCheckpoints() ([]*event.ProjectionCheckpoint, error) {
+	return s.runner.Checkpoints()
+}
+
+// GetUserDailyTotals returns a user's daily totals between two UTC days, both
+// inclusive and formatted as YYYY-MM-DD
+func (s *ProjectionService) GetUserDailyTotals(ctx context.Context, userID uuid.UUID, from, to string) ([]*projection.DailyTotal, error) {
+	fromDay, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date, use YYYY-MM-DD")
+	}
+	toDay, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date, use YYYY-MM-DD")
+	}
+	if toDay.Before(fromDay) {
+		return nil, fmt.Errorf("from date must not be after to date")
+	}
+	if toDay.Sub(fromDay) > maxDailyTotalsRange {
+		return nil, fmt.Errorf("date range cannot exceed 366 days")
+	}
+
+	return s.dailyTotals.GetUserTotals(ctx, userID, from, to)
+}
+
+// GetUserBalances returns a user's balances as projected from balance events
+func (s *ProjectionService) GetUserBalances(ctx context.Context, userID uuid.UUID) ([]*projection.BalanceView, error) {
+	return s.balances.GetUserBalances(ctx, userID)
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+	"insider-backend/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type ReconciliationService struct {
+	repos              *repository.Repositories
+	reconciliationRepo repository.ReconciliationRepository
+	cacheRepo          repository.CacheRepository
+}
+
+func NewReconciliationService(repos *repository.Repositories) *ReconciliationService {
+	return &ReconciliationService{
+		repos:              repos,
+		reconciliationRepo: repos.Reconciliation,
+		cacheRepo:          repos.Cache,
+	}
+}
+
+// Reconcile recomputes every wallet from completed transactions and reports
+// the ones whose stored balance has drifted. With repair set, drifted
+// balances are reset to the recomputed amount, and the ledger and the balance
+// event stream are adjusted to match. A nil userID checks every user.
+func (s *ReconciliationService) Reconcile(ctx context.Context, userID *uuid.UUID, repair bool) (*domain.ReconciliationReport, error) {
+	report := domain.NewReconciliationReport(userID, repair)
+
+	states, err := s.reconciliationRepo.GetWalletStates(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet states: %w", err)
+	}
+	report.WalletsChecked = len(states)
+
+	for _, state := range states {
+		if !state.HasDrift() {
+			continue
+		}
+
+		transactionIDs, err := s.reconciliationRepo.GetSuspectTransactions(ctx, state.UserID, state.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get suspect transactions: %w", err)
+		}
+
+		mismatch := &domain.BalanceMismatch{
+			WalletState:    *state,
+			Drift:          state.Difference(),
+			TransactionIDs: transactionIDs,
+		}
+		report.Mismatches = append(report.Mismatches, mismatch)
+
+		log.Warn().
+			Str("user_id", state.UserID.String()).
+			Str("currency", state.Currency.String()).
+			Str("balance", state.BalanceAmount.String()).
+			Str("expected", state.TransactionAmount.String()).
+			Str("drift", mismatch.Drift.String()).
+			Int("suspect_transactions", len(transactionIDs)).
+			Msg("Balance drift detected")
+
+		if !repair {
+			continue
+		}
+
+		if err := s.repairWallet(ctx, state.UserID, state.Currency); err != nil {
+			log.Error().Err(err).
+				Str("user_id", state.UserID.String()).
+				Str("currency", state.Currency.String()).
+				Msg("Failed to repair balance")
+			continue
+		}
+
+		mismatch.Repaired = true
+		report.RepairedCount++
+	}
+
+	report.CompletedAt = time.Now()
+
+	log.Info().
+		Int("wallets_checked", report.WalletsChecked).
+		Int("mismatches", len(report.Mismatches)).
+		Int("repaired", report.RepairedCount).
+		Bool("repair", repair).
+		Dur("duration", report.CompletedAt.Sub(report.StartedAt)).
+		Msg("Balance reconciliation completed")
+
+	return report, nil
+}
+
+// repairWallet resets a wallet to the amount recomputed from completed
+// transactions. The state is read again inside the database transaction so a
+// transaction completing in between is not overwritten.
+func (s *ReconciliationService) repairWallet(ctx context.Context, userID uuid.UUID, currency domain.Currency) error {
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		balance, err := repos.Balance.GetByUserIDAndCurrency(ctx, userID, currency)
+		if err != nil {
+			return fmt.Errorf("failed to get balance: %w", err)
+		}
+
+		states, err := repos.Reconciliation.GetWalletStates(ctx, &userID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet state: %w", err)
+		}
+
+		var state *domain.WalletState
+		for _, candidate := range states {
+			if candidate.Currency == currency {
+				state = candidate
+				break
+			}
+		}
+		if state == nil || !state.HasDrift() {
+			return nil
+		}
+
+		previousAmount := balance.GetAmount()
+		if err := balance.SetAmount(state.TransactionAmount); err != nil {
+			return fmt.Errorf("cannot repair balance: %w", err)
+		}
+
+		if err := repos.Balance.UpdateWithLock(ctx, balance); err != nil {
+			return fmt.Errorf("failed to update balance: %w", err)
+		}
+
+		// Keep the ledger, which the balances are projected from, in step
+		if err := s.adjustLedger(ctx, repos, userID, currency, state.TransactionAmount); err != nil {
+			return err
+		}
+
+		// Keep the balance event stream, which reconstructions and the balance
+		// projection replay, in step
+		if err := s.appendAdjustment(ctx, repos, balance, previousAmount); err != nil {
+			return err
+		}
+
+		auditDetails := domain.BalanceAuditDetails{
+			UserID:         userID,
+			Currency:       currency,
+			Amount:         balance.GetAmount(),
+			PreviousAmount: previousAmount,
+			Operation:      "reconcile",
+		}
+
+		auditLog, err := domain.NewAuditLog(
+			domain.EntityTypeBalance,
+			domain.ActionUpdate,
+			userID,
+			auditDetails,
+			nil,
+			nil,
+			"",
+		)
+		if err != nil {
+			return fmt.Errorf("failed to build audit log: %w", err)
+		}
+
+		if err := repos.AuditLog.Create(ctx, auditLog); err != nil {
+			return fmt.Errorf("failed to create audit log: %w", err)
+		}
+
+		log.Info().
+			Str("user_id", userID.String()).
+			Str("currency", currency.String()).
+			Str("previous_amount", previousAmount.String()).
+			Str("amount", balance.GetAmount().String()).
+			Msg("Balance repaired")
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.cacheRepo != nil {
+		if err := s.cacheRepo.Delete(ctx, fmt.Sprintf("balances:%s", userID.String())); err != nil {
+			log.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to invalidate balance cache")
+		}
+	}
+
+	return nil
+}
+
+// appendAdjustment appends the credited or debited event of a repaired
+// balance, for the drift between previousAmount and its repaired amount
+func (s *ReconciliationService) appendAdjustment(ctx context.Context, repos *repository.Repositories, balance *domain.Balance, previousAmount domain.Money) error {
+	drift := balance.GetAmount().Sub(previousAmount)
+	if drift.IsZero() {
+		return nil
+	}
+
+	eventType := event.BalanceCreditedEvent
+	if drift < 0 {
+		eventType = event.BalanceDebitedEvent
+		drift = drift.Neg()
+	}
+
+	return appendEvent(ctx, repos, eventType, event.BalanceAggregateID(balance.UserID, balance.Currency), event.BalanceChangedEventData{
+		UserID:     balance.UserID,
+		Currency:   balance.Currency,
+		OldBalance: previousAmount,
+		NewBalance: balance.GetAmount(),
+		Amount:     drift,
+		Operation:  "reconcile",
+	}, eventMetadata(nil, nil, "", eventSourceReconciliation))
+}
+
+// adjustLedger posts an adjustment against the external account so the
+// user's ledger account sums to target
+func (s *ReconciliationService) adjustLedger(ctx context.Context, repos *repository.Repositories, userID uuid.UUID, currency domain.Currency, target domain.Money) error {
+	ledgerBalances, err := repos.Ledger.GetUserBalances(ctx, &userID)
+	if err != nil {
+		return fmt.Errorf("failed to get ledger balances: %w", err)
+	}
+
+	var current domain.Money
+	for _, ledgerBalance := range ledgerBalances {
+		if ledgerBalance.Currency == currency {
+			current = ledgerBalance.Amount
+			break
+		}
+	}
+
+	adjustment := target.Sub(current)
+	if adjustment.IsZero() {
+		return nil
+	}
+
+	entry, err := domain.NewJournalEntry(nil, "Reconciliation adjustment",
+		domain.UserPosting(userID, currency, adjustment),
+		domain.SystemPosting(domain.LedgerAccountExternal, currency, adjustment.Neg()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build journal entry: %w", err)
+	}
+
+	if err := repos.Ledger.CreateEntry(ctx, entry); err != nil {
+		return fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	return nil
+}
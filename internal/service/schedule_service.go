@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/worker"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// runDueBatchSize bounds how many runs one scheduler tick executes
+const runDueBatchSize = 100
+
+type ScheduleService struct {
+	repos        *repository.Repositories
+	scheduleRepo repository.ScheduleRepository
+	userRepo     repository.UserRepository
+	auditRepo    repository.AuditLogRepository
+	workerPool   *worker.WorkerPool
+}
+
+func NewScheduleService(repos *repository.Repositories, workerPool *worker.WorkerPool) *ScheduleService {
+	return &ScheduleService{
+		repos:        repos,
+		scheduleRepo: repos.Schedule,
+		userRepo:     repos.User,
+		auditRepo:    repos.AuditLog,
+		workerPool:   workerPool,
+	}
+}
+
+// CreateSchedule stores a schedule for a one-off or recurring transaction
+func (s *ScheduleService) CreateSchedule(ctx context.Context, req domain.CreateScheduleRequest, userID uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Schedule, error) {
+	schedule, err := domain.NewSchedule(userID, req, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	for _, participant := range []*uuid.UUID{schedule.FromUserID, schedule.ToUserID} {
+		if participant == nil {
+			continue
+		}
+		if _, err := s.userRepo.GetByID(ctx, *participant); err != nil {
+			return nil, fmt.Errorf("user %s not found: %w", participant, err)
+		}
+	}
+
+	err = s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := claimIdempotencyKey(ctx, repos); err != nil {
+			return err
+		}
+
+		if err := repos.Schedule.Create(ctx, schedule); err != nil {
+			return fmt.Errorf("failed to save schedule: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog, _ := domain.NewAuditLog(
+		domain.EntityTypeSchedule,
+		domain.ActionCreate,
+		schedule.ID,
+		schedule,
+		&userID,
+		ipAddress,
+		userAgent,
+	)
+
+	if err := s.auditRepo.Create(ctx, auditLog); err != nil {
+		log.Warn().Err(err).Msg("Failed to create audit log")
+	}
+
+	log.Info().
+		Str("schedule_id", schedule.ID.String()).
+		Str("type", string(schedule.Type)).
+		Str("amount", schedule.Amount.String()).
+		Str("currency", schedule.Currency.String()).
+		Str("recurrence", string(schedule.Recurrence)).
+		Time("next_run_at", *schedule.NextRunAt).
+		Msg("Schedule created")
+
+	return schedule, nil
+}
+
+// GetSchedule retrieves a schedule by ID
+func (s *ScheduleService) GetSchedule(ctx context.Context, scheduleID uuid.UUID) (*domain.Schedule, error) {
+	return s.scheduleRepo.GetByID(ctx, scheduleID)
+}
+
+// GetUserSchedules retrieves the schedules created by a user
+func (s *ScheduleService) GetUserSchedules(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.Schedule, error) {
+	return s.scheduleRepo.GetByUserID(ctx, userID, limit, offset)
+}
+
+// CancelSchedule stops a schedule. Transactions it already created are not affected.
+func (s *ScheduleService) CancelSchedule(ctx context.Context, scheduleID uuid.UUID, userID *uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Schedule, error) {
+	var schedule *domain.Schedule
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		var err error
+		schedule, err = repos.Schedule.GetByIDForUpdate(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+
+		if err := schedule.Cancel(); err != nil {
+			return err
+		}
+
+		if err := repos.Schedule.Update(ctx, schedule); err != nil {
+			return fmt.Errorf("failed to cancel schedule: %w", err)
+		}
+
+		auditLog, err := domain.NewAuditLog(
+			domain.EntityTypeSchedule,
+			domain.ActionUpdate,
+			schedule.ID,
+			schedule,
+			userID,
+			ipAddress,
+			userAgent,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to build audit log: %w", err)
+		}
+
+		return repos.AuditLog.Create(ctx, auditLog)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("schedule_id", schedule.ID.String()).Msg("Schedule cancelled")
+
+	return schedule, nil
+}
+
+// RunDue creates the transactions of due schedules and queues them for the
+// worker pool, returning how many were created. Each run is claimed, recorded
+// and its transaction created and queued in one database transaction with the
+// schedule row locked, so a run happens exactly once across all instances and
+// restarts.
+func (s *ScheduleService) RunDue(ctx context.Context) (int, error) {
+	created := 0
+
+	for created < runDueBatchSize {
+		var transaction *domain.Transaction
+		err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+			schedules, err := repos.Schedule.ClaimDue(ctx, time.Now(), 1)
+			if err != nil {
+				return err
+			}
+			if len(schedules) == 0 {
+				return nil
+			}
+			schedule := schedules[0]
+
+			transaction, err = schedule.NewTransaction()
+			if err != nil {
+				return fmt.Errorf("failed to create transaction for schedule %s: %w", schedule.ID, err)
+			}
+
+			if err := repos.Transaction.Create(ctx, transaction); err != nil {
+				return fmt.Errorf("failed to save transaction: %w", err)
+			}
+
+			if err := appendTransactionCreated(ctx, repos, transaction, eventMetadata(&schedule.UserID, nil, "", eventSourceScheduler)); err != nil {
+				return err
+			}
+
+			if err := schedule.RecordRun(transaction.ID); err != nil {
+				return err
+			}
+
+			if err := repos.Schedule.Update(ctx, schedule); err != nil {
+				return fmt.Errorf("failed to update schedule: %w", err)
+			}
+
+			if err := s.workerPool.EnqueueTransaction(ctx, repos, transaction.ID); err != nil {
+				return err
+			}
+
+			auditLog, err := domain.NewAuditLog(
+				domain.EntityTypeTransaction,
+				domain.ActionCreate,
+				transaction.ID,
+				domain.NewTransactionAuditDetails(transaction),
+				&schedule.UserID,
+				nil,
+				"",
+			)
+			if err != nil {
+				return fmt.Errorf("failed to build audit log: %w", err)
+			}
+
+			return repos.AuditLog.Create(ctx, auditLog)
+		})
+		if err != nil {
+			return created, err
+		}
+		if transaction == nil {
+			break
+		}
+		created++
+
+		s.workerPool.Notify()
+
+		log.Info().
+			Str("schedule_id", transaction.ScheduleID.String()).
+			Str("transaction_id", transaction.ID.String()).
+			Msg("Scheduled transaction created")
+	}
+
+	return created, nil
+}
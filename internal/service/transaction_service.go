@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
 	"insider-backend/internal/repository"
 	"insider-backend/internal/worker"
 	"net"
@@ -13,22 +14,33 @@ import (
 )
 
 type TransactionService struct {
+	repos           *repository.Repositories
 	transactionRepo repository.TransactionRepository
 	balanceRepo     repository.BalanceRepository
 	userRepo        repository.UserRepository
 	auditRepo       repository.AuditLogRepository
 	cacheRepo       repository.CacheRepository
 	workerPool      *worker.WorkerPool
+	fxProvider      FXRateProvider
 }
 
-func NewTransactionService(repos *repository.Repositories, workerPool *worker.WorkerPool) *TransactionService {
+// FXRateProvider supplies exchange rates for cross-currency transfers
+type FXRateProvider interface {
+	GetRate(ctx context.Context, from, to domain.Currency) (*domain.ExchangeRate, error)
+}
+
+// NewTransactionService creates a transaction service. fxProvider may be nil,
+// in which case cross-currency transfers are rejected.
+func NewTransactionService(repos *repository.Repositories, workerPool *worker.WorkerPool, fxProvider FXRateProvider) *TransactionService {
 	return &TransactionService{
+		repos:           repos,
 		transactionRepo: repos.Transaction,
 		balanceRepo:     repos.Balance,
 		userRepo:        repos.User,
 		auditRepo:       repos.AuditLog,
 		cacheRepo:       repos.Cache,
 		workerPool:      workerPool,
+		fxProvider:      fxProvider,
 	}
 }
 
@@ -36,7 +48,8 @@ func NewTransactionService(repos *repository.Repositories, workerPool *worker.Wo
 func (s *TransactionService) CreateCredit(ctx context.Context, req domain.CreateTransactionRequest, userID *uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Transaction, error) {
 	log.Info().
 		Str("to_user_id", req.ToUserID.String()).
-		Float64("amount", req.Amount).
+		Str("amount", req.Amount.String()).
+		Str("currency", req.Currency).
 		Msg("Creating credit transaction")
 
 	if req.ToUserID == nil {
@@ -49,11 +62,17 @@ func (s *TransactionService) CreateCredit(ctx context.Context, req domain.Create
 		return nil, fmt.Errorf("target user not found: %w", err)
 	}
 
+	currency, err := domain.ParseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create transaction
 	transaction, err := domain.NewTransaction(
 		nil,
 		req.ToUserID,
 		req.Amount,
+		currency,
 		domain.TransactionTypeCredit,
 		req.Description,
 		req.ReferenceID,
@@ -63,31 +82,19 @@ func (s *TransactionService) CreateCredit(ctx context.Context, req domain.Create
 	}
 
 	// Save transaction
-	if err := s.transactionRepo.Create(ctx, transaction); err != nil {
-		return nil, fmt.Errorf("failed to save transaction: %w", err)
+	metadata := eventMetadata(userID, ipAddress, userAgent, eventSourceAPI)
+	if err := s.saveTransaction(ctx, transaction, metadata); err != nil {
+		return nil, err
 	}
 
-	// Submit to worker pool for processing
-	job := worker.NewTransactionJob(transaction.ID, &repository.Repositories{
-		Transaction: s.transactionRepo,
-		Balance:     s.balanceRepo,
-		User:        s.userRepo,
-		AuditLog:    s.auditRepo,
-		Cache:       s.cacheRepo,
-	})
-
-	if err := s.workerPool.SubmitJob(job); err != nil {
-		log.Error().Err(err).Str("transaction_id", transaction.ID.String()).Msg("Failed to submit transaction job")
-		// Mark transaction as failed
-		transaction.MarkFailed()
-		s.transactionRepo.Update(ctx, transaction)
-		return nil, fmt.Errorf("failed to process transaction: %w", err)
-	}
+	// Wake the worker pool to process the queued transaction
+	s.workerPool.Notify()
 
 	// Create audit log
 	auditDetails := domain.TransactionAuditDetails{
 		ToUserID:    req.ToUserID,
 		Amount:      req.Amount,
+		Currency:    currency,
 		Type:        string(domain.TransactionTypeCredit),
 		Status:      string(transaction.Status),
 		Description: req.Description,
@@ -110,7 +117,8 @@ func (s *TransactionService) CreateCredit(ctx context.Context, req domain.Create
 
 	log.Info().
 		Str("transaction_id", transaction.ID.String()).
-		Float64("amount", req.Amount).
+		Str("amount", req.Amount.String()).
+		Str("currency", currency.String()).
 		Msg("Credit transaction created")
 
 	return transaction, nil
@@ -120,7 +128,8 @@ func (s *TransactionService) CreateCredit(ctx context.Context, req domain.Create
 func (s *TransactionService) CreateDebit(ctx context.Context, req domain.CreateTransactionRequest, userID *uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Transaction, error) {
 	log.Info().
 		Str("from_user_id", req.FromUserID.String()).
-		Float64("amount", req.Amount).
+		Str("amount", req.Amount.String()).
+		Str("currency", req.Currency).
 		Msg("Creating debit transaction")
 
 	if req.FromUserID == nil {
@@ -133,14 +142,19 @@ func (s *TransactionService) CreateDebit(ctx context.Context, req domain.CreateT
 		return nil, fmt.Errorf("source user not found: %w", err)
 	}
 
+	currency, err := domain.ParseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check balance before creating transaction
-	balance, err := s.balanceRepo.GetByUserID(ctx, *req.FromUserID)
+	balance, err := s.balanceRepo.GetByUserIDAndCurrency(ctx, *req.FromUserID, currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
 
 	if !balance.HasSufficientBalance(req.Amount) {
-		return nil, fmt.Errorf("insufficient balance: have %.2f, need %.2f", balance.GetAmount(), req.Amount)
+		return nil, fmt.Errorf("insufficient balance: have %s, need %s", balance.GetAmount(), req.Amount)
 	}
 
 	// Create transaction
@@ -148,6 +162,7 @@ func (s *TransactionService) CreateDebit(ctx context.Context, req domain.CreateT
 		req.FromUserID,
 		nil,
 		req.Amount,
+		currency,
 		domain.TransactionTypeDebit,
 		req.Description,
 		req.ReferenceID,
@@ -157,31 +172,19 @@ func (s *TransactionService) CreateDebit(ctx context.Context, req domain.CreateT
 	}
 
 	// Save transaction
-	if err := s.transactionRepo.Create(ctx, transaction); err != nil {
-		return nil, fmt.Errorf("failed to save transaction: %w", err)
+	metadata := eventMetadata(userID, ipAddress, userAgent, eventSourceAPI)
+	if err := s.saveTransaction(ctx, transaction, metadata); err != nil {
+		return nil, err
 	}
 
-	// Submit to worker pool for processing
-	job := worker.NewTransactionJob(transaction.ID, &repository.Repositories{
-		Transaction: s.transactionRepo,
-		Balance:     s.balanceRepo,
-		User:        s.userRepo,
-		AuditLog:    s.auditRepo,
-		Cache:       s.cacheRepo,
-	})
-
-	if err := s.workerPool.SubmitJob(job); err != nil {
-		log.Error().Err(err).Str("transaction_id", transaction.ID.String()).Msg("Failed to submit transaction job")
-		// Mark transaction as failed
-		transaction.MarkFailed()
-		s.transactionRepo.Update(ctx, transaction)
-		return nil, fmt.Errorf("failed to process transaction: %w", err)
-	}
+	// Wake the worker pool to process the queued transaction
+	s.workerPool.Notify()
 
 	// Create audit log
 	auditDetails := domain.TransactionAuditDetails{
 		FromUserID:  req.FromUserID,
 		Amount:      req.Amount,
+		Currency:    currency,
 		Type:        string(domain.TransactionTypeDebit),
 		Status:      string(transaction.Status),
 		Description: req.Description,
@@ -204,7 +207,8 @@ func (s *TransactionService) CreateDebit(ctx context.Context, req domain.CreateT
 
 	log.Info().
 		Str("transaction_id", transaction.ID.String()).
-		Float64("amount", req.Amount).
+		Str("amount", req.Amount.String()).
+		Str("currency", currency.String()).
 		Msg("Debit transaction created")
 
 	return transaction, nil
@@ -215,7 +219,8 @@ func (s *TransactionService) CreateTransfer(ctx context.Context, req domain.Crea
 	log.Info().
 		Str("from_user_id", req.FromUserID.String()).
 		Str("to_user_id", req.ToUserID.String()).
-		Float64("amount", req.Amount).
+		Str("amount", req.Amount.String()).
+		Str("currency", req.Currency).
 		Msg("Creating transfer transaction")
 
 	if req.FromUserID == nil || req.ToUserID == nil {
@@ -237,14 +242,28 @@ func (s *TransactionService) CreateTransfer(ctx context.Context, req domain.Crea
 		return nil, fmt.Errorf("target user not found: %w", err)
 	}
 
+	currency, err := domain.ParseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// The receiver is credited in to_currency when it differs from currency
+	toCurrency := currency
+	if req.ToCurrency != "" {
+		toCurrency, err = domain.ParseCurrency(req.ToCurrency)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Check balance before creating transaction
-	balance, err := s.balanceRepo.GetByUserID(ctx, *req.FromUserID)
+	balance, err := s.balanceRepo.GetByUserIDAndCurrency(ctx, *req.FromUserID, currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
 
 	if !balance.HasSufficientBalance(req.Amount) {
-		return nil, fmt.Errorf("insufficient balance: have %.2f, need %.2f", balance.GetAmount(), req.Amount)
+		return nil, fmt.Errorf("insufficient balance: have %s, need %s", balance.GetAmount(), req.Amount)
 	}
 
 	// Create transaction
@@ -252,6 +271,7 @@ func (s *TransactionService) CreateTransfer(ctx context.Context, req domain.Crea
 		req.FromUserID,
 		req.ToUserID,
 		req.Amount,
+		currency,
 		domain.TransactionTypeTransfer,
 		req.Description,
 		req.ReferenceID,
@@ -260,38 +280,23 @@ func (s *TransactionService) CreateTransfer(ctx context.Context, req domain.Crea
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	// Save transaction
-	if err := s.transactionRepo.Create(ctx, transaction); err != nil {
-		return nil, fmt.Errorf("failed to save transaction: %w", err)
+	if toCurrency != currency {
+		if err := s.applyExchangeRate(ctx, transaction, toCurrency); err != nil {
+			return nil, err
+		}
 	}
 
-	// Submit to worker pool for processing
-	job := worker.NewTransactionJob(transaction.ID, &repository.Repositories{
-		Transaction: s.transactionRepo,
-		Balance:     s.balanceRepo,
-		User:        s.userRepo,
-		AuditLog:    s.auditRepo,
-		Cache:       s.cacheRepo,
-	})
-
-	if err := s.workerPool.SubmitJob(job); err != nil {
-		log.Error().Err(err).Str("transaction_id", transaction.ID.String()).Msg("Failed to submit transaction job")
-		// Mark transaction as failed
-		transaction.MarkFailed()
-		s.transactionRepo.Update(ctx, transaction)
-		return nil, fmt.Errorf("failed to process transaction: %w", err)
+	// Save transaction
+	metadata := eventMetadata(userID, ipAddress, userAgent, eventSourceAPI)
+	if err := s.saveTransaction(ctx, transaction, metadata); err != nil {
+		return nil, err
 	}
 
+	// Wake the worker pool to process the queued transaction
+	s.workerPool.Notify()
+
 	// Create audit log
-	auditDetails := domain.TransactionAuditDetails{
-		FromUserID:  req.FromUserID,
-		ToUserID:    req.ToUserID,
-		Amount:      req.Amount,
-		Type:        string(domain.TransactionTypeTransfer),
-		Status:      string(transaction.Status),
-		Description: req.Description,
-		ReferenceID: req.ReferenceID,
-	}
+	auditDetails := domain.NewTransactionAuditDetails(transaction)
 
 	auditLog, _ := domain.NewAuditLog(
 		domain.EntityTypeTransaction,
@@ -309,12 +314,64 @@ func (s *TransactionService) CreateTransfer(ctx context.Context, req domain.Crea
 
 	log.Info().
 		Str("transaction_id", transaction.ID.String()).
-		Float64("amount", req.Amount).
+		Str("amount", req.Amount.String()).
+		Str("currency", currency.String()).
+		Str("to_currency", transaction.CreditCurrency().String()).
+		Str("to_amount", transaction.CreditAmount().String()).
 		Msg("Transfer transaction created")
 
 	return transaction, nil
 }
 
+// saveTransaction saves a new transaction together with its created event,
+// the job processing it and the claim of the request's idempotency key
+func (s *TransactionService) saveTransaction(ctx context.Context, transaction *domain.Transaction, metadata event.Metadata) error {
+	return s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := claimIdempotencyKey(ctx, repos); err != nil {
+			return err
+		}
+
+		if err := repos.Transaction.Create(ctx, transaction); err != nil {
+			return fmt.Errorf("failed to save transaction: %w", err)
+		}
+
+		if err := appendTransactionCreated(ctx, repos, transaction, metadata); err != nil {
+			return err
+		}
+
+		return s.workerPool.EnqueueTransaction(ctx, repos, transaction.ID)
+	})
+}
+
+// applyExchangeRate quotes a rate from the FX provider and records the
+// conversion on the transfer
+func (s *TransactionService) applyExchangeRate(ctx context.Context, transaction *domain.Transaction, toCurrency domain.Currency) error {
+	if s.fxProvider == nil {
+		return fmt.Errorf("currency conversion is not supported: cannot transfer %s into a %s wallet", transaction.Currency, toCurrency)
+	}
+
+	rate, err := s.fxProvider.GetRate(ctx, transaction.Currency, toCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	if err := transaction.ApplyExchangeRate(*rate); err != nil {
+		return fmt.Errorf("failed to convert amount: %w", err)
+	}
+
+	log.Info().
+		Str("transaction_id", transaction.ID.String()).
+		Str("from_currency", rate.From.String()).
+		Str("to_currency", rate.To.String()).
+		Str("rate", rate.Rate).
+		Str("spread", rate.Spread).
+		Str("source", rate.Source).
+		Time("rate_at", rate.Timestamp).
+		Msg("Applied exchange rate to transfer")
+
+	return nil
+}
+
 // GetTransaction retrieves a transaction by ID
 func (s *TransactionService) GetTransaction(ctx context.Context, transactionID uuid.UUID) (*domain.Transaction, error) {
 	// Try cache first
@@ -330,8 +387,8 @@ func (s *TransactionService) GetTransaction(ctx context.Context, transactionID u
 		return nil, err
 	}
 
-	// Cache for future requests if completed
-	if transaction.IsCompleted() {
+	// Cache for future requests if settled. Reversals invalidate the entry.
+	if transaction.IsSettled() {
 		s.cacheRepo.Set(ctx, cacheKey, transaction, 3600) // 1 hour
 	}
 
@@ -355,32 +412,35 @@ func (s *TransactionService) GetTransactionByReference(ctx context.Context, refe
 
 // CancelTransaction cancels a pending transaction
 func (s *TransactionService) CancelTransaction(ctx context.Context, transactionID uuid.UUID, userID *uuid.UUID, ipAddress net.IP, userAgent string) error {
-	transaction, err := s.transactionRepo.GetByID(ctx, transactionID)
-	if err != nil {
-		return err
-	}
+	var transaction *domain.Transaction
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		// Lock the transaction so a worker cannot process it meanwhile
+		var err error
+		transaction, err = repos.Transaction.GetByIDForUpdate(ctx, transactionID)
+		if err != nil {
+			return err
+		}
 
-	if !transaction.IsPending() {
-		return fmt.Errorf("transaction cannot be cancelled, current status: %s", transaction.Status)
-	}
+		if !transaction.IsPending() {
+			return fmt.Errorf("transaction cannot be cancelled, current status: %s", transaction.Status)
+		}
 
-	// Mark as cancelled
-	transaction.MarkCancelled()
-	if err := s.transactionRepo.Update(ctx, transaction); err != nil {
-		return fmt.Errorf("failed to cancel transaction: %w", err)
+		// Mark as cancelled
+		transaction.MarkCancelled()
+		if err := repos.Transaction.Update(ctx, transaction); err != nil {
+			return fmt.Errorf("failed to cancel transaction: %w", err)
+		}
+
+		return appendTransactionStatusChanged(ctx, repos, event.TransactionCancelledEvent, transaction, domain.TransactionStatusPending, "",
+			eventMetadata(userID, ipAddress, userAgent, eventSourceAPI))
+	})
+	if err != nil {
+		return err
 	}
 
 	// Create audit log
-	auditDetails := domain.TransactionAuditDetails{
-		FromUserID:  transaction.FromUserID,
-		ToUserID:    transaction.ToUserID,
-		Amount:      transaction.Amount,
-		Type:        string(transaction.Type),
-		Status:      string(transaction.Status),
-		OldStatus:   string(domain.TransactionStatusPending),
-		Description: transaction.Description,
-		ReferenceID: transaction.ReferenceID,
-	}
+	auditDetails := domain.NewTransactionAuditDetails(transaction)
+	auditDetails.OldStatus = string(domain.TransactionStatusPending)
 
 	auditLog, _ := domain.NewAuditLog(
 		domain.EntityTypeTransaction,
@@ -403,27 +463,122 @@ func (s *TransactionService) CancelTransaction(ctx context.Context, transactionI
 	return nil
 }
 
-// ProcessPendingTransactions processes pending transactions (for batch processing)
+// ReverseTransaction creates a compensating transaction for amount of a
+// settled transaction. A zero amount reverses whatever has not been reversed
+// yet. The original only moves to partially_reversed or reversed once the
+// reversal has been processed.
+func (s *TransactionService) ReverseTransaction(ctx context.Context, transactionID uuid.UUID, req domain.ReverseTransactionRequest, userID *uuid.UUID, ipAddress net.IP, userAgent string) (*domain.Transaction, error) {
+	if req.Reason == "" {
+		return nil, domain.ErrReversalReasonRequired
+	}
+
+	metadata := eventMetadata(userID, ipAddress, userAgent, eventSourceAPI)
+
+	var reversal *domain.Transaction
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := claimIdempotencyKey(ctx, repos); err != nil {
+			return err
+		}
+
+		// Lock the original so concurrent reversals cannot exceed its amount
+		original, err := repos.Transaction.GetByIDForUpdate(ctx, transactionID)
+		if err != nil {
+			return err
+		}
+
+		pending, err := repos.Transaction.GetPendingReversalAmount(ctx, original.ID)
+		if err != nil {
+			return err
+		}
+
+		amount := req.Amount
+		if amount.IsZero() {
+			amount = original.ReversibleAmount().Sub(pending)
+		}
+		if amount.Add(pending) > original.ReversibleAmount() {
+			return fmt.Errorf("%w: %s requested, %s reversible (%s pending)", domain.ErrReversalExceedsAmount, amount, original.ReversibleAmount().Sub(pending), pending)
+		}
+
+		reversal, err = original.NewReversal(amount, req.Reason)
+		if err != nil {
+			return err
+		}
+
+		if err := repos.Transaction.Create(ctx, reversal); err != nil {
+			return fmt.Errorf("failed to save reversal: %w", err)
+		}
+
+		if err := appendTransactionCreated(ctx, repos, reversal, metadata); err != nil {
+			return err
+		}
+
+		if err := s.workerPool.EnqueueTransaction(ctx, repos, reversal.ID); err != nil {
+			return err
+		}
+
+		auditDetails := domain.NewTransactionAuditDetails(original)
+		auditDetails.ReversalTransactionID = &reversal.ID
+		auditDetails.ReversedAmount = &reversal.Amount
+		auditDetails.Reason = req.Reason
+
+		auditLog, err := domain.NewAuditLog(
+			domain.EntityTypeTransaction,
+			domain.ActionReverse,
+			original.ID,
+			auditDetails,
+			userID,
+			ipAddress,
+			userAgent,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to build audit log: %w", err)
+		}
+
+		if err := repos.AuditLog.Create(ctx, auditLog); err != nil {
+			return fmt.Errorf("failed to create audit log: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Wake the worker pool to process the queued reversal
+	s.workerPool.Notify()
+
+	log.Info().
+		Str("transaction_id", reversal.ID.String()).
+		Str("reverses_transaction_id", transactionID.String()).
+		Str("amount", reversal.Amount.String()).
+		Str("currency", reversal.Currency.String()).
+		Str("reason", req.Reason).
+		Msg("Reversal transaction created")
+
+	return reversal, nil
+}
+
+// ProcessPendingTransactions queues the processing of pending transactions
+// that have no unfinished job, such as those created before the job queue
 func (s *TransactionService) ProcessPendingTransactions(ctx context.Context, limit int) error {
 	transactions, err := s.transactionRepo.ListPending(ctx, limit)
 	if err != nil {
 		return fmt.Errorf("failed to list pending transactions: %w", err)
 	}
 
-	for _, transaction := range transactions {
-		job := worker.NewTransactionJob(transaction.ID, &repository.Repositories{
-			Transaction: s.transactionRepo,
-			Balance:     s.balanceRepo,
-			User:        s.userRepo,
-			AuditLog:    s.auditRepo,
-			Cache:       s.cacheRepo,
-		})
-
-		if err := s.workerPool.SubmitJob(job); err != nil {
-			log.Error().Err(err).Str("transaction_id", transaction.ID.String()).Msg("Failed to submit transaction job")
+	err = s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		for _, transaction := range transactions {
+			if err := s.workerPool.EnqueueTransaction(ctx, repos, transaction.ID); err != nil {
+				return fmt.Errorf("failed to enqueue transaction %s: %w", transaction.ID, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.workerPool.Notify()
 
-	log.Info().Int("count", len(transactions)).Msg("Submitted pending transactions for processing")
+	log.Info().Int("count", len(transactions)).Msg("Queued pending transactions for processing")
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
 	"insider-backend/internal/repository"
 	"net"
 	"time"
@@ -14,6 +15,7 @@ import (
 )
 
 type UserService struct {
+	repos       *repository.Repositories
 	userRepo    repository.UserRepository
 	balanceRepo repository.BalanceRepository
 	auditRepo   repository.AuditLogRepository
@@ -32,6 +34,7 @@ type JWTClaims struct {
 
 func NewUserService(repos *repository.Repositories, jwtSecret string, accessTTL, refreshTTL time.Duration) *UserService {
 	return &UserService{
+		repos:       repos,
 		userRepo:    repos.User,
 		balanceRepo: repos.Balance,
 		auditRepo:   repos.AuditLog,
@@ -83,13 +86,25 @@ func (s *UserService) Register(ctx context.Context, req domain.CreateUserRequest
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Save user to database
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		return nil, fmt.Errorf("failed to save user: %w", err)
+	// Save user to database together with its created event
+	err = s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.User.Create(ctx, user); err != nil {
+			return fmt.Errorf("failed to save user: %w", err)
+		}
+
+		return appendEvent(ctx, repos, event.UserCreatedEvent, user.ID, event.UserCreatedEventData{
+			UserID:   user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     string(user.Role),
+		}, eventMetadata(&user.ID, ipAddress, userAgent, eventSourceAPI))
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Create initial balance
-	balance := domain.NewBalance(user.ID)
+	balance := domain.NewBalance(user.ID, domain.DefaultCurrency)
 	if err := s.balanceRepo.Create(ctx, balance); err != nil {
 		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to create initial balance")
 	}
@@ -226,51 +241,47 @@ func (s *UserService) GetUser(ctx context.Context, userID uuid.UUID) (*domain.Us
 
 // UpdateUser updates user information
 func (s *UserService) UpdateUser(ctx context.Context, userID uuid.UUID, req domain.UpdateUserRequest, ipAddress net.IP, userAgent string) (*domain.User, error) {
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	oldUser := *user // Copy for audit
-
-	// Update fields
-	if req.Username != "" {
-		// Check if username is already taken
-		exists, err := s.userRepo.ExistsByUsername(ctx, req.Username)
+	var user *domain.User
+	var oldUser domain.User
+
+	// An update committed after the stream version is read moves the stream
+	// on, so saving fails with a concurrency conflict and the update is redone
+	// on the fresh user instead of overwriting it
+	err := retryOnConflict(ctx, func() error {
+		version, err := s.repos.Outbox.LastVersion(ctx, userID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to check username existence: %w", err)
+			return err
 		}
-		if exists && req.Username != user.Username {
-			return nil, fmt.Errorf("username already exists")
-		}
-		user.Username = req.Username
-	}
 
-	if req.Email != "" {
-		// Check if email is already taken
-		exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
+		user, err = s.userRepo.GetByID(ctx, userID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to check email existence: %w", err)
-		}
-		if exists && req.Email != user.Email {
-			return nil, fmt.Errorf("email already exists")
+			return err
 		}
-		user.Email = req.Email
-	}
 
-	if req.Role != "" {
-		user.Role = domain.UserRole(req.Role)
-	}
-
-	user.UpdatedAt = time.Now()
+		oldUser = *user // Copy for audit
 
-	// Validate updated user
-	if err := user.Validate(); err != nil {
-		return nil, err
-	}
+		if err := s.applyUserUpdate(ctx, user, req); err != nil {
+			return err
+		}
 
-	// Save to database
-	if err := s.userRepo.Update(ctx, user); err != nil {
+		// Save to database together with the updated event
+		return s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+			if err := repos.User.Update(ctx, user); err != nil {
+				return err
+			}
+
+			return appendEventAt(ctx, repos, version, event.UserUpdatedEvent, user.ID, event.UserUpdatedEventData{
+				UserID:      user.ID,
+				Username:    user.Username,
+				Email:       user.Email,
+				Role:        string(user.Role),
+				OldUsername: oldUser.Username,
+				OldEmail:    oldUser.Email,
+				OldRole:     string(oldUser.Role),
+			}, eventMetadata(&user.ID, ipAddress, userAgent, eventSourceAPI))
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -309,6 +320,42 @@ func (s *UserService) UpdateUser(ctx context.Context, userID uuid.UUID, req doma
 	return user, nil
 }
 
+// applyUserUpdate applies the fields set in req to user and validates the result
+func (s *UserService) applyUserUpdate(ctx context.Context, user *domain.User, req domain.UpdateUserRequest) error {
+	if req.Username != "" {
+		// Check if username is already taken
+		exists, err := s.userRepo.ExistsByUsername(ctx, req.Username)
+		if err != nil {
+			return fmt.Errorf("failed to check username existence: %w", err)
+		}
+		if exists && req.Username != user.Username {
+			return fmt.Errorf("username already exists")
+		}
+		user.Username = req.Username
+	}
+
+	if req.Email != "" {
+		// Check if email is already taken
+		exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
+		if err != nil {
+			return fmt.Errorf("failed to check email existence: %w", err)
+		}
+		if exists && req.Email != user.Email {
+			return fmt.Errorf("email already exists")
+		}
+		user.Email = req.Email
+	}
+
+	if req.Role != "" {
+		user.Role = domain.UserRole(req.Role)
+	}
+
+	user.UpdatedAt = time.Now()
+
+	// Validate updated user
+	return user.Validate()
+}
+
 // ListUsers returns a paginated list of users
 func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*domain.User, error) {
 	return s.userRepo.List(ctx, limit, offset)
@@ -321,7 +368,19 @@ func (s *UserService) DeleteUser(ctx context.Context, userID uuid.UUID, ipAddres
 		return err
 	}
 
-	if err := s.userRepo.Delete(ctx, userID); err != nil {
+	err = s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.User.Delete(ctx, userID); err != nil {
+			return err
+		}
+
+		return appendEvent(ctx, repos, event.UserDeletedEvent, user.ID, event.UserDeletedEventData{
+			UserID:   user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     string(user.Role),
+		}, eventMetadata(&user.ID, ipAddress, userAgent, eventSourceAPI))
+	})
+	if err != nil {
 		return err
 	}
 
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
+	"insider-backend/internal/repository"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// webhookDeliveryBatchSize bounds how many deliveries one dispatch run sends
+const webhookDeliveryBatchSize = 50
+
+// Headers sent with every webhook request
+const (
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+type WebhookService struct {
+	repos        *repository.Repositories
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	client       *http.Client
+	timeout      time.Duration
+	maxAttempts  int
+}
+
+// NewWebhookService creates a webhook service. Requests time out after
+// timeout and a delivery becomes dead after maxAttempts failed attempts.
+func NewWebhookService(repos *repository.Repositories, timeout time.Duration, maxAttempts int) *WebhookService {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return &WebhookService{
+		repos:        repos,
+		webhookRepo:  repos.Webhook,
+		deliveryRepo: repos.WebhookDelivery,
+		client:       &http.Client{Timeout: timeout},
+		timeout:      timeout,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+// CreateSubscription creates a webhook subscription
+func (s *WebhookService) CreateSubscription(ctx context.Context, req domain.CreateWebhookRequest) (*domain.WebhookSubscription, error) {
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	subscription, err := domain.NewWebhookSubscription(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepo.Create(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	log.Info().
+		Str("subscription_id", subscription.ID.String()).
+		Str("url", subscription.URL).
+		Strs("event_types", subscription.EventTypes).
+		Msg("Webhook subscription created")
+
+	return subscription, nil
+}
+
+// GetSubscription gets a webhook subscription by ID
+func (s *WebhookService) GetSubscription(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	return s.webhookRepo.GetByID(ctx, id)
+}
+
+// UpdateSubscription applies the fields set in req to a subscription
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id uuid.UUID, req domain.UpdateWebhookRequest) (*domain.WebhookSubscription, error) {
+	if req.EventTypes != nil {
+		if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := subscription.Apply(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepo.Update(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// DeleteSubscription deletes a subscription and its deliveries
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+// ListSubscriptions lists webhook subscriptions
+func (s *WebhookService) ListSubscriptions(ctx context.Context, limit, offset int) ([]*domain.WebhookSubscription, error) {
+	return s.webhookRepo.List(ctx, limit, offset)
+}
+
+// GetSubscriptionDeliveries lists the deliveries of a subscription, optionally
+// filtered by status
+func (s *WebhookService) GetSubscriptionDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit, offset int) ([]*domain.WebhookDelivery, error) {
+	return s.deliveryRepo.GetBySubscriptionID(ctx, subscriptionID, status, limit, offset)
+}
+
+// GetDelivery gets a delivery together with its attempts
+func (s *WebhookService) GetDelivery(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, []*domain.WebhookAttempt, error) {
+	delivery, err := s.deliveryRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts, err := s.deliveryRepo.GetAttempts(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return delivery, attempts, nil
+}
+
+// ListDeadLetters lists the dead letters that have not been replayed
+func (s *WebhookService) ListDeadLetters(ctx context.Context, limit, offset int) ([]*domain.WebhookDeadLetter, error) {
+	return s.deliveryRepo.ListDeadLetters(ctx, limit, offset)
+}
+
+// ReplayDelivery queues a delivered or dead delivery to be sent again
+func (s *WebhookService) ReplayDelivery(ctx context.Context, id uuid.UUID) (*domain.WebhookDelivery, error) {
+	var delivery *domain.WebhookDelivery
+
+	err := s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		var err error
+		delivery, err = repos.WebhookDelivery.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := delivery.Replay(now); err != nil {
+			return err
+		}
+
+		if err := repos.WebhookDelivery.Update(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to update webhook delivery: %w", err)
+		}
+
+		return repos.WebhookDelivery.MarkDeadLettersReplayed(ctx, delivery.ID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("delivery_id", delivery.ID.String()).Msg("Webhook delivery replayed")
+
+	return delivery, nil
+}
+
+// Publish queues a delivery of the event to every active subscription to its
+// type. It implements event.EventPublisher so the outbox relay can feed it.
+// Events published again after a relay retry do not create new deliveries.
+func (s *WebhookService) Publish(evt *event.Event) error {
+	ctx := context.Background()
+
+	subscriptions, err := s.webhookRepo.GetActiveByEventType(ctx, string(evt.Type))
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %s: %w", evt.ID, err)
+	}
+
+	for _, subscription := range subscriptions {
+		delivery := domain.NewWebhookDelivery(subscription.ID, evt.ID, string(evt.Type), payload)
+		if _, err := s.deliveryRepo.Create(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishBatch queues deliveries for events in order, stopping at the first failure
+func (s *WebhookService) PublishBatch(events []*event.Event) error {
+	for _, evt := range events {
+		if err := s.Publish(evt); err != nil {
+			return fmt.Errorf("failed to publish event %s: %w", evt.ID, err)
+		}
+	}
+	return nil
+}
+
+// DeliverDue sends due deliveries and returns how many succeeded. Deliveries
+// are leased while they are sent, so every instance can run it; one left
+// behind by a crashed instance is retried when its lease ends.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	deliveries, err := s.deliveryRepo.ClaimDue(ctx, now, now.Add(2*s.timeout), webhookDeliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		ok, err := s.deliver(ctx, delivery)
+		if err != nil {
+			log.Error().Err(err).Str("delivery_id", delivery.ID.String()).Msg("Failed to record webhook delivery")
+			continue
+		}
+		if ok {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// deliver sends one delivery and records the outcome, reporting whether the
+// subscriber accepted it
+func (s *WebhookService) deliver(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error) {
+	subscription, err := s.webhookRepo.GetByID(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		responseStatus int
+		sendErr        error
+		duration       time.Duration
+	)
+	if subscription.Active {
+		started := time.Now()
+		responseStatus, sendErr = s.send(ctx, subscription, delivery)
+		duration = time.Since(started)
+	} else {
+		sendErr = fmt.Errorf("subscription is inactive")
+	}
+
+	now := time.Now()
+	if sendErr == nil {
+		delivery.MarkDelivered(responseStatus, now)
+	} else {
+		permanent := !subscription.Active || domain.IsPermanentWebhookFailure(responseStatus)
+		delivery.MarkAttemptFailed(sendErr, responseStatus, permanent, now, s.maxAttempts)
+	}
+
+	err = s.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if subscription.Active {
+			attempt := domain.NewWebhookAttempt(delivery.ID, responseStatus, sendErr, duration)
+			if err := repos.WebhookDelivery.CreateAttempt(ctx, attempt); err != nil {
+				return err
+			}
+		}
+
+		if err := repos.WebhookDelivery.Update(ctx, delivery); err != nil {
+			return err
+		}
+
+		if delivery.Status == domain.WebhookDeliveryStatusDead {
+			return repos.WebhookDelivery.CreateDeadLetter(ctx, domain.NewWebhookDeadLetter(delivery))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if sendErr != nil {
+		logEvent := log.Warn()
+		if delivery.Status == domain.WebhookDeliveryStatusDead {
+			logEvent = log.Error()
+		}
+		logEvent.
+			Err(sendErr).
+			Str("delivery_id", delivery.ID.String()).
+			Str("subscription_id", subscription.ID.String()).
+			Str("event_type", delivery.EventType).
+			Int("attempts", delivery.Attempts).
+			Str("status", string(delivery.Status)).
+			Msg("Webhook delivery failed")
+	}
+
+	return sendErr == nil, nil
+}
+
+// send POSTs the signed payload and returns the response status. Any status
+// other than 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery) (int, error) {
+	timestamp := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "insider-backend-webhooks/1.0")
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(WebhookSignatureHeader, subscription.Sign(timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func validateWebhookEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		if !event.IsKnownEventType(eventType) {
+			return fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	return nil
+}
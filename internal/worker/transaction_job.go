@@ -2,19 +2,64 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"insider-backend/internal/domain"
+	"insider-backend/internal/event"
 	"insider-backend/internal/repository"
+	"insider-backend/internal/repository/breaker"
+	"insider-backend/internal/retry"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
 )
 
+// eventSourceWorker is recorded in the metadata of events emitted by jobs
+const eventSourceWorker = "worker"
+
+// TransactionJobType is the type of the queued jobs processing a transaction
+const TransactionJobType = "transaction"
+
+// TransactionJobPolicy retries a queued transaction job that did not fail the
+// transaction, e.g. because the database was down, for up to the pool's
+// attempts with a backoff of up to five minutes
+var TransactionJobPolicy = retry.Policy{
+	BaseDelay: time.Second,
+	MaxDelay:  5 * time.Minute,
+}
+
+// transactionJobPayload is the payload of a queued transaction job
+type transactionJobPayload struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+}
+
+// transactionRetryBudget is shared by all transaction jobs, so that a burst
+// of conflicts or a struggling database cannot keep the workers busy with
+// retries
+var transactionRetryBudget = retry.NewBudget(0.2, 50)
+
+// transactionRetryPolicy redoes the processing of a transaction that lost the
+// race for a wallet or hit a transient database error
+var transactionRetryPolicy = retry.Policy{
+	MaxAttempts: 5,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Retryable:   isRetryable,
+	Budget:      transactionRetryBudget,
+}
+
 // TransactionJob represents a transaction processing job
 type TransactionJob struct {
 	ID            string
 	TransactionID uuid.UUID
 	repositories  *repository.Repositories
+
+	mu       sync.Mutex
+	attempts []retry.Attempt
 }
 
 // NewTransactionJob creates a new transaction job
@@ -26,35 +71,294 @@ func NewTransactionJob(transactionID uuid.UUID, repos *repository.Repositories)
 	}
 }
 
-// Execute processes the transaction
+// NewTransactionJobFactory returns the factory of the queued transaction jobs
+func NewTransactionJobFactory(repos *repository.Repositories) JobFactory {
+	return func(queued *domain.Job) (Job, error) {
+		var payload transactionJobPayload
+		if err := json.Unmarshal(queued.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		return NewTransactionJob(payload.TransactionID, repos), nil
+	}
+}
+
+// EnqueueTransaction queues the processing of a transaction in the unit of
+// work of repos, which must be the one saving the transaction
+func (wp *WorkerPool) EnqueueTransaction(ctx context.Context, repos *repository.Repositories, transactionID uuid.UUID) error {
+	return wp.Enqueue(ctx, repos, TransactionJobType, fmt.Sprintf("transaction-%s", transactionID), transactionJobPayload{TransactionID: transactionID})
+}
+
+// Execute processes the transaction. All balance changes, history rows, the
+// status transition, audit records and events are written in one database
+// transaction, which is redone as transactionRetryPolicy allows when it loses
+// the race for a wallet or the database fails transiently. The transaction
+// fails only when it cannot be processed, and the error is then marked
+// permanent so the queue does not retry the job; any other error, including
+// a race still lost after the last attempt, leaves the transaction pending
+// for the queue to retry.
 func (tj *TransactionJob) Execute(ctx context.Context) error {
 	log.Info().
 		Str("job_id", tj.ID).
 		Str("transaction_id", tj.TransactionID.String()).
 		Msg("Processing transaction")
 
-	// Get the transaction
-	transaction, err := tj.repositories.Transaction.GetByID(ctx, tj.TransactionID)
+	var reversedID *uuid.UUID
+	policy := transactionRetryPolicy
+	policy.OnAttempt = tj.recordAttempt
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		reversedID, err = tj.process(ctx)
+		return err
+	})
+
+	// The job was cancelled, e.g. because its lease was lost, so another
+	// attempt decides the outcome of the transaction
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	var failed *transactionFailedError
+	if errors.As(err, &failed) {
+		// Everything else was rolled back, so record the failure on its own
+		if updateErr := tj.markFailed(ctx, err); updateErr != nil {
+			log.Error().Err(updateErr).Str("transaction_id", tj.TransactionID.String()).Msg("Failed to mark transaction as failed")
+			return err
+		}
+		return retry.Permanent(err)
+	}
+	if errors.Is(err, errNotProcessable) {
+		return retry.Permanent(err)
+	}
+
+	if err == nil && reversedID != nil && tj.repositories.Cache != nil {
+		if cacheErr := tj.repositories.Cache.Delete(ctx, fmt.Sprintf("transaction:%s", reversedID.String())); cacheErr != nil {
+			log.Warn().Err(cacheErr).Str("transaction_id", reversedID.String()).Msg("Failed to invalidate transaction cache")
+		}
+	}
+
+	return err
+}
+
+// process makes one attempt at processing the transaction and returns the ID
+// of the transaction it reverses, if any
+func (tj *TransactionJob) process(ctx context.Context) (*uuid.UUID, error) {
+	var reversedID *uuid.UUID
+	err := tj.repositories.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		// Lock the transaction so it cannot be processed twice concurrently
+		transaction, err := repos.Transaction.GetByIDForUpdate(ctx, tj.TransactionID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		// Check if transaction can be processed
+		if !transaction.CanBeProcessed() {
+			return fmt.Errorf("transaction %s %w, status: %s", transaction.ID, errNotProcessable, transaction.Status)
+		}
+
+		if transaction.IsReversal() {
+			reversedID = transaction.ReversesTransactionID
+			if err := tj.applyReversal(ctx, repos, transaction); err != nil {
+				return err
+			}
+		}
+
+		// Process based on transaction type
+		switch transaction.Type {
+		case domain.TransactionTypeCredit:
+			return tj.processCredit(ctx, repos, transaction)
+		case domain.TransactionTypeDebit:
+			return tj.processDebit(ctx, repos, transaction)
+		case domain.TransactionTypeTransfer:
+			return tj.processTransfer(ctx, repos, transaction)
+		default:
+			return failTransaction(fmt.Errorf("unknown transaction type: %s", transaction.Type))
+		}
+	})
+	return reversedID, err
+}
+
+// recordAttempt keeps an attempt at processing the transaction
+func (tj *TransactionJob) recordAttempt(attempt retry.Attempt) {
+	tj.mu.Lock()
+	tj.attempts = append(tj.attempts, attempt)
+	tj.mu.Unlock()
+
+	if attempt.Error != "" && attempt.GaveUp == "" {
+		log.Warn().
+			Str("job_id", tj.ID).
+			Int("attempt", attempt.Number).
+			Dur("delay", attempt.Delay).
+			Str("error", attempt.Error).
+			Msg("Retrying transaction")
+	}
+}
+
+// Attempts returns the attempts made at processing the transaction so far
+func (tj *TransactionJob) Attempts() []retry.Attempt {
+	tj.mu.Lock()
+	defer tj.mu.Unlock()
+
+	return append([]retry.Attempt(nil), tj.attempts...)
+}
+
+// isRetryable reports whether processing that failed with err may succeed if
+// it is redone: it lost the race for a wallet, or the database failed
+// transiently. Failures of the transaction itself are final.
+func isRetryable(err error) bool {
+	var failed *transactionFailedError
+	if errors.As(err, &failed) {
+		return false
+	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return true
+	}
+
+	// Serialization failures and deadlocks
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01") {
+		return true
+	}
+
+	return breaker.IsPostgresFailure(err)
+}
+
+// errNotProcessable is returned for a transaction that was already processed,
+// e.g. by a job claimed again after its lease expired
+var errNotProcessable = errors.New("cannot be processed")
+
+// balanceUpdateError fails the transaction unless saving a balance lost the
+// race with a concurrent update, which is retried
+func balanceUpdateError(message string, err error) error {
+	err = fmt.Errorf("%s: %w", message, err)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return err
+	}
+	return failTransaction(err)
+}
+
+// balanceLookupError fails the transaction unless getting or creating its
+// wallet failed transiently or raced with a concurrent creation of the
+// wallet, which is retried
+func balanceLookupError(message string, err error) error {
+	err = fmt.Errorf("%s: %w", message, err)
+	var pqErr *pq.Error
+	if isRetryable(err) || (errors.As(err, &pqErr) && pqErr.Code == "23505") {
+		return err
+	}
+	return failTransaction(err)
+}
+
+// transactionFailedError marks an error after which the transaction must be
+// moved to failed rather than left pending for another attempt
+type transactionFailedError struct {
+	err error
+}
+
+func (e *transactionFailedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transactionFailedError) Unwrap() error {
+	return e.err
+}
+
+func failTransaction(err error) error {
+	return &transactionFailedError{err: err}
+}
+
+// markFailed moves the transaction to failed because of cause in a unit of
+// work of its own
+func (tj *TransactionJob) markFailed(ctx context.Context, cause error) error {
+	return tj.repositories.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		return tj.failPending(ctx, repos, cause.Error())
+	})
+}
+
+// OnDead fails the transaction when its job was dead-lettered or discarded,
+// as no attempt will process it anymore
+func (tj *TransactionJob) OnDead(ctx context.Context, repos *repository.Repositories, queued *domain.Job) error {
+	return tj.failPending(ctx, repos, fmt.Sprintf("processing gave up after %d attempts: %s", queued.Attempts, queued.LastError))
+}
+
+// failPending moves the transaction to failed for reason in the unit of work
+// of repos, unless it is no longer pending. A hold it was capturing becomes
+// active again, its funds are still reserved and it can be captured anew.
+func (tj *TransactionJob) failPending(ctx context.Context, repos *repository.Repositories, reason string) error {
+	transaction, err := repos.Transaction.GetByIDForUpdate(ctx, tj.TransactionID)
 	if err != nil {
-		return fmt.Errorf("failed to get transaction: %w", err)
+		return err
 	}
 
-	// Check if transaction can be processed
+	// Another attempt processed or failed the transaction meanwhile
 	if !transaction.CanBeProcessed() {
-		return fmt.Errorf("transaction %s cannot be processed, status: %s", transaction.ID, transaction.Status)
+		return nil
 	}
 
-	// Process based on transaction type
-	switch transaction.Type {
-	case domain.TransactionTypeCredit:
-		return tj.processCredit(ctx, transaction)
-	case domain.TransactionTypeDebit:
-		return tj.processDebit(ctx, transaction)
-	case domain.TransactionTypeTransfer:
-		return tj.processTransfer(ctx, transaction)
-	default:
-		return fmt.Errorf("unknown transaction type: %s", transaction.Type)
+	if err := repos.Transaction.UpdateStatus(ctx, transaction.ID, domain.TransactionStatusFailed); err != nil {
+		return err
 	}
+
+	err = tj.appendEvent(ctx, repos, event.TransactionFailedEvent, transaction.ID, event.TransactionStatusChangedEventData{
+		TransactionID: transaction.ID,
+		OldStatus:     string(transaction.Status),
+		NewStatus:     string(domain.TransactionStatusFailed),
+		Reason:        reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	if transaction.HoldID == nil {
+		return nil
+	}
+
+	hold, err := repos.Hold.GetByIDForUpdate(ctx, *transaction.HoldID)
+	if err != nil {
+		return err
+	}
+	if hold.Status != domain.HoldStatusCaptured || hold.TransactionID == nil || *hold.TransactionID != transaction.ID {
+		return nil
+	}
+
+	hold.Reopen()
+	return repos.Hold.Update(ctx, hold)
+}
+
+// appendEvent appends an event to its aggregate's stream in the unit of work
+// of repos, so it commits or rolls back with the change it describes
+func (tj *TransactionJob) appendEvent(ctx context.Context, repos *repository.Repositories, eventType event.EventType, aggregateID uuid.UUID, data interface{}) error {
+	evt, err := event.NewEvent(eventType, aggregateID, data, event.Metadata{Source: eventSourceWorker}, 0)
+	if err != nil {
+		return fmt.Errorf("failed to build %s event: %w", eventType, err)
+	}
+
+	if err := repos.Outbox.Append(ctx, evt); err != nil {
+		return fmt.Errorf("failed to append %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// appendBalanceChange appends the credited or debited event of a balance
+func (tj *TransactionJob) appendBalanceChange(ctx context.Context, repos *repository.Repositories, eventType event.EventType, balance *domain.Balance, previousAmount, amount domain.Money, operation string, transactionID uuid.UUID) error {
+	return tj.appendEvent(ctx, repos, eventType, event.BalanceAggregateID(balance.UserID, balance.Currency), event.BalanceChangedEventData{
+		UserID:        balance.UserID,
+		Currency:      balance.Currency,
+		OldBalance:    previousAmount,
+		NewBalance:    balance.GetAmount(),
+		Amount:        amount,
+		Operation:     operation,
+		TransactionID: &transactionID,
+	})
+}
+
+// appendCompleted appends the completed event of a processed transaction
+func (tj *TransactionJob) appendCompleted(ctx context.Context, repos *repository.Repositories, transaction *domain.Transaction) error {
+	return tj.appendEvent(ctx, repos, event.TransactionCompletedEvent, transaction.ID, event.TransactionStatusChangedEventData{
+		TransactionID: transaction.ID,
+		OldStatus:     string(domain.TransactionStatusPending),
+		NewStatus:     string(transaction.Status),
+	})
 }
 
 // GetID returns the job ID
@@ -64,60 +368,139 @@ func (tj *TransactionJob) GetID() string {
 
 // GetType returns the job type
 func (tj *TransactionJob) GetType() string {
-	return "transaction"
+	return TransactionJobType
+}
+
+// applyReversal records the reversal on the original transaction. The
+// original is locked so its reversals never add up to more than its amount.
+func (tj *TransactionJob) applyReversal(ctx context.Context, repos *repository.Repositories, reversal *domain.Transaction) error {
+	original, err := repos.Transaction.GetByIDForUpdate(ctx, *reversal.ReversesTransactionID)
+	if err != nil {
+		return fmt.Errorf("failed to get reversed transaction: %w", err)
+	}
+
+	if !original.IsSettled() {
+		return failTransaction(fmt.Errorf("transaction %s cannot be reversed, status: %s", original.ID, original.Status))
+	}
+
+	oldStatus := original.Status
+	if err := original.ApplyReversal(reversal.Amount); err != nil {
+		return failTransaction(err)
+	}
+
+	if err := repos.Transaction.Update(ctx, original); err != nil {
+		return fmt.Errorf("failed to update reversed transaction: %w", err)
+	}
+
+	err = tj.appendEvent(ctx, repos, event.TransactionReversedEvent, original.ID, event.TransactionStatusChangedEventData{
+		TransactionID: original.ID,
+		OldStatus:     string(oldStatus),
+		NewStatus:     string(original.Status),
+		Reason:        reversal.Description,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("transaction_id", reversal.ID.String()).
+		Str("reverses_transaction_id", original.ID.String()).
+		Str("reversed_amount", original.ReversedAmount.String()).
+		Str("status", string(original.Status)).
+		Msg("Reversal applied to original transaction")
+
+	return nil
+}
+
+// capturedHoldAmount returns the amount reserved by the hold the transaction
+// captures, or zero when it captures none. The whole amount is released when
+// the transaction is debited, which frees the remainder of a partial capture.
+func (tj *TransactionJob) capturedHoldAmount(ctx context.Context, repos *repository.Repositories, transaction *domain.Transaction, balance *domain.Balance) (domain.Money, error) {
+	if transaction.HoldID == nil {
+		return 0, nil
+	}
+
+	hold, err := repos.Hold.GetByIDForUpdate(ctx, *transaction.HoldID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	if hold.Status != domain.HoldStatusCaptured || hold.TransactionID == nil || *hold.TransactionID != transaction.ID {
+		return 0, failTransaction(fmt.Errorf("hold %s is not captured by transaction %s, status: %s", hold.ID, transaction.ID, hold.Status))
+	}
+	if hold.UserID != balance.UserID || hold.Currency != balance.Currency {
+		return 0, failTransaction(fmt.Errorf("hold %s does not belong to the %s wallet of user %s", hold.ID, balance.Currency, balance.UserID))
+	}
+
+	return hold.Amount, nil
+}
+
+// postToLedger writes the balanced postings of the transaction to the ledger
+func (tj *TransactionJob) postToLedger(ctx context.Context, repos *repository.Repositories, transaction *domain.Transaction) error {
+	entry, err := domain.NewJournalEntryForTransaction(transaction)
+	if err != nil {
+		return failTransaction(fmt.Errorf("failed to build journal entry: %w", err))
+	}
+
+	if err := repos.Ledger.CreateEntry(ctx, entry); err != nil {
+		return fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	return nil
 }
 
 // processCredit processes a credit transaction
-func (tj *TransactionJob) processCredit(ctx context.Context, transaction *domain.Transaction) error {
+func (tj *TransactionJob) processCredit(ctx context.Context, repos *repository.Repositories, transaction *domain.Transaction) error {
 	if transaction.ToUserID == nil {
-		return fmt.Errorf("to_user_id is required for credit transaction")
+		return failTransaction(fmt.Errorf("to_user_id is required for credit transaction"))
 	}
 
 	// Get user balance
-	balance, err := tj.repositories.Balance.GetByUserID(ctx, *transaction.ToUserID)
+	balance, err := repos.Balance.GetByUserIDAndCurrency(ctx, *transaction.ToUserID, transaction.Currency)
 	if err != nil {
-		return fmt.Errorf("failed to get balance: %w", err)
+		return balanceLookupError("failed to get balance", err)
 	}
 
 	previousAmount := balance.GetAmount()
 
 	// Credit the amount
 	if err := balance.Credit(transaction.Amount); err != nil {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("failed to credit balance: %w", err)
+		return failTransaction(fmt.Errorf("failed to credit balance: %w", err))
 	}
 
 	// Update balance in database
-	if err := tj.repositories.Balance.UpdateWithLock(ctx, balance); err != nil {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("failed to update balance: %w", err)
+	if err := repos.Balance.UpdateWithLock(ctx, balance); err != nil {
+		return balanceUpdateError("failed to update balance", err)
 	}
 
 	// Create balance history
-	history := domain.NewBalanceHistory(*transaction.ToUserID, transaction.ID, balance.GetAmount(), previousAmount)
-	if err := tj.repositories.Balance.CreateHistory(ctx, history); err != nil {
-		log.Warn().Err(err).Msg("Failed to create balance history")
+	history := domain.NewBalanceHistory(*transaction.ToUserID, transaction.ID, transaction.Currency, balance.GetAmount(), previousAmount)
+	if err := repos.Balance.CreateHistory(ctx, history); err != nil {
+		return fmt.Errorf("failed to create balance history: %w", err)
+	}
+
+	// Record the journal entry the updated balances are projected from
+	if err := tj.postToLedger(ctx, repos, transaction); err != nil {
+		return err
 	}
 
 	// Mark transaction as completed
 	transaction.MarkCompleted()
-	if err := tj.repositories.Transaction.Update(ctx, transaction); err != nil {
-		log.Error().Err(err).Msg("Failed to update transaction status")
-		return err
+	if err := repos.Transaction.Update(ctx, transaction); err != nil {
+		return fmt.Errorf("failed to update transaction status: %w", err)
 	}
 
 	// Create audit log
 	auditDetails := domain.BalanceAuditDetails{
 		UserID:         *transaction.ToUserID,
+		Currency:       transaction.Currency,
 		Amount:         balance.GetAmount(),
 		PreviousAmount: previousAmount,
 		TransactionID:  &transaction.ID,
 		Operation:      "credit",
 	}
 
-	auditLog, _ := domain.NewAuditLog(
+	auditLog, err := domain.NewAuditLog(
 		domain.EntityTypeBalance,
 		domain.ActionCredit,
 		*transaction.ToUserID,
@@ -126,14 +509,26 @@ func (tj *TransactionJob) processCredit(ctx context.Context, transaction *domain
 		nil,
 		"",
 	)
+	if err != nil {
+		return fmt.Errorf("failed to build audit log: %w", err)
+	}
 
-	if err := tj.repositories.AuditLog.Create(ctx, auditLog); err != nil {
-		log.Warn().Err(err).Msg("Failed to create audit log")
+	if err := repos.AuditLog.Create(ctx, auditLog); err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	if err := tj.appendBalanceChange(ctx, repos, event.BalanceCreditedEvent, balance, previousAmount, transaction.Amount, "credit", transaction.ID); err != nil {
+		return err
+	}
+
+	if err := tj.appendCompleted(ctx, repos, transaction); err != nil {
+		return err
 	}
 
 	log.Info().
 		Str("transaction_id", transaction.ID.String()).
-		Float64("amount", transaction.Amount).
+		Str("amount", transaction.Amount.String()).
+		Str("currency", transaction.Currency.String()).
 		Str("user_id", transaction.ToUserID.String()).
 		Msg("Credit transaction completed")
 
@@ -141,63 +536,68 @@ func (tj *TransactionJob) processCredit(ctx context.Context, transaction *domain
 }
 
 // processDebit processes a debit transaction
-func (tj *TransactionJob) processDebit(ctx context.Context, transaction *domain.Transaction) error {
+func (tj *TransactionJob) processDebit(ctx context.Context, repos *repository.Repositories, transaction *domain.Transaction) error {
 	if transaction.FromUserID == nil {
-		return fmt.Errorf("from_user_id is required for debit transaction")
+		return failTransaction(fmt.Errorf("from_user_id is required for debit transaction"))
 	}
 
 	// Get user balance
-	balance, err := tj.repositories.Balance.GetByUserID(ctx, *transaction.FromUserID)
+	balance, err := repos.Balance.GetByUserIDAndCurrency(ctx, *transaction.FromUserID, transaction.Currency)
 	if err != nil {
-		return fmt.Errorf("failed to get balance: %w", err)
+		return balanceLookupError("failed to get balance", err)
 	}
 
 	previousAmount := balance.GetAmount()
 
+	// A captured hold pays for the debit with the funds it reserved
+	held, err := tj.capturedHoldAmount(ctx, repos, transaction, balance)
+	if err != nil {
+		return err
+	}
+
 	// Check if sufficient balance
-	if !balance.HasSufficientBalance(transaction.Amount) {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("insufficient balance: have %.2f, need %.2f", balance.GetAmount(), transaction.Amount)
+	if !balance.HasSufficientBalance(transaction.Amount.Sub(held)) {
+		return failTransaction(fmt.Errorf("insufficient balance: have %s available, need %s", balance.GetAvailable().Add(held), transaction.Amount))
 	}
 
 	// Debit the amount
-	if err := balance.Debit(transaction.Amount); err != nil {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("failed to debit balance: %w", err)
+	if err := balance.DebitHeld(held, transaction.Amount); err != nil {
+		return failTransaction(fmt.Errorf("failed to debit balance: %w", err))
 	}
 
 	// Update balance in database
-	if err := tj.repositories.Balance.UpdateWithLock(ctx, balance); err != nil {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("failed to update balance: %w", err)
+	if err := repos.Balance.UpdateWithLock(ctx, balance); err != nil {
+		return balanceUpdateError("failed to update balance", err)
 	}
 
 	// Create balance history
-	history := domain.NewBalanceHistory(*transaction.FromUserID, transaction.ID, balance.GetAmount(), previousAmount)
-	if err := tj.repositories.Balance.CreateHistory(ctx, history); err != nil {
-		log.Warn().Err(err).Msg("Failed to create balance history")
+	history := domain.NewBalanceHistory(*transaction.FromUserID, transaction.ID, transaction.Currency, balance.GetAmount(), previousAmount)
+	if err := repos.Balance.CreateHistory(ctx, history); err != nil {
+		return fmt.Errorf("failed to create balance history: %w", err)
+	}
+
+	// Record the journal entry the updated balances are projected from
+	if err := tj.postToLedger(ctx, repos, transaction); err != nil {
+		return err
 	}
 
 	// Mark transaction as completed
 	transaction.MarkCompleted()
-	if err := tj.repositories.Transaction.Update(ctx, transaction); err != nil {
-		log.Error().Err(err).Msg("Failed to update transaction status")
-		return err
+	if err := repos.Transaction.Update(ctx, transaction); err != nil {
+		return fmt.Errorf("failed to update transaction status: %w", err)
 	}
 
 	// Create audit log
 	auditDetails := domain.BalanceAuditDetails{
 		UserID:         *transaction.FromUserID,
+		Currency:       transaction.Currency,
 		Amount:         balance.GetAmount(),
 		PreviousAmount: previousAmount,
 		TransactionID:  &transaction.ID,
 		Operation:      "debit",
 	}
 
-	auditLog, _ := domain.NewAuditLog(
+	auditLog, err := domain.NewAuditLog(
 		domain.EntityTypeBalance,
 		domain.ActionDebit,
 		*transaction.FromUserID,
@@ -206,14 +606,26 @@ func (tj *TransactionJob) processDebit(ctx context.Context, transaction *domain.
 		nil,
 		"",
 	)
+	if err != nil {
+		return fmt.Errorf("failed to build audit log: %w", err)
+	}
 
-	if err := tj.repositories.AuditLog.Create(ctx, auditLog); err != nil {
-		log.Warn().Err(err).Msg("Failed to create audit log")
+	if err := repos.AuditLog.Create(ctx, auditLog); err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	if err := tj.appendBalanceChange(ctx, repos, event.BalanceDebitedEvent, balance, previousAmount, transaction.Amount, "debit", transaction.ID); err != nil {
+		return err
+	}
+
+	if err := tj.appendCompleted(ctx, repos, transaction); err != nil {
+		return err
 	}
 
 	log.Info().
 		Str("transaction_id", transaction.ID.String()).
-		Float64("amount", transaction.Amount).
+		Str("amount", transaction.Amount.String()).
+		Str("currency", transaction.Currency.String()).
 		Str("user_id", transaction.FromUserID.String()).
 		Msg("Debit transaction completed")
 
@@ -221,91 +633,98 @@ func (tj *TransactionJob) processDebit(ctx context.Context, transaction *domain.
 }
 
 // processTransfer processes a transfer transaction
-func (tj *TransactionJob) processTransfer(ctx context.Context, transaction *domain.Transaction) error {
+func (tj *TransactionJob) processTransfer(ctx context.Context, repos *repository.Repositories, transaction *domain.Transaction) error {
 	if transaction.FromUserID == nil || transaction.ToUserID == nil {
-		return fmt.Errorf("both from_user_id and to_user_id are required for transfer transaction")
+		return failTransaction(fmt.Errorf("both from_user_id and to_user_id are required for transfer transaction"))
 	}
 
 	// Get both balances
-	fromBalance, err := tj.repositories.Balance.GetByUserID(ctx, *transaction.FromUserID)
+	fromBalance, err := repos.Balance.GetByUserIDAndCurrency(ctx, *transaction.FromUserID, transaction.Currency)
 	if err != nil {
-		return fmt.Errorf("failed to get from balance: %w", err)
+		return balanceLookupError("failed to get from balance", err)
 	}
 
-	toBalance, err := tj.repositories.Balance.GetByUserID(ctx, *transaction.ToUserID)
+	// Cross-currency transfers credit the receiver's wallet in the converted currency
+	toBalance, err := repos.Balance.GetByUserIDAndCurrency(ctx, *transaction.ToUserID, transaction.CreditCurrency())
 	if err != nil {
-		return fmt.Errorf("failed to get to balance: %w", err)
+		return balanceLookupError("failed to get to balance", err)
+	}
+
+	// A captured hold pays for the transfer with the funds it reserved
+	held, err := tj.capturedHoldAmount(ctx, repos, transaction, fromBalance)
+	if err != nil {
+		return err
 	}
 
 	// Check if sufficient balance
-	if !fromBalance.HasSufficientBalance(transaction.Amount) {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("insufficient balance: have %.2f, need %.2f", fromBalance.GetAmount(), transaction.Amount)
+	if !fromBalance.HasSufficientBalance(transaction.Amount.Sub(held)) {
+		return failTransaction(fmt.Errorf("insufficient balance: have %s available, need %s", fromBalance.GetAvailable().Add(held), transaction.Amount))
 	}
 
 	previousFromAmount := fromBalance.GetAmount()
 	previousToAmount := toBalance.GetAmount()
 
 	// Debit from sender
-	if err := fromBalance.Debit(transaction.Amount); err != nil {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("failed to debit from balance: %w", err)
+	if err := fromBalance.DebitHeld(held, transaction.Amount); err != nil {
+		return failTransaction(fmt.Errorf("failed to debit from balance: %w", err))
 	}
 
 	// Credit to receiver
-	if err := toBalance.Credit(transaction.Amount); err != nil {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("failed to credit to balance: %w", err)
+	if err := toBalance.Credit(transaction.CreditAmount()); err != nil {
+		return failTransaction(fmt.Errorf("failed to credit to balance: %w", err))
 	}
 
 	// Update both balances atomically
 	balances := []*domain.Balance{fromBalance, toBalance}
-	if err := tj.repositories.Balance.BatchUpdate(ctx, balances); err != nil {
-		transaction.MarkFailed()
-		tj.repositories.Transaction.Update(ctx, transaction)
-		return fmt.Errorf("failed to update balances: %w", err)
+	if err := repos.Balance.BatchUpdate(ctx, balances); err != nil {
+		return balanceUpdateError("failed to update balances", err)
 	}
 
 	// Create balance histories
-	fromHistory := domain.NewBalanceHistory(*transaction.FromUserID, transaction.ID, fromBalance.GetAmount(), previousFromAmount)
-	toHistory := domain.NewBalanceHistory(*transaction.ToUserID, transaction.ID, toBalance.GetAmount(), previousToAmount)
+	fromHistory := domain.NewBalanceHistory(*transaction.FromUserID, transaction.ID, transaction.Currency, fromBalance.GetAmount(), previousFromAmount)
+	toHistory := domain.NewBalanceHistory(*transaction.ToUserID, transaction.ID, transaction.CreditCurrency(), toBalance.GetAmount(), previousToAmount)
 
-	if err := tj.repositories.Balance.CreateHistory(ctx, fromHistory); err != nil {
-		log.Warn().Err(err).Msg("Failed to create from balance history")
+	if err := repos.Balance.CreateHistory(ctx, fromHistory); err != nil {
+		return fmt.Errorf("failed to create from balance history: %w", err)
 	}
 
-	if err := tj.repositories.Balance.CreateHistory(ctx, toHistory); err != nil {
-		log.Warn().Err(err).Msg("Failed to create to balance history")
+	if err := repos.Balance.CreateHistory(ctx, toHistory); err != nil {
+		return fmt.Errorf("failed to create to balance history: %w", err)
+	}
+
+	// Record the journal entry the updated balances are projected from
+	if err := tj.postToLedger(ctx, repos, transaction); err != nil {
+		return err
 	}
 
 	// Mark transaction as completed
 	transaction.MarkCompleted()
-	if err := tj.repositories.Transaction.Update(ctx, transaction); err != nil {
-		log.Error().Err(err).Msg("Failed to update transaction status")
-		return err
+	if err := repos.Transaction.Update(ctx, transaction); err != nil {
+		return fmt.Errorf("failed to update transaction status: %w", err)
 	}
 
 	// Create audit logs
 	fromAuditDetails := domain.BalanceAuditDetails{
 		UserID:         *transaction.FromUserID,
+		Currency:       transaction.Currency,
 		Amount:         fromBalance.GetAmount(),
 		PreviousAmount: previousFromAmount,
 		TransactionID:  &transaction.ID,
 		Operation:      "transfer_out",
+		FXRate:         transaction.FXRate,
 	}
 
 	toAuditDetails := domain.BalanceAuditDetails{
 		UserID:         *transaction.ToUserID,
+		Currency:       transaction.CreditCurrency(),
 		Amount:         toBalance.GetAmount(),
 		PreviousAmount: previousToAmount,
 		TransactionID:  &transaction.ID,
 		Operation:      "transfer_in",
+		FXRate:         transaction.FXRate,
 	}
 
-	fromAuditLog, _ := domain.NewAuditLog(
+	fromAuditLog, err := domain.NewAuditLog(
 		domain.EntityTypeBalance,
 		domain.ActionTransfer,
 		*transaction.FromUserID,
@@ -314,8 +733,11 @@ func (tj *TransactionJob) processTransfer(ctx context.Context, transaction *doma
 		nil,
 		"",
 	)
+	if err != nil {
+		return fmt.Errorf("failed to build audit log: %w", err)
+	}
 
-	toAuditLog, _ := domain.NewAuditLog(
+	toAuditLog, err := domain.NewAuditLog(
 		domain.EntityTypeBalance,
 		domain.ActionTransfer,
 		*transaction.ToUserID,
@@ -324,18 +746,36 @@ func (tj *TransactionJob) processTransfer(ctx context.Context, transaction *doma
 		nil,
 		"",
 	)
+	if err != nil {
+		return fmt.Errorf("failed to build audit log: %w", err)
+	}
+
+	if err := repos.AuditLog.Create(ctx, fromAuditLog); err != nil {
+		return fmt.Errorf("failed to create from audit log: %w", err)
+	}
+
+	if err := repos.AuditLog.Create(ctx, toAuditLog); err != nil {
+		return fmt.Errorf("failed to create to audit log: %w", err)
+	}
 
-	if err := tj.repositories.AuditLog.Create(ctx, fromAuditLog); err != nil {
-		log.Warn().Err(err).Msg("Failed to create from audit log")
+	if err := tj.appendBalanceChange(ctx, repos, event.BalanceDebitedEvent, fromBalance, previousFromAmount, transaction.Amount, "transfer_out", transaction.ID); err != nil {
+		return err
 	}
 
-	if err := tj.repositories.AuditLog.Create(ctx, toAuditLog); err != nil {
-		log.Warn().Err(err).Msg("Failed to create to audit log")
+	if err := tj.appendBalanceChange(ctx, repos, event.BalanceCreditedEvent, toBalance, previousToAmount, transaction.CreditAmount(), "transfer_in", transaction.ID); err != nil {
+		return err
+	}
+
+	if err := tj.appendCompleted(ctx, repos, transaction); err != nil {
+		return err
 	}
 
 	log.Info().
 		Str("transaction_id", transaction.ID.String()).
-		Float64("amount", transaction.Amount).
+		Str("amount", transaction.Amount.String()).
+		Str("currency", transaction.Currency.String()).
+		Str("to_amount", transaction.CreditAmount().String()).
+		Str("to_currency", transaction.CreditCurrency().String()).
 		Str("from_user_id", transaction.FromUserID.String()).
 		Str("to_user_id", transaction.ToUserID.String()).
 		Msg("Transfer transaction completed")
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"insider-backend/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, true},
+		{"wrapped deadlock", fmt.Errorf("update balance: %w", &pq.Error{Code: "40P01"}), true},
+		{"version conflict", repository.ErrVersionConflict, true},
+		{"wrapped version conflict", balanceUpdateError("failed to save balance", repository.ErrVersionConflict), true},
+		{"connection failure", &pq.Error{Code: "08006"}, true},
+		{"bad connection", driver.ErrBadConn, true},
+		{"connection timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, true},
+		{"caller deadline", fmt.Errorf("get balance: %w", context.DeadlineExceeded), false},
+		{"caller deadline on dial", &net.OpError{Op: "dial", Net: "tcp", Err: context.DeadlineExceeded}, false},
+		{"caller cancelled", context.Canceled, false},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"failed transaction", failTransaction(errors.New("insufficient balance")), false},
+		{"failed on balance update", balanceUpdateError("failed to save balance", errors.New("constraint")), false},
+		{"failed on deadlock", failTransaction(&pq.Error{Code: "40P01"}), false},
+		{"failed on wallet lookup", balanceLookupError("failed to get balance", &pq.Error{Code: "23503"}), false},
+		{"wallet lookup on bad connection", balanceLookupError("failed to get balance", driver.ErrBadConn), true},
+		{"not processable", errNotProcessable, false},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
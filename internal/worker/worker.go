@@ -2,7 +2,11 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"insider-backend/internal/domain"
+	"insider-backend/internal/repository"
+	"insider-backend/internal/retry"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,31 +14,91 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// Pool defaults
+const (
+	defaultPollInterval      = time.Second
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultMaxAttempts       = 5
+	pruneInterval            = time.Hour
+)
+
 type Job interface {
 	Execute(ctx context.Context) error
 	GetID() string
 	GetType() string
 }
 
+// DeadJob is implemented by jobs that settle their work when they are
+// dead-lettered or discarded. OnDead runs in the unit of work of repos that
+// records the outcome of the job.
+type DeadJob interface {
+	OnDead(ctx context.Context, repos *repository.Repositories, queued *domain.Job) error
+}
+
+// JobFactory builds the job a queued job of its type stands for from its
+// payload. An error fails the queued job without retrying it.
+type JobFactory func(queued *domain.Job) (Job, error)
+
+// jobHandler runs the jobs of a type
+type jobHandler struct {
+	factory JobFactory
+	// policy sets the attempts of the jobs, the backoff between them and the
+	// errors that are retried
+	policy retry.Policy
+}
+
 type JobResult struct {
 	JobID string
 	Error error
 }
 
+// PoolConfig configures a worker pool
+type PoolConfig struct {
+	Workers int
+	// Name identifies the instance in the leases of the jobs it claims
+	Name string
+	// PollInterval is the wait before looking for due jobs again when none
+	// were found
+	PollInterval time.Duration
+	// VisibilityTimeout is the lease of a claimed job, renewed while it
+	// executes. A job whose worker did not report back within it is claimed
+	// again. Its execution is cancelled when it runs for longer or its lease
+	// is lost to another worker.
+	VisibilityTimeout time.Duration
+	// MaxAttempts is the number of attempts of an enqueued job whose type
+	// policy does not set one
+	MaxAttempts int
+	// Retention is how long completed, failed and discarded jobs are kept,
+	// zero keeps them forever
+	Retention time.Duration
+}
+
+// WorkerPool runs the jobs of the persistent job queue. A dispatcher claims
+// due jobs for idle workers only, so jobs waiting in the queue stay
+// available to the other instances consuming it. Every attempt is recorded,
+// and a job that runs out of attempts is kept as a dead letter until it is
+// requeued or discarded.
 type WorkerPool struct {
-	workerCount   int
-	jobQueue      chan Job
+	config        PoolConfig
+	repos         *repository.Repositories
+	handlers      map[string]jobHandler
+	jobQueue      chan *domain.Job
+	ready         chan struct{}
+	wake          chan struct{}
 	resultChannel chan JobResult
 	workers       []*Worker
 	wg            sync.WaitGroup
 	ctx           context.Context
 	cancel        context.CancelFunc
+	dispatchStop  context.CancelFunc
+	dispatchDone  chan struct{}
 	metrics       *WorkerMetrics
 }
 
 type Worker struct {
 	id         int
-	jobQueue   chan Job
+	pool       *WorkerPool
+	jobQueue   chan *domain.Job
 	resultChan chan JobResult
 	ctx        context.Context
 	metrics    *WorkerMetrics
@@ -49,28 +113,60 @@ type WorkerMetrics struct {
 	mu               sync.RWMutex
 }
 
-// NewWorkerPool creates a new worker pool
-func NewWorkerPool(workerCount, queueSize int) *WorkerPool {
+// NewWorkerPool creates a new worker pool consuming the job queue of repos
+func NewWorkerPool(repos *repository.Repositories, config PoolConfig) *WorkerPool {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &WorkerPool{
-		workerCount:   workerCount,
-		jobQueue:      make(chan Job, queueSize),
-		resultChannel: make(chan JobResult, queueSize),
-		workers:       make([]*Worker, workerCount),
+		config:        config,
+		repos:         repos,
+		handlers:      make(map[string]jobHandler),
+		jobQueue:      make(chan *domain.Job),
+		ready:         make(chan struct{}, config.Workers),
+		wake:          make(chan struct{}, 1),
+		resultChannel: make(chan JobResult, config.Workers),
+		workers:       make([]*Worker, config.Workers),
 		ctx:           ctx,
 		cancel:        cancel,
+		dispatchDone:  make(chan struct{}),
 		metrics:       &WorkerMetrics{},
 	}
 }
 
-// Start initializes and starts all workers
+// Register sets the factory of the jobs of a type and the policy they are
+// retried with. A policy without MaxAttempts gets the pool's. Register every
+// type before starting the pool.
+func (wp *WorkerPool) Register(jobType string, factory JobFactory, policy retry.Policy) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = wp.config.MaxAttempts
+	}
+	wp.handlers[jobType] = jobHandler{factory: factory, policy: policy}
+}
+
+// Start initializes and starts all workers and the dispatcher
 func (wp *WorkerPool) Start() {
-	log.Info().Int("worker_count", wp.workerCount).Msg("Starting worker pool")
+	log.Info().
+		Int("worker_count", wp.config.Workers).
+		Str("name", wp.config.Name).
+		Msg("Starting worker pool")
 
-	for i := 0; i < wp.workerCount; i++ {
+	for i := 0; i < wp.config.Workers; i++ {
 		worker := &Worker{
 			id:         i,
+			pool:       wp,
 			jobQueue:   wp.jobQueue,
 			resultChan: wp.resultChannel,
 			ctx:        wp.ctx,
@@ -84,13 +180,22 @@ func (wp *WorkerPool) Start() {
 
 	// Start result processor
 	go wp.processResults()
+
+	dispatchCtx, dispatchStop := context.WithCancel(context.Background())
+	wp.dispatchStop = dispatchStop
+	go wp.dispatch(dispatchCtx)
 }
 
-// Stop gracefully shuts down the worker pool
+// Stop gracefully shuts down the worker pool. Jobs being executed are
+// finished; jobs still in the queue are left to the next instance.
 func (wp *WorkerPool) Stop() {
 	log.Info().Msg("Stopping worker pool")
 
-	// Close job queue to signal workers to stop accepting new jobs
+	// Stop claiming jobs
+	wp.dispatchStop()
+	<-wp.dispatchDone
+
+	// Close job queue to signal workers to stop once idle
 	close(wp.jobQueue)
 
 	// Wait for all workers to finish
@@ -103,16 +208,33 @@ func (wp *WorkerPool) Stop() {
 	log.Info().Msg("Worker pool stopped")
 }
 
-// SubmitJob submits a job to the worker pool
-func (wp *WorkerPool) SubmitJob(job Job) error {
+// Enqueue queues a job in the unit of work of repos, so that it is queued if
+// and only if the change it follows from commits. Work already queued under
+// the same key is not queued twice. Call Notify once the unit of work
+// committed.
+func (wp *WorkerPool) Enqueue(ctx context.Context, repos *repository.Repositories, jobType, key string, payload interface{}) error {
+	job, err := domain.NewJob(jobType, key, payload, wp.policy(jobType).MaxAttempts, time.Now())
+	if err != nil {
+		return err
+	}
+
+	queued, err := repos.Job.Enqueue(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	if queued {
+		log.Debug().Str("job_id", job.ID.String()).Str("job_type", jobType).Str("key", key).Msg("Job enqueued")
+	}
+	return nil
+}
+
+// Notify wakes the dispatcher to claim jobs that were just enqueued rather
+// than at its next poll
+func (wp *WorkerPool) Notify() {
 	select {
-	case wp.jobQueue <- job:
-		log.Debug().Str("job_id", job.GetID()).Str("job_type", job.GetType()).Msg("Job submitted")
-		return nil
-	case <-wp.ctx.Done():
-		return fmt.Errorf("worker pool is shutting down")
+	case wp.wake <- struct{}{}:
 	default:
-		return fmt.Errorf("job queue is full")
 	}
 }
 
@@ -130,211 +252,301 @@ func (wp *WorkerPool) GetMetrics() WorkerMetrics {
 	}
 }
 
-// processResults processes job results
-func (wp *WorkerPool) processResults() {
-	for result := range wp.resultChannel {
-		if result.Error != nil {
-			log.Error().
-				Str("job_id", result.JobID).
-				Err(result.Error).
-				Msg("Job failed")
-			atomic.AddInt64(&wp.metrics.JobsFailed, 1)
-		} else {
-			log.Debug().
-				Str("job_id", result.JobID).
-				Msg("Job completed successfully")
-			atomic.AddInt64(&wp.metrics.JobsSuccessful, 1)
-		}
+// dispatch claims due jobs for the idle workers and hands them over. Every
+// token on ready stands for a worker waiting for a job, so a claimed job never
+// waits for a worker while its lease runs.
+func (wp *WorkerPool) dispatch(ctx context.Context) {
+	defer close(wp.dispatchDone)
+
+	var prune <-chan time.Time
+	if wp.config.Retention > 0 {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		prune = ticker.C
 	}
-}
-
-// start starts the worker
-func (w *Worker) start(wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	log.Debug().Int("worker_id", w.id).Msg("Worker started")
 
+	idle := 0
 	for {
-		select {
-		case job, ok := <-w.jobQueue:
-			if !ok {
-				log.Debug().Int("worker_id", w.id).Msg("Worker stopped - job queue closed")
+		if idle == 0 {
+			select {
+			case <-wp.ready:
+				idle++
+			case <-ctx.Done():
 				return
 			}
+		}
+		for drained := false; !drained; {
+			select {
+			case <-wp.ready:
+				idle++
+			default:
+				drained = true
+			}
+		}
 
-			w.processJob(job)
+		jobs, err := wp.claim(ctx, idle)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to claim jobs")
+		}
+		for _, job := range jobs {
+			wp.jobQueue <- job
+			idle--
+		}
+		if len(jobs) > 0 {
+			continue
+		}
 
-		case <-w.ctx.Done():
-			log.Debug().Int("worker_id", w.id).Msg("Worker stopped - context cancelled")
+		timer := time.NewTimer(wp.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
 			return
+		case <-wp.wake:
+			timer.Stop()
+		case <-prune:
+			timer.Stop()
+			wp.prune(ctx)
+		case <-timer.C:
 		}
 	}
 }
 
-// processJob processes a single job
-func (w *Worker) processJob(job Job) {
-	atomic.AddInt64(&w.metrics.JobsInProgress, 1)
-	atomic.AddInt64(&w.metrics.JobsProcessed, 1)
-	defer atomic.AddInt64(&w.metrics.JobsInProgress, -1)
+// claim leases up to limit due jobs to this instance
+func (wp *WorkerPool) claim(ctx context.Context, limit int) ([]*domain.Job, error) {
+	now := time.Now()
+	return wp.repos.Job.Claim(ctx, wp.config.Name, now, now.Add(wp.config.VisibilityTimeout), limit)
+}
 
-	startTime := time.Now()
+// prune deletes the jobs that finished longer than the retention ago
+func (wp *WorkerPool) prune(ctx context.Context) {
+	deleted, err := wp.repos.Job.DeleteFinished(ctx, time.Now().Add(-wp.config.Retention))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to prune finished jobs")
+		return
+	}
 
-	log.Debug().
-		Int("worker_id", w.id).
-		Str("job_id", job.GetID()).
-		Str("job_type", job.GetType()).
-		Msg("Processing job")
+	if deleted > 0 {
+		log.Info().Int64("count", deleted).Msg("Pruned finished jobs")
+	}
+}
 
-	err := job.Execute(w.ctx)
+// execute runs a claimed job within its lease
+func (wp *WorkerPool) execute(ctx context.Context, queued *domain.Job) error {
+	// Its workers kept dying, most likely because of the job itself
+	if queued.LeaseExpired() {
+		return fmt.Errorf("lease expired on all %d attempts", queued.MaxAttempts)
+	}
 
-	duration := time.Since(startTime)
-	w.metrics.mu.Lock()
-	w.metrics.TotalProcessTime += duration
-	w.metrics.mu.Unlock()
+	handler, ok := wp.handlers[queued.Type]
+	if !ok {
+		// Another instance may know the type, e.g. during a rolling deploy
+		return fmt.Errorf("unknown job type: %s", queued.Type)
+	}
 
-	result := JobResult{
-		JobID: job.GetID(),
-		Error: err,
+	job, err := handler.factory(queued)
+	if err != nil {
+		return retry.Permanent(fmt.Errorf("invalid %s job: %w", queued.Type, err))
 	}
 
-	select {
-	case w.resultChan <- result:
-	case <-w.ctx.Done():
-		log.Warn().Str("job_id", job.GetID()).Msg("Could not send job result - context cancelled")
+	ctx, cancel := context.WithTimeout(ctx, wp.config.VisibilityTimeout)
+	defer cancel()
+
+	lost := make(chan error, 1)
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	go func() {
+		lost <- wp.renewLease(renewCtx, queued, cancel)
+	}()
+
+	err = job.Execute(ctx)
+	stopRenewing()
+	if leaseErr := <-lost; leaseErr != nil {
+		return fmt.Errorf("job aborted: %w", leaseErr)
 	}
+	return err
 }
 
-// BatchProcessor processes jobs in batches
-type BatchProcessor struct {
-	workerPool *WorkerPool
-	batchSize  int
-	flushTime  time.Duration
-	jobs       []Job
-	mu         sync.Mutex
-	ticker     *time.Ticker
-	ctx        context.Context
-	cancel     context.CancelFunc
+// renewLease extends the lease of an executing job until ctx ends. When the
+// job was claimed again by another worker, it cancels the execution with
+// abort and returns repository.ErrJobLeaseLost.
+func (wp *WorkerPool) renewLease(ctx context.Context, queued *domain.Job, abort context.CancelFunc) error {
+	ticker := time.NewTicker(wp.config.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		queued.ExtendLease(now.Add(wp.config.VisibilityTimeout), now)
+		err := wp.repos.Job.Update(ctx, queued)
+		if errors.Is(err, repository.ErrJobLeaseLost) {
+			abort()
+			return err
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Str("job_id", queued.ID.String()).Msg("Failed to renew job lease")
+		}
+	}
 }
 
-// NewBatchProcessor creates a new batch processor
-func NewBatchProcessor(workerPool *WorkerPool, batchSize int, flushTime time.Duration) *BatchProcessor {
-	ctx, cancel := context.WithCancel(context.Background())
+// SettleDead lets the job a dead queued job stands for settle its work in the
+// unit of work of repos, if it is a DeadJob. A job of an unknown type or with
+// an invalid payload has nothing to settle.
+func (wp *WorkerPool) SettleDead(ctx context.Context, repos *repository.Repositories, queued *domain.Job) error {
+	handler, ok := wp.handlers[queued.Type]
+	if !ok {
+		return nil
+	}
 
-	bp := &BatchProcessor{
-		workerPool: workerPool,
-		batchSize:  batchSize,
-		flushTime:  flushTime,
-		jobs:       make([]Job, 0, batchSize),
-		ticker:     time.NewTicker(flushTime),
-		ctx:        ctx,
-		cancel:     cancel,
+	job, err := handler.factory(queued)
+	if err != nil {
+		return nil
 	}
 
-	go bp.flushPeriodically()
+	dead, ok := job.(DeadJob)
+	if !ok {
+		return nil
+	}
+	return dead.OnDead(ctx, repos, queued)
+}
 
-	return bp
+// policy returns the retry policy of a job type
+func (wp *WorkerPool) policy(jobType string) retry.Policy {
+	if handler, ok := wp.handlers[jobType]; ok {
+		return handler.policy
+	}
+	return retry.Policy{MaxAttempts: wp.config.MaxAttempts}
 }
 
-// AddJob adds a job to the batch
-func (bp *BatchProcessor) AddJob(job Job) error {
-	bp.mu.Lock()
-	defer bp.mu.Unlock()
+// finish records the outcome of an attempt at a claimed job that took
+// duration. A failed job is retried after the backoff of its type's policy
+// until it runs out of attempts, when it is dead-lettered and settled, unless
+// the policy does not retry its error.
+func (wp *WorkerPool) finish(ctx context.Context, queued *domain.Job, err error, duration time.Duration) {
+	attempt := domain.NewJobAttempt(queued, err, duration)
+
+	now := time.Now()
+	if err == nil {
+		queued.MarkCompleted(now)
+	} else {
+		policy := wp.policy(queued.Type)
+		queued.MarkAttemptFailed(err, !policy.IsRetryable(err), now, policy.Delay(queued.Attempts))
+	}
 
-	bp.jobs = append(bp.jobs, job)
+	updateErr := wp.repos.WithinTransaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Job.Update(ctx, queued); err != nil {
+			return err
+		}
+
+		if err := repos.Job.CreateAttempt(ctx, attempt); err != nil {
+			return err
+		}
 
-	if len(bp.jobs) >= bp.batchSize {
-		return bp.flushBatch()
+		if queued.Status != domain.JobStatusDead {
+			return nil
+		}
+		if err := repos.Job.CreateDeadLetter(ctx, domain.NewJobDeadLetter(queued)); err != nil {
+			return err
+		}
+		return wp.SettleDead(ctx, repos, queued)
+	})
+	if updateErr != nil {
+		if errors.Is(updateErr, repository.ErrJobLeaseLost) {
+			log.Warn().Str("job_id", queued.ID.String()).Msg("Job lease expired before its outcome was recorded")
+			return
+		}
+		log.Error().Err(updateErr).Str("job_id", queued.ID.String()).Msg("Failed to record job outcome")
+		return
 	}
 
-	return nil
+	if queued.Status == domain.JobStatusDead {
+		log.Error().
+			Str("job_id", queued.ID.String()).
+			Str("job_type", queued.Type).
+			Int("attempts", queued.Attempts).
+			Str("error", queued.LastError).
+			Msg("Job dead-lettered")
+	}
 }
 
-// flushBatch flushes the current batch of jobs
-func (bp *BatchProcessor) flushBatch() error {
-	if len(bp.jobs) == 0 {
-		return nil
+// processResults processes job results
+func (wp *WorkerPool) processResults() {
+	for result := range wp.resultChannel {
+		if result.Error != nil {
+			log.Error().
+				Str("job_id", result.JobID).
+				Err(result.Error).
+				Msg("Job failed")
+			atomic.AddInt64(&wp.metrics.JobsFailed, 1)
+		} else {
+			log.Debug().
+				Str("job_id", result.JobID).
+				Msg("Job completed successfully")
+			atomic.AddInt64(&wp.metrics.JobsSuccessful, 1)
+		}
 	}
+}
 
-	batchJob := NewBatchJob(bp.jobs)
-	bp.jobs = bp.jobs[:0] // Clear the slice but keep capacity
+// start starts the worker
+func (w *Worker) start(wg *sync.WaitGroup) {
+	defer wg.Done()
 
-	return bp.workerPool.SubmitJob(batchJob)
-}
+	log.Debug().Int("worker_id", w.id).Msg("Worker started")
 
-// flushPeriodically flushes jobs periodically
-func (bp *BatchProcessor) flushPeriodically() {
 	for {
-		select {
-		case <-bp.ticker.C:
-			bp.mu.Lock()
-			bp.flushBatch()
-			bp.mu.Unlock()
-		case <-bp.ctx.Done():
-			bp.ticker.Stop()
+		// Ask the dispatcher for a job
+		w.pool.ready <- struct{}{}
+
+		job, ok := <-w.jobQueue
+		if !ok {
+			log.Debug().Int("worker_id", w.id).Msg("Worker stopped - job queue closed")
 			return
 		}
+
+		w.processJob(job)
 	}
 }
 
-// Stop stops the batch processor
-func (bp *BatchProcessor) Stop() {
-	bp.cancel()
-
-	bp.mu.Lock()
-	bp.flushBatch()
-	bp.mu.Unlock()
-}
+// processJob processes a single claimed job
+func (w *Worker) processJob(job *domain.Job) {
+	atomic.AddInt64(&w.metrics.JobsInProgress, 1)
+	atomic.AddInt64(&w.metrics.JobsProcessed, 1)
+	defer atomic.AddInt64(&w.metrics.JobsInProgress, -1)
 
-// BatchJob represents a batch of jobs
-type BatchJob struct {
-	id   string
-	jobs []Job
-}
+	startTime := time.Now()
 
-// NewBatchJob creates a new batch job
-func NewBatchJob(jobs []Job) *BatchJob {
-	return &BatchJob{
-		id:   fmt.Sprintf("batch-%d", time.Now().UnixNano()),
-		jobs: jobs,
-	}
-}
+	log.Debug().
+		Int("worker_id", w.id).
+		Str("job_id", job.ID.String()).
+		Str("job_type", job.Type).
+		Int("attempt", job.Attempts).
+		Msg("Processing job")
 
-// Execute executes all jobs in the batch
-func (bj *BatchJob) Execute(ctx context.Context) error {
-	var wg sync.WaitGroup
-	errorChan := make(chan error, len(bj.jobs))
-
-	for _, job := range bj.jobs {
-		wg.Add(1)
-		go func(j Job) {
-			defer wg.Done()
-			if err := j.Execute(ctx); err != nil {
-				errorChan <- fmt.Errorf("job %s failed: %w", j.GetID(), err)
-			}
-		}(job)
-	}
+	err := w.pool.execute(w.ctx, job)
 
-	wg.Wait()
-	close(errorChan)
+	duration := time.Since(startTime)
+	w.metrics.mu.Lock()
+	w.metrics.TotalProcessTime += duration
+	w.metrics.mu.Unlock()
 
-	var errors []error
-	for err := range errorChan {
-		errors = append(errors, err)
+	// The worker that claimed the job next records its outcome
+	if errors.Is(err, repository.ErrJobLeaseLost) {
+		log.Warn().Err(err).Str("job_id", job.ID.String()).Msg("Job lease lost during execution")
+	} else {
+		w.pool.finish(w.ctx, job, err, duration)
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("batch job failed with %d errors: %v", len(errors), errors)
+	result := JobResult{
+		JobID: job.ID.String(),
+		Error: err,
 	}
 
-	return nil
-}
-
-// GetID returns the batch job ID
-func (bj *BatchJob) GetID() string {
-	return bj.id
-}
-
-// GetType returns the job type
-func (bj *BatchJob) GetType() string {
-	return "batch"
+	select {
+	case w.resultChan <- result:
+	case <-w.ctx.Done():
+		log.Warn().Str("job_id", job.ID.String()).Msg("Could not send job result - context cancelled")
+	}
 }
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_balance_history_user_currency_created_at;
+DROP INDEX IF EXISTS idx_balances_user_id;
+DROP INDEX IF EXISTS idx_transactions_currency;
+
+ALTER TABLE balance_history DROP COLUMN IF EXISTS currency;
+
+DELETE FROM balances WHERE currency <> 'USD';
+ALTER TABLE balances DROP CONSTRAINT IF EXISTS balances_pkey;
+ALTER TABLE balances ADD PRIMARY KEY (user_id);
+ALTER TABLE balances DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,11 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE balances ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE balances DROP CONSTRAINT IF EXISTS balances_pkey;
+ALTER TABLE balances ADD PRIMARY KEY (user_id, currency);
+
+ALTER TABLE balance_history ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$');
+
+CREATE INDEX idx_transactions_currency ON transactions(currency);
+CREATE INDEX idx_balances_user_id ON balances(user_id);
+CREATE INDEX idx_balance_history_user_currency_created_at ON balance_history(user_id, currency, created_at);
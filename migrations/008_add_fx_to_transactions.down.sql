@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_transactions_to_currency;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS chk_transactions_fx_complete;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS fx_rate_source;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fx_rate_at;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fx_spread;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fx_rate;
+ALTER TABLE transactions DROP COLUMN IF EXISTS to_amount;
+ALTER TABLE transactions DROP COLUMN IF EXISTS to_currency;
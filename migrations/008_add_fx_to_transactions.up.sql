@@ -0,0 +1,13 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS to_currency CHAR(3) CHECK (to_currency ~ '^[A-Z]{3}$');
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS to_amount DECIMAL(15,2) CHECK (to_amount > 0);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(30,10) CHECK (fx_rate > 0);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_spread NUMERIC(12,10) CHECK (fx_spread >= 0 AND fx_spread < 1);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate_source VARCHAR(100);
+
+ALTER TABLE transactions ADD CONSTRAINT chk_transactions_fx_complete CHECK (
+    (to_currency IS NULL AND to_amount IS NULL AND fx_rate IS NULL AND fx_rate_at IS NULL)
+    OR (to_currency IS NOT NULL AND to_amount IS NOT NULL AND fx_rate IS NOT NULL AND fx_rate_at IS NOT NULL)
+);
+
+CREATE INDEX idx_transactions_to_currency ON transactions(to_currency) WHERE to_currency IS NOT NULL;
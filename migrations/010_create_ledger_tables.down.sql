@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trg_postings_balanced ON postings;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS ledger_accounts;
@@ -0,0 +1,79 @@
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(100) UNIQUE NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('user', 'external', 'fx')),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((type = 'user') = (user_id IS NOT NULL))
+);
+
+CREATE INDEX idx_ledger_accounts_user_id ON ledger_accounts(user_id) WHERE user_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID REFERENCES transactions(id) ON DELETE CASCADE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_journal_entries_transaction_id ON journal_entries(transaction_id) WHERE transaction_id IS NOT NULL;
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at);
+
+CREATE TABLE IF NOT EXISTS postings (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    journal_entry_id UUID NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    currency CHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_postings_journal_entry_id ON postings(journal_entry_id);
+CREATE INDEX idx_postings_account_id ON postings(account_id);
+
+-- Postings of a journal entry must sum to zero per currency. Checked at commit
+-- so the postings of an entry can be inserted one by one.
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM postings
+        WHERE journal_entry_id = NEW.journal_entry_id
+        GROUP BY currency
+        HAVING SUM(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.journal_entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER trg_postings_balanced
+    AFTER INSERT OR UPDATE ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Seed the ledger with the existing balances as opening entries against the
+-- external account so that rebuilding the projection preserves them
+INSERT INTO ledger_accounts (code, type, user_id, currency)
+SELECT 'user:' || user_id::text || ':' || currency, 'user', user_id, currency FROM balances
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO ledger_accounts (code, type, currency)
+SELECT DISTINCT 'external:' || currency, 'external', currency FROM balances WHERE amount <> 0
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TEMP TABLE opening_balances AS
+SELECT uuid_generate_v4() AS entry_id, user_id, currency, amount FROM balances WHERE amount <> 0;
+
+INSERT INTO journal_entries (id, description)
+SELECT entry_id, 'Opening balance' FROM opening_balances;
+
+INSERT INTO postings (journal_entry_id, account_id, currency, amount)
+SELECT o.entry_id, a.id, o.currency, o.amount
+FROM opening_balances o JOIN ledger_accounts a ON a.code = 'user:' || o.user_id::text || ':' || o.currency
+UNION ALL
+SELECT o.entry_id, a.id, o.currency, -o.amount
+FROM opening_balances o JOIN ledger_accounts a ON a.code = 'external:' || o.currency;
+
+DROP TABLE opening_balances;
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_transactions_reverses_transaction_id;
+
+UPDATE transactions SET status = 'completed' WHERE status IN ('partially_reversed', 'reversed');
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check CHECK (
+    status IN ('pending', 'completed', 'failed', 'cancelled')
+);
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS chk_transactions_reversed_amount;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversed_amount;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reverses_transaction_id;
@@ -0,0 +1,13 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reverses_transaction_id UUID REFERENCES transactions(id);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversed_amount DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+ALTER TABLE transactions ADD CONSTRAINT chk_transactions_reversed_amount CHECK (
+    reversed_amount >= 0 AND reversed_amount <= amount
+);
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check CHECK (
+    status IN ('pending', 'completed', 'failed', 'cancelled', 'partially_reversed', 'reversed')
+);
+
+CREATE INDEX idx_transactions_reverses_transaction_id ON transactions(reverses_transaction_id) WHERE reverses_transaction_id IS NOT NULL;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_transactions_hold_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS hold_id;
+
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE balances DROP CONSTRAINT IF EXISTS chk_balances_held_amount;
+ALTER TABLE balances DROP COLUMN IF EXISTS held_amount;
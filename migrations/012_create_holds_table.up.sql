@@ -0,0 +1,24 @@
+ALTER TABLE balances ADD COLUMN IF NOT EXISTS held_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00;
+ALTER TABLE balances ADD CONSTRAINT chk_balances_held_amount CHECK (held_amount >= 0 AND held_amount <= amount);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    captured_amount DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    description TEXT,
+    reference_id VARCHAR(100),
+    transaction_id UUID REFERENCES transactions(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_holds_user_id_currency ON holds(user_id, currency);
+CREATE INDEX idx_holds_expires_at ON holds(expires_at) WHERE status = 'active';
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS hold_id UUID REFERENCES holds(id);
+
+CREATE INDEX idx_transactions_hold_id ON transactions(hold_id) WHERE hold_id IS NOT NULL;
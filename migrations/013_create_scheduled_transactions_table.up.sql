@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS scheduled_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('credit', 'debit', 'transfer')),
+    description TEXT,
+    recurrence VARCHAR(20) NOT NULL CHECK (recurrence IN ('none', 'daily', 'weekly', 'monthly', 'cron')),
+    cron_expr VARCHAR(100) NOT NULL DEFAULT '',
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_at TIMESTAMP WITH TIME ZONE,
+    max_runs INTEGER CHECK (max_runs > 0),
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    run_count INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'cancelled')),
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_scheduled_transactions_user_id ON scheduled_transactions(user_id);
+CREATE INDEX idx_scheduled_transactions_next_run_at ON scheduled_transactions(next_run_at) WHERE status = 'active';
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES scheduled_transactions(id);
+
+CREATE INDEX idx_transactions_schedule_id ON transactions(schedule_id) WHERE schedule_id IS NOT NULL;
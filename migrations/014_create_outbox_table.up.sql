@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE REFERENCES events(id) ON DELETE CASCADE,
+    aggregate_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_outbox_status CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+-- Pending messages are claimed in id order per aggregate
+CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at, id) WHERE status = 'pending';
+CREATE INDEX idx_outbox_aggregate_pending ON outbox(aggregate_id, id) WHERE status = 'pending';
+CREATE INDEX idx_outbox_delivered_at ON outbox(delivered_at) WHERE status = 'delivered';
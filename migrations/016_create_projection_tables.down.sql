@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS projection_balances;
+DROP TABLE IF EXISTS projection_daily_totals_events;
+DROP TABLE IF EXISTS projection_daily_totals;
+DROP TABLE IF EXISTS projection_checkpoints;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS projection_checkpoints (
+    name VARCHAR(100) PRIMARY KEY,
+    position TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_event_id UUID,
+    events_processed BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS projection_daily_totals (
+    user_id UUID NOT NULL,
+    currency CHAR(3) NOT NULL,
+    day DATE NOT NULL,
+    credited DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    debited DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    credit_count INTEGER NOT NULL DEFAULT 0,
+    debit_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, currency, day)
+);
+
+CREATE INDEX idx_projection_daily_totals_user_day ON projection_daily_totals(user_id, day);
+
+-- Events already added to the daily totals, so an event handled twice is counted once
+CREATE TABLE IF NOT EXISTS projection_daily_totals_events (
+    event_id UUID PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS projection_balances (
+    user_id UUID NOT NULL,
+    currency CHAR(3) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    version INTEGER NOT NULL,
+    last_transaction_id UUID,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, currency)
+);
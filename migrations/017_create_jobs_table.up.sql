@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    key VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    last_error TEXT,
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_by VARCHAR(255),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    lease_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_jobs_status CHECK (status IN ('pending', 'running', 'completed', 'failed', 'dead')),
+    CONSTRAINT chk_jobs_max_attempts CHECK (max_attempts > 0)
+);
+
+-- Work is queued once while it is unfinished
+CREATE UNIQUE INDEX idx_jobs_key_unfinished ON jobs(key) WHERE status IN ('pending', 'running');
+-- Pending jobs are claimed when due, running ones when their lease expires
+CREATE INDEX idx_jobs_pending ON jobs(scheduled_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_running ON jobs(locked_until) WHERE status = 'running';
+CREATE INDEX idx_jobs_completed_at ON jobs(completed_at) WHERE status IN ('completed', 'failed');
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS job_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    worker VARCHAR(255) NOT NULL,
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_job_attempts_job_id ON job_attempts(job_id, created_at);
+
+CREATE TABLE IF NOT EXISTS job_dead_letters (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    resolution VARCHAR(20),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_job_dead_letters_resolution CHECK (resolution IN ('requeued', 'discarded'))
+);
+
+CREATE INDEX idx_job_dead_letters_created_at ON job_dead_letters(created_at DESC) WHERE resolved_at IS NULL;
+CREATE INDEX idx_job_dead_letters_job_id ON job_dead_letters(job_id);
@@ -0,0 +1,2 @@
+DELETE FROM idempotency_keys WHERE status = 'in_progress';
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS status;
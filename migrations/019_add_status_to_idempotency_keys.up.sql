@@ -0,0 +1,3 @@
+-- A key is claimed in progress in the database transaction of the change the
+-- request makes, and completed with its response afterwards
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'completed';